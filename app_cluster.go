@@ -64,7 +64,7 @@ type ClusterState struct {
 
 // GetAppClusteringInfo returns the counters associated with clustering setup.
 func (cli *OvnClient) GetAppClusteringInfo(db string) (ClusterState, error) {
-	var app Client
+	var app *Client
 	var dbName string
 	var err error
 	server := ClusterState{}
@@ -72,10 +72,10 @@ func (cli *OvnClient) GetAppClusteringInfo(db string) (ClusterState, error) {
 	cmd := "cluster/status"
 	switch db {
 	case "ovsdb-server-northbound":
-		app, err = NewClient(cli.Database.Northbound.Socket.Control, cli.Timeout)
+		app, err = NewClient(cli.Database.Northbound.Socket.Control, withTimeoutSeconds(cli.Timeout))
 		dbName = cli.Database.Northbound.Name
 	case "ovsdb-server-southbound":
-		app, err = NewClient(cli.Database.Southbound.Socket.Control, cli.Timeout)
+		app, err = NewClient(cli.Database.Southbound.Socket.Control, withTimeoutSeconds(cli.Timeout))
 		dbName = cli.Database.Southbound.Name
 	default:
 		return server, fmt.Errorf("The '%s' database is unsupported for '%s'", db, cmd)
@@ -21,11 +21,11 @@ import (
 )
 
 func getAppCoverageMetrics(db string, sock string, timeout int) (map[string]map[string]float64, error) {
-	var app Client
+	var app *Client
 	var err error
 	cmd := "coverage/show"
 	metrics := make(map[string]map[string]float64)
-	app, err = NewClient(sock, timeout)
+	app, err = NewClient(sock, withTimeoutSeconds(timeout))
 	if err != nil {
 		app.Close()
 		return metrics, fmt.Errorf("failed '%s' from %s: %s", cmd, db, err)
@@ -27,13 +27,13 @@ import (
 //
 // Reference: http://www.openvswitch.org/support/dist-docs/ovs-vswitchd.8.txt
 func getAppDatapathInterfaces(db string, sock string, timeout int) ([]*OvsDatapath, []*OvsBridge, []*OvsInterface, error) {
-	var app Client
+	var app *Client
 	var err error
 	cmd := "dpif/show"
 	dps := []*OvsDatapath{}
 	brs := []*OvsBridge{}
 	intfs := []*OvsInterface{}
-	app, err = NewClient(sock, timeout)
+	app, err = NewClient(sock, withTimeoutSeconds(timeout))
 	if err != nil {
 		app.Close()
 		return dps, brs, intfs, fmt.Errorf("failed '%s' from %s: %s", cmd, db, err)
@@ -166,11 +166,11 @@ func getAppDatapathInterfaces(db string, sock string, timeout int) ([]*OvsDatapa
 }
 
 func getAppDatapath(db string, sock string, timeout int) ([]*OvsDatapath, error) {
-	var app Client
+	var app *Client
 	var err error
 	cmd := "dpctl/show"
 	dps := []*OvsDatapath{}
-	app, err = NewClient(sock, timeout)
+	app, err = NewClient(sock, withTimeoutSeconds(timeout))
 	if err != nil {
 		app.Close()
 		return dps, fmt.Errorf("failed '%s' from %s: %s", cmd, db, err)
@@ -21,11 +21,11 @@ import (
 )
 
 func appListCommands(db string, sock string, timeout int) (map[string]bool, error) {
-	var app Client
+	var app *Client
 	var err error
 	cmd := "list-commands"
 	cmds := make(map[string]bool)
-	app, err = NewClient(sock, timeout)
+	app, err = NewClient(sock, withTimeoutSeconds(timeout))
 	if err != nil {
 		return cmds, fmt.Errorf("failed '%s' from %s: %s", cmd, db, err)
 	}
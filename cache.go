@@ -0,0 +1,646 @@
+// Copyright 2018 Paul Greenberg (greenpau@outlook.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovsdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// TableCache holds a local copy of the rows of the tables of a database.
+// The rows are keyed by table name and UUID. The cache stays in sync with
+// the server via the updates of a monitor. The tables having indexes in
+// the schema are indexed by the values of the indexed columns.
+type TableCache struct {
+	Database string
+	Schema   Schema
+	mux      sync.RWMutex
+	tables   map[string]map[string]Row
+	indexes  map[string][]*cacheIndex
+	monitor  *Monitor
+}
+
+// cacheIndex maps the values of the columns of an index to row UUIDs.
+type cacheIndex struct {
+	columns []string
+	rows    map[string]string
+}
+
+// NewTableCache returns an empty cache for the tables of a database.
+// The cache is populated with Populate or Populate2.
+func NewTableCache(schema Schema, tables ...string) (*TableCache, error) {
+	tc := &TableCache{
+		Database: schema.Name,
+		Schema:   schema,
+		tables:   make(map[string]map[string]Row),
+		indexes:  make(map[string][]*cacheIndex),
+	}
+	for _, table := range tables {
+		t, exists := schema.Tables[table]
+		if !exists {
+			return nil, fmt.Errorf("cache error: table %s not found in %s", table, schema.Name)
+		}
+		tc.tables[table] = make(map[string]Row)
+		for _, idx := range t.Indexes {
+			columns := []string{}
+			items, ok := idx.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("cache error: table %s: unsupported index: %v", table, idx)
+			}
+			for _, item := range items {
+				column, ok := item.(string)
+				if !ok {
+					return nil, fmt.Errorf("cache error: table %s: unsupported index: %v", table, idx)
+				}
+				columns = append(columns, column)
+			}
+			tc.indexes[table] = append(tc.indexes[table], &cacheIndex{
+				columns: columns,
+				rows:    make(map[string]string),
+			})
+		}
+	}
+	return tc, nil
+}
+
+// indexKey returns the key of a row in an index. The second return value
+// is false when the row does not have all the indexed columns.
+func (idx *cacheIndex) indexKey(row Row) (string, bool) {
+	values := []interface{}{}
+	for _, column := range idx.columns {
+		v, exists := row[column]
+		if !exists {
+			return "", false
+		}
+		values = append(values, v)
+	}
+	b, err := json.Marshal(values)
+	if err != nil {
+		return "", false
+	}
+	return string(b), true
+}
+
+// Tables returns the names of the cached tables.
+func (tc *TableCache) Tables() []string {
+	tc.mux.RLock()
+	defer tc.mux.RUnlock()
+	return tc.cachedTables()
+}
+
+// HasTable returns true when the table is cached.
+func (tc *TableCache) HasTable(table string) bool {
+	tc.mux.RLock()
+	defer tc.mux.RUnlock()
+	_, exists := tc.tables[table]
+	return exists
+}
+
+// Row returns a copy of the row with the UUID.
+func (tc *TableCache) Row(table, uuid string) (Row, bool) {
+	tc.mux.RLock()
+	defer tc.mux.RUnlock()
+	row, exists := tc.tables[table][uuid]
+	if !exists {
+		return nil, false
+	}
+	return copyRow(row), true
+}
+
+// Rows returns a copy of the rows of a table sorted by UUID.
+func (tc *TableCache) Rows(table string) []Row {
+	tc.mux.RLock()
+	defer tc.mux.RUnlock()
+	rows := []Row{}
+	for _, uuid := range tc.uuids(table) {
+		rows = append(rows, copyRow(tc.tables[table][uuid]))
+	}
+	return rows
+}
+
+// count returns the number of the rows of a table. The second return value
+// is false when the table is not cached.
+func (tc *TableCache) count(table string) (int, bool) {
+	tc.mux.RLock()
+	defer tc.mux.RUnlock()
+	rows, cached := tc.tables[table]
+	return len(rows), cached
+}
+
+// Lookup returns a copy of the row having the values of the columns of
+// an index, e.g. a logical switch port by its name. The keys of the values
+// must match the columns of one of the indexes of the table.
+func (tc *TableCache) Lookup(table string, values map[string]interface{}) (Row, bool) {
+	tc.mux.RLock()
+	defer tc.mux.RUnlock()
+	for _, idx := range tc.indexes[table] {
+		if len(idx.columns) != len(values) {
+			continue
+		}
+		key, ok := idx.indexKey(Row(values))
+		if !ok {
+			continue
+		}
+		uuid, exists := idx.rows[key]
+		if !exists {
+			return nil, false
+		}
+		return copyRow(tc.tables[table][uuid]), true
+	}
+	return nil, false
+}
+
+// Select returns the rows of a table matching the conditions. The rows
+// contain only the requested columns, or all columns when none requested.
+// The second return value is false when the conditions cannot be evaluated
+// locally.
+func (tc *TableCache) Select(table string, columns []string, conditions []Condition) ([]Row, bool) {
+	tc.mux.RLock()
+	defer tc.mux.RUnlock()
+	if _, exists := tc.tables[table]; !exists {
+		return nil, false
+	}
+	rows := []Row{}
+	for _, uuid := range tc.uuids(table) {
+		row := tc.tables[table][uuid]
+		matched := true
+		for _, c := range conditions {
+			m, ok := matchCachedRow(row, c)
+			if !ok {
+				return nil, false
+			}
+			if !m {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		if len(columns) == 0 {
+			rows = append(rows, copyRow(row))
+			continue
+		}
+		r := make(Row)
+		for _, column := range columns {
+			v, exists := row[column]
+			if !exists {
+				// The column is not monitored.
+				return nil, false
+			}
+			r[column] = v
+		}
+		rows = append(rows, r)
+	}
+	return rows, true
+}
+
+// matchCachedRow evaluates a condition on a row, as described in
+// https://tools.ietf.org/html/rfc7047#section-5.1. The second return value
+// is false when the condition cannot be evaluated locally.
+func matchCachedRow(row Row, c Condition) (bool, bool) {
+	v, exists := row[c.Column]
+	if !exists {
+		return false, false
+	}
+	actual, err := DecodeDatum(v)
+	if err != nil {
+		return false, false
+	}
+	expected, err := c.Datum()
+	if err != nil {
+		return false, false
+	}
+	switch c.Function {
+	case "==":
+		return datumIncludes(actual, expected) && datumIncludes(expected, actual), true
+	case "!=":
+		return !(datumIncludes(actual, expected) && datumIncludes(expected, actual)), true
+	case "includes":
+		return datumIncludes(actual, expected), true
+	case "excludes":
+		return datumExcludes(actual, expected), true
+	case "<", "<=", ">", ">=":
+		if s, ok := actual.(OvsSet); ok && len(s) == 0 {
+			// The empty optional column matches no comparison.
+			return false, true
+		}
+		a, ok := atomNumber(actual)
+		if !ok {
+			return false, false
+		}
+		e, ok := atomNumber(expected)
+		if !ok {
+			return false, false
+		}
+		switch c.Function {
+		case "<":
+			return a < e, true
+		case "<=":
+			return a <= e, true
+		case ">":
+			return a > e, true
+		}
+		return a >= e, true
+	}
+	return false, false
+}
+
+// datumIncludes returns true when every element of the set, or every pair
+// of the map, b is in a.
+func datumIncludes(a, b interface{}) bool {
+	if bm, ok := b.(OvsMap); ok {
+		am, _ := a.(OvsMap)
+		for bk, bv := range bm {
+			found := false
+			for ak, av := range am {
+				if atomEqual(ak, bk) && atomEqual(av, bv) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	}
+	if _, ok := a.(OvsMap); ok {
+		s, isSet := b.(OvsSet)
+		return isSet && len(s) == 0
+	}
+	for _, be := range datumSet(b) {
+		found := false
+		for _, ae := range datumSet(a) {
+			if atomEqual(ae, be) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// datumExcludes returns true when no element of the set, or no pair of the
+// map, b is in a.
+func datumExcludes(a, b interface{}) bool {
+	if bm, ok := b.(OvsMap); ok {
+		for k, v := range bm {
+			if datumIncludes(a, OvsMap{k: v}) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, e := range datumSet(b) {
+		if datumIncludes(a, e) {
+			return false
+		}
+	}
+	return true
+}
+
+// atomEqual compares the atoms. The strings equal the UUIDs with the same
+// value, and the integers equal the reals with the same value.
+func atomEqual(a, b interface{}) bool {
+	if a == b {
+		return true
+	}
+	// The Barewords not converted into the types of their columns equal
+	// the strings with the same text, or the atoms they look like.
+	if w, ok := a.(Bareword); ok {
+		return b == string(w) || atomEqual(barewordValue(string(w)), b)
+	}
+	if w, ok := b.(Bareword); ok {
+		return a == string(w) || atomEqual(a, barewordValue(string(w)))
+	}
+	switch x := a.(type) {
+	case UUID:
+		return b == string(x)
+	case string:
+		return b == UUID(x)
+	}
+	an, aok := atomNumber(a)
+	bn, bok := atomNumber(b)
+	return aok && bok && an == bn
+}
+
+// atomNumber returns the value of an integer or real atom, or of a set
+// with exactly one such atom.
+func atomNumber(v interface{}) (float64, bool) {
+	if s, ok := v.(OvsSet); ok && len(s) == 1 {
+		v = s[0]
+	}
+	switch x := v.(type) {
+	case int64:
+		return float64(x), true
+	case float64:
+		return x, true
+	case Bareword:
+		f, err := strconv.ParseFloat(string(x), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// uuids returns the sorted UUIDs of the rows of a table.
+func (tc *TableCache) uuids(table string) []string {
+	uuids := []string{}
+	for uuid := range tc.tables[table] {
+		uuids = append(uuids, uuid)
+	}
+	sort.Strings(uuids)
+	return uuids
+}
+
+// Populate applies the updates received from "monitor" method and
+// "update" notifications.
+func (tc *TableCache) Populate(updates TableUpdates) {
+	tc.mux.Lock()
+	defer tc.mux.Unlock()
+	for table, tableUpdate := range updates {
+		if _, exists := tc.tables[table]; !exists {
+			continue
+		}
+		for uuid, rowUpdate := range tableUpdate {
+			if rowUpdate.New == nil {
+				tc.deleteRow(table, uuid)
+				continue
+			}
+			row := make(Row)
+			if rowUpdate.Old != nil {
+				// The modified row may contain only the changed columns.
+				for k, v := range tc.tables[table][uuid] {
+					row[k] = v
+				}
+			}
+			for k, v := range rowUpdate.New {
+				row[k] = v
+			}
+			tc.setRow(table, uuid, row)
+		}
+	}
+}
+
+// Populate2 applies the updates received from "monitor_cond" and
+// "monitor_cond_since" methods and "update2" and "update3" notifications.
+func (tc *TableCache) Populate2(updates TableUpdates2) error {
+	tc.mux.Lock()
+	defer tc.mux.Unlock()
+	for table, tableUpdate := range updates {
+		if _, exists := tc.tables[table]; !exists {
+			continue
+		}
+		for uuid, rowUpdate := range tableUpdate {
+			switch {
+			case rowUpdate.Delete:
+				tc.deleteRow(table, uuid)
+			case rowUpdate.Initial != nil:
+				tc.setRow(table, uuid, copyRow(rowUpdate.Initial))
+			case rowUpdate.Insert != nil:
+				tc.setRow(table, uuid, copyRow(rowUpdate.Insert))
+			case rowUpdate.Modify != nil:
+				old, exists := tc.tables[table][uuid]
+				if !exists {
+					return fmt.Errorf("cache error: table %s: modified row %s not found", table, uuid)
+				}
+				row := copyRow(old)
+				for column, diff := range rowUpdate.Modify {
+					v, err := applyDatumDiff(tc.Schema.Tables[table].Columns[column], row[column], diff)
+					if err != nil {
+						return fmt.Errorf("cache error: table %s column %s: %w", table, column, err)
+					}
+					row[column] = v
+				}
+				tc.setRow(table, uuid, row)
+			}
+		}
+	}
+	return nil
+}
+
+func (tc *TableCache) setRow(table, uuid string, row Row) {
+	tc.deleteRow(table, uuid)
+	row["_uuid"] = []interface{}{"uuid", uuid}
+	tc.tables[table][uuid] = row
+	for _, idx := range tc.indexes[table] {
+		if key, ok := idx.indexKey(row); ok {
+			idx.rows[key] = uuid
+		}
+	}
+}
+
+func (tc *TableCache) deleteRow(table, uuid string) {
+	row, exists := tc.tables[table][uuid]
+	if !exists {
+		return
+	}
+	for _, idx := range tc.indexes[table] {
+		if key, ok := idx.indexKey(row); ok && idx.rows[key] == uuid {
+			delete(idx.rows, key)
+		}
+	}
+	delete(tc.tables[table], uuid)
+}
+
+func copyRow(row Row) Row {
+	r := make(Row)
+	for k, v := range row {
+		r[k] = v
+	}
+	return r
+}
+
+// datumKind returns "map", "set" or "atom" for a column, based on the
+// cardinality of the column type in the schema.
+func datumKind(c Column) string {
+	switch {
+	case c.Type.IsMap():
+		return "map"
+	case c.Type.IsSet():
+		return "set"
+	}
+	return "atom"
+}
+
+// datumElements returns the elements of a set, or the key-value pairs of
+// a map, encoded per RFC 7047 section 5.1.
+func datumElements(v interface{}, kind string) []interface{} {
+	if x, ok := v.([]interface{}); ok && len(x) == 2 {
+		if tag, ok := x[0].(string); ok && tag == kind {
+			if elements, ok := x[1].([]interface{}); ok {
+				return elements
+			}
+		}
+	}
+	if v == nil || kind == "map" {
+		return []interface{}{}
+	}
+	// A set with a single element is encoded as the element.
+	return []interface{}{v}
+}
+
+// applyDatumDiff applies the changes of a column reported by
+// "modify" member of <row-update2>, as described in ovsdb-server(7).
+func applyDatumDiff(c Column, old, diff interface{}) (interface{}, error) {
+	switch datumKind(c) {
+	case "set":
+		elements := datumElements(old, "set")
+		for _, d := range datumElements(diff, "set") {
+			found := false
+			for i, e := range elements {
+				if reflect.DeepEqual(e, d) {
+					elements = append(elements[:i:i], elements[i+1:]...)
+					found = true
+					break
+				}
+			}
+			if !found {
+				elements = append(elements, d)
+			}
+		}
+		if len(elements) == 1 {
+			return elements[0], nil
+		}
+		return []interface{}{"set", elements}, nil
+	case "map":
+		pairs := datumElements(old, "map")
+		for _, d := range datumElements(diff, "map") {
+			dp, ok := d.([]interface{})
+			if !ok || len(dp) != 2 {
+				return nil, fmt.Errorf("invalid map diff: %v", diff)
+			}
+			found := false
+			for i, p := range pairs {
+				pp, ok := p.([]interface{})
+				if !ok || len(pp) != 2 || !reflect.DeepEqual(pp[0], dp[0]) {
+					continue
+				}
+				found = true
+				if reflect.DeepEqual(pp[1], dp[1]) {
+					pairs = append(pairs[:i:i], pairs[i+1:]...)
+				} else {
+					pairs[i] = dp
+				}
+				break
+			}
+			if !found {
+				pairs = append(pairs, dp)
+			}
+		}
+		return []interface{}{"map", pairs}, nil
+	}
+	return diff, nil
+}
+
+// tableCaches holds the caches of a client by database name.
+type tableCaches struct {
+	mux    sync.RWMutex
+	caches map[string]*TableCache
+}
+
+func (c *tableCaches) get(db string) *TableCache {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+	return c.caches[db]
+}
+
+// set installs the cache of a database and returns the replaced one, if any.
+func (c *tableCaches) set(db string, tc *TableCache) *TableCache {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	old := c.caches[db]
+	c.caches[db] = tc
+	return old
+}
+
+// CacheTables creates a cache for the tables of a database and keeps it in
+// sync via a monitor. Caching the tables of the same database again
+// replaces the cache and cancels the monitor of the replaced one.
+//
+// Once cached, the following reads of the tables are served from the cache
+// rather than the server:
+//   - the "select" queries of Transact, including the joined tables of the
+//     queries, when the cache evaluates all their conditions, i.e. "==",
+//     "!=", "includes", and "excludes" on any column, and "<", "<=", ">",
+//     and ">=" on integer and real columns, see TableCache.Select;
+//   - the rows followed by FollowReferences.
+//
+// The other operations, and the transactions of Execute, are sent to the
+// server. The cache holds the rows as of the last update received by the
+// monitor. The server sends the update for a transaction committed by the
+// client separately from the reply to the transaction, so a select served
+// right after a write may return the rows as they were before the write.
+func (cli *Client) CacheTables(db string, tables ...string) (*TableCache, error) {
+	return cli.CacheTablesContext(context.Background(), db, tables...)
+}
+
+// CacheTablesContext is like CacheTables, but honors the cancellation and
+// the deadline of the context.
+func (cli *Client) CacheTablesContext(ctx context.Context, db string, tables ...string) (*TableCache, error) {
+	if cli == nil {
+		return nil, fmt.Errorf("client was not initialized")
+	}
+	if len(tables) == 0 {
+		return nil, fmt.Errorf("cache error: no tables")
+	}
+	schema, err := cli.GetSchemaContext(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	tc, err := NewTableCache(schema, tables...)
+	if err != nil {
+		return nil, err
+	}
+	requests := make(map[string]MonitorRequest)
+	for _, table := range tables {
+		requests[table] = MonitorRequest{}
+	}
+	m, err := cli.MonitorContext(ctx, db, requests)
+	if err != nil {
+		return nil, fmt.Errorf("cache error: %w", err)
+	}
+	tc.monitor = m
+	tc.Populate(m.Initial)
+	go func() {
+		for updates := range m.Updates {
+			tc.Populate(updates)
+		}
+	}()
+	if old := cli.caches.set(db, tc); old != nil && old.monitor != nil {
+		// The monitor of the replaced cache is canceled, which closes
+		// its updates and stops the goroutine populating it.
+		if err := cli.MonitorCancelContext(ctx, old.monitor); err != nil {
+			cli.logf("ovsdb: cache of %s: %v", db, err)
+		}
+	}
+	return tc, nil
+}
+
+// Cache returns the cache of a database, if any.
+func (cli *Client) Cache(db string) *TableCache {
+	if cli == nil || cli.caches == nil {
+		return nil
+	}
+	return cli.caches.get(db)
+}
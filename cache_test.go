@@ -0,0 +1,250 @@
+// Copyright 2018 Paul Greenberg (greenpau@outlook.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovsdb
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+var testNorthboundSchema = []byte(`{
+  "name": "OVN_Northbound",
+  "version": "5.32.1",
+  "tables": {
+    "Logical_Switch": {
+      "columns": {
+        "name": {"type": "string"},
+        "ports": {"type": {"key": {"type": "uuid", "refTable": "Logical_Switch_Port", "refType": "strong"}, "min": 0, "max": "unlimited"}},
+        "external_ids": {"type": {"key": "string", "value": "string", "min": 0, "max": "unlimited"}}
+      },
+      "isRoot": true
+    },
+    "Logical_Switch_Port": {
+      "columns": {
+        "name": {"type": "string"},
+        "up": {"type": {"key": "boolean", "min": 0, "max": 1}},
+        "tag": {"type": {"key": {"type": "integer", "minInteger": 1, "maxInteger": 4095}, "min": 0, "max": 1}},
+        "addresses": {"type": {"key": "string", "min": 0, "max": "unlimited"}},
+        "external_ids": {"type": {"key": "string", "value": "string", "min": 0, "max": "unlimited"}}
+      },
+      "indexes": [["name"]],
+      "isRoot": false
+    }
+  }
+}`)
+
+func newTestSchema(t *testing.T, b []byte) Schema {
+	var schema Schema
+	if err := json.Unmarshal(b, &schema); err != nil {
+		t.Fatalf("FAIL: failed to decode schema: %v", err)
+	}
+	return schema
+}
+
+func TestTableCachePopulate(t *testing.T) {
+	schema := newTestSchema(t, testNorthboundSchema)
+	tc, err := NewTableCache(schema, "Logical_Switch_Port")
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	tc.Populate(TableUpdates{
+		"Logical_Switch_Port": {
+			"uuid-1": {New: Row{"name": "lsp1", "up": false}},
+			"uuid-2": {New: Row{"name": "lsp2", "up": false}},
+		},
+	})
+	tc.Populate(TableUpdates{
+		"Logical_Switch_Port": {
+			"uuid-1": {Old: Row{"name": "lsp1"}, New: Row{"name": "lsp3"}},
+			"uuid-2": {Old: Row{"name": "lsp2", "up": false}},
+		},
+	})
+	if len(tc.Rows("Logical_Switch_Port")) != 1 {
+		t.Fatalf("FAIL: expected a single row, but found: %v", tc.Rows("Logical_Switch_Port"))
+	}
+	if _, found := tc.Lookup("Logical_Switch_Port", map[string]interface{}{"name": "lsp1"}); found {
+		t.Fatalf("FAIL: expected the index entry for the old name to be removed")
+	}
+	row, found := tc.Lookup("Logical_Switch_Port", map[string]interface{}{"name": "lsp3"})
+	if !found {
+		t.Fatalf("FAIL: expected to find the row by name")
+	}
+	if row["up"] != false {
+		t.Fatalf("FAIL: expected the unchanged columns to be preserved: %v", row)
+	}
+	t.Logf("PASS: monitor updates applied to the cache")
+}
+
+func TestTableCachePopulate2(t *testing.T) {
+	schema := newTestSchema(t, testNorthboundSchema)
+	tc, err := NewTableCache(schema, "Logical_Switch_Port")
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	var updates TableUpdates2
+	for i, test := range []struct {
+		input  string
+		column string
+		value  string
+	}{
+		{
+			input:  `{"Logical_Switch_Port":{"uuid-1":{"initial":{"name":"lsp1","addresses":["set",["a","b"]],"external_ids":["map",[["k1","v1"],["k2","v2"]]]}}}}`,
+			column: "addresses",
+			value:  `["set",["a","b"]]`,
+		},
+		{
+			input:  `{"Logical_Switch_Port":{"uuid-1":{"modify":{"addresses":["set",["b","c"]]}}}}`,
+			column: "addresses",
+			value:  `["set",["a","c"]]`,
+		},
+		{
+			input:  `{"Logical_Switch_Port":{"uuid-1":{"modify":{"addresses":"a"}}}}`,
+			column: "addresses",
+			value:  `"c"`,
+		},
+		{
+			input:  `{"Logical_Switch_Port":{"uuid-1":{"modify":{"external_ids":["map",[["k1","v1"],["k2","v3"],["k4","v4"]]]}}}}`,
+			column: "external_ids",
+			value:  `["map",[["k2","v3"],["k4","v4"]]]`,
+		},
+		{
+			input:  `{"Logical_Switch_Port":{"uuid-1":{"modify":{"name":"lsp2"}}}}`,
+			column: "name",
+			value:  `"lsp2"`,
+		},
+	} {
+		updates = TableUpdates2{}
+		if err := json.Unmarshal([]byte(test.input), &updates); err != nil {
+			t.Fatalf("FAIL: Test %d: %v", i, err)
+		}
+		if err := tc.Populate2(updates); err != nil {
+			t.Fatalf("FAIL: Test %d: %v", i, err)
+		}
+		row, found := tc.Row("Logical_Switch_Port", "uuid-1")
+		if !found {
+			t.Fatalf("FAIL: Test %d: row not found", i)
+		}
+		b, _ := json.Marshal(row[test.column])
+		if string(b) != test.value {
+			t.Fatalf("FAIL: Test %d: column %s: %s (expected) vs. %s (actual)", i, test.column, test.value, b)
+		}
+		t.Logf("PASS: Test %d: column %s: %s", i, test.column, b)
+	}
+	if _, found := tc.Lookup("Logical_Switch_Port", map[string]interface{}{"name": "lsp2"}); !found {
+		t.Fatalf("FAIL: expected to find the row by name")
+	}
+	if err := json.Unmarshal([]byte(`{"Logical_Switch_Port":{"uuid-1":{"delete":null}}}`), &updates); err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	if err := tc.Populate2(updates); err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	if _, found := tc.Row("Logical_Switch_Port", "uuid-1"); found {
+		t.Fatalf("FAIL: expected the row to be deleted")
+	}
+}
+
+func TestTransactFromCache(t *testing.T) {
+	srv := newTestServer(t, func(s *testServer, req testRequest) {
+		switch req.Method {
+		case "get_schema":
+			s.reply(req, json.RawMessage(testNorthboundSchema))
+		case "monitor":
+			s.reply(req, map[string]interface{}{
+				"Logical_Switch_Port": map[string]interface{}{
+					"uuid-1": map[string]interface{}{
+						"new": map[string]interface{}{"name": "lsp1", "up": true},
+					},
+					"uuid-2": map[string]interface{}{
+						"new": map[string]interface{}{"name": "lsp2", "up": false},
+					},
+				},
+			})
+		case "transact":
+			s.reply(req, []interface{}{map[string]interface{}{"rows": []interface{}{}}})
+		}
+	})
+	cli, err := NewClient(srv.Socket)
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	defer cli.Close()
+	if _, err := cli.CacheTables("OVN_Northbound", "Logical_Switch_Port"); err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	result, err := cli.Transact("OVN_Northbound", "SELECT _uuid, name FROM Logical_Switch_Port WHERE name==\"lsp2\"")
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	if len(result.Rows) != 1 {
+		t.Fatalf("FAIL: expected a single row from the cache, but found: %v", result.Rows)
+	}
+	value, valueType, err := result.Rows[0].GetColumnValue("_uuid", result.Columns)
+	if err != nil || valueType != "string" || value.(string) != "uuid-2" {
+		t.Fatalf("FAIL: unexpected row: %v", result.Rows[0])
+	}
+	result, err = cli.Transact("OVN_Northbound", "SELECT _uuid, name FROM Logical_Switch_Port WHERE up==true")
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	if len(result.Rows) != 1 {
+		t.Fatalf("FAIL: expected a single row from the cache, but found: %v", result.Rows)
+	}
+	value, _, err = result.Rows[0].GetColumnValue("_uuid", result.Columns)
+	if err != nil || value.(string) != "uuid-1" {
+		t.Fatalf("FAIL: unexpected row: %v", result.Rows[0])
+	}
+	t.Logf("PASS: select served from the cache")
+}
+
+func TestCacheTablesReplace(t *testing.T) {
+	canceled := make(chan string, 1)
+	srv := newTestServer(t, func(s *testServer, req testRequest) {
+		switch req.Method {
+		case "get_schema":
+			s.reply(req, json.RawMessage(testNorthboundSchema))
+		case "monitor":
+			s.reply(req, map[string]interface{}{})
+		case "monitor_cancel":
+			var id string
+			json.Unmarshal(req.Params[0], &id)
+			canceled <- id
+			s.reply(req, map[string]interface{}{})
+		}
+	})
+	cli, err := NewClient(srv.Socket)
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	defer cli.Close()
+	first, err := cli.CacheTables("OVN_Northbound", "Logical_Switch_Port")
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	second, err := cli.CacheTables("OVN_Northbound", "Logical_Switch", "Logical_Switch_Port")
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	if cli.Cache("OVN_Northbound") != second {
+		t.Fatalf("FAIL: expected the second cache to replace the first one")
+	}
+	if id := <-canceled; id != first.monitor.ID {
+		t.Fatalf("FAIL: expected '%s' monitor to be canceled, but canceled '%s'", first.monitor.ID, id)
+	}
+	if _, open := <-first.monitor.Updates; open {
+		t.Fatalf("FAIL: expected the updates of the replaced cache to be closed")
+	}
+	t.Logf("PASS: replaced cache stopped its monitor")
+}
@@ -17,7 +17,9 @@
 package ovsdb
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	//"github.com/davecgh/go-spew/spew"
 	"io"
@@ -26,112 +28,378 @@ import (
 	"net/rpc"
 	"reflect"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
-// Client DOCS-TBD
+// Client is a client of an OVSDB server, as described in
+// https://tools.ietf.org/html/rfc7047#section-4. It is created with
+// NewClient and is safe for concurrent use.
 type Client struct {
-	mux        sync.Mutex
-	Endpoint   string
-	Timeout    int
-	MaxRetries int
-	Schemas    map[string]Schema
-	References map[string]map[string]map[string]string
-	txQueue    chan Request
-	rxQueue    chan Response
-	errQueue   chan error
-	closed     bool
-}
-
-// NewClient TODO
-func NewClient(s string, t int) (Client, error) {
-	cli := Client{}
-	cli.Endpoint = s
-	cli.Timeout = t
-	cli.MaxRetries = 2
+	// sem guards the connection. Unlike a mutex, the callers waiting for
+	// a reconnect honor the cancellation of their contexts.
+	sem               chan struct{}
+	Endpoint          string
+	Schemas           map[string]Schema
+	References        map[string]map[string]map[string]string
+	dialTimeout       time.Duration
+	requestTimeout    time.Duration
+	maxRetries        int
+	tls               *TLSOptions
+	logger            Logger
+	dialContext       DialContextFunc
+	randomRemotes     bool
+	leaderOnly        bool
+	leaderDatabases   map[string]bool
+	inactivityProbe   time.Duration
+	reconnect         ReconnectPolicy
+	onConnectionEvent func(ConnectionEvent)
+	schemaMux         *sync.RWMutex
+	state             *connState
+	notifier          *notificationHandler
+	caches            *tableCaches
+}
+
+// connState is the connection of a client. It is guarded by sem. The
+// closed is set when the connection is unusable, e.g. it was lost, and the
+// shutdown is set by Close, after which the client does not reconnect.
+type connState struct {
+	conn     *connection
+	closed   bool
+	shutdown bool
+}
+
+// connection is a connection served by ovsdbMessenger. The requests sent
+// over a connection are multiplexed by their ids, so many requests may
+// await their responses at the same time.
+type connection struct {
+	remote  string
+	txQueue chan Request
+	counter uint64
+	// done is closed when the messenger exits. The err holds the reason.
+	done chan struct{}
+	err  error
+	// reported is set when the loss of the connection was reported to
+	// the connection event handler.
+	reported bool
+}
+
+// NewClient returns a client connected to an endpoint. The endpoint is
+// a remote, e.g. "unix:/var/run/openvswitch/db.sock", "tcp:127.0.0.1:6641",
+// or "ssl:127.0.0.1:6641", or a comma-separated list of the remotes of
+// a clustered database, e.g. "tcp:a:6641,tcp:b:6641,tcp:c:6641". The client
+// connects to the first available remote. When the connection fails, the
+// returned client is usable, and reconnects on the next request.
+func NewClient(endpoint string, opts ...Option) (*Client, error) {
+	cli := &Client{
+		Endpoint:    endpoint,
+		dialTimeout: DefaultDialTimeout,
+		maxRetries:  DefaultMaxRetries,
+	}
+	for _, opt := range opts {
+		opt(cli)
+	}
+	if cli.dialContext == nil {
+		dialer := &net.Dialer{}
+		cli.dialContext = dialer.DialContext
+	}
+	cli.sem = make(chan struct{}, 1)
 	cli.Schemas = make(map[string]Schema)
 	cli.References = make(map[string]map[string]map[string]string)
-	// send only channel
-	cli.txQueue = make(chan Request, 1)
-	// receive only channels
-	cli.rxQueue = make(chan Response, 1)
-	cli.errQueue = make(chan error, 1)
-	go ovsdbMessenger(cli.Endpoint, cli.Timeout, cli.txQueue, cli.rxQueue, cli.errQueue)
-	err := <-cli.errQueue
-	return cli, err
+	cli.schemaMux = &sync.RWMutex{}
+	cli.notifier = newNotificationHandler()
+	cli.caches = &tableCaches{caches: make(map[string]*TableCache)}
+	cli.state = &connState{}
+	cli.sem <- struct{}{}
+	defer func() { <-cli.sem }()
+	if _, err := cli.redial(); err != nil {
+		return cli, err
+	}
+	return cli, nil
+}
+
+// dialRemote connects to a remote and starts the messenger of the
+// connection.
+func (cli *Client) dialRemote(remote string) (*connection, error) {
+	serverProto, serverAddr, err := parseSocket(remote)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	if cli.dialTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cli.dialTimeout)
+		defer cancel()
+	}
+	var c net.Conn
+	if serverProto == "ssl" {
+		c, err = dialTLS(ctx, cli.dialContext, serverAddr, cli.tls)
+	} else {
+		c, err = cli.dialContext(ctx, serverProto, serverAddr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	conn := &connection{
+		remote:  remote,
+		txQueue: make(chan Request),
+		done:    make(chan struct{}),
+	}
+	go ovsdbMessenger(c, conn, cli.notifier, cli.inactivityProbe, cli.logf)
+	return conn, nil
 }
 
-// Close TODO
+// Close closes the connection of the client and stops its monitors. The
+// client does not reconnect afterwards: the subsequent requests fail with
+// ErrClientClosed.
 func (cli *Client) Close() error {
-	_, err := cli.query("shutdown", nil)
-	return err
+	if cli == nil || cli.sem == nil {
+		return fmt.Errorf("client was not initialized")
+	}
+	cli.sem <- struct{}{}
+	conn := cli.state.conn
+	cli.state.conn = nil
+	cli.state.closed = true
+	cli.state.shutdown = true
+	if conn != nil && !conn.reported {
+		conn.reported = true
+		cli.emit(ConnectionEvent{State: StateDisconnected, Remote: conn.remote})
+	}
+	<-cli.sem
+	if conn != nil {
+		conn.close(nil)
+	}
+	cli.notifier.closeAll()
+	return nil
+}
+
+// connect returns the current connection, reconnecting when the
+// connection is closed. Once the client is closed by Close, it fails with
+// ErrClientClosed.
+func (cli *Client) connect(ctx context.Context) (*connection, error) {
+	select {
+	case cli.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, contextError(ctx)
+	}
+	defer func() { <-cli.sem }()
+	if cli.state.shutdown {
+		return nil, &ConnectionError{Remote: cli.Endpoint, Err: ErrClientClosed}
+	}
+	if cli.state.conn != nil && !cli.state.closed {
+		select {
+		case <-cli.state.conn.done:
+		default:
+			return cli.state.conn, nil
+		}
+	}
+	return cli.redial()
 }
 
 func (cli *Client) query(method string, param interface{}) (*Response, error) {
-	if cli == nil {
+	return cli.queryContext(context.Background(), method, param)
+}
+
+// queryContext sends a request and waits for the response. The requests
+// of concurrent callers share the connection. When the context is done
+// before the response arrives, the client asks the server to cancel the
+// request, as described in https://tools.ietf.org/html/rfc7047#section-4.1.4.
+// When the connection fails, the client reconnects and resends the request
+// up to maxRetries times, waiting between the attempts as configured by
+// the reconnect policy. However, once sent, only the requests of
+// idempotentMethods are resent, and the others, e.g. "transact", fail with
+// ConnectionError. The requests without a deadline are limited by the
+// request timeout, if any.
+func (cli *Client) queryContext(ctx context.Context, method string, param interface{}) (*Response, error) {
+	if cli == nil || cli.sem == nil {
 		return nil, fmt.Errorf("client was not initialized")
 	}
-	if method == "shutdown" && cli.closed {
-		return nil, nil
+	if err := ctx.Err(); err != nil {
+		return nil, contextError(ctx)
 	}
-	cli.mux.Lock()
-	defer cli.mux.Unlock()
-	errMsgs := []string{}
-	req := Request{
-		Method: method,
-		Params: param,
+	if _, ok := ctx.Deadline(); !ok && cli.requestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cli.requestTimeout)
+		defer cancel()
 	}
-	for {
-		if cli.closed == false {
-			cli.txQueue <- req
+	errMsgs := []string{}
+	var lastErr error
+	for attempt := 0; attempt <= cli.maxRetries; attempt++ {
+		if attempt > 0 {
+			timer := time.NewTimer(cli.reconnect.Backoff(attempt - 1))
 			select {
-			case err := <-cli.errQueue:
-				cli.closed = true
-				if method == "shutdown" {
-					return nil, nil
-				}
-				errMsgs = append(errMsgs, err.Error())
-			case resp := <-cli.rxQueue:
-				return &resp, nil
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, contextError(ctx)
 			}
 		}
-		retryAttempts := cli.MaxRetries
-		for {
-			if cli.closed {
-				go ovsdbMessenger(cli.Endpoint, cli.Timeout, cli.txQueue, cli.rxQueue, cli.errQueue)
-				err := <-cli.errQueue
-				if err == nil {
-					cli.closed = false
-					break
-				}
+		conn, err := cli.connect(ctx)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, ErrClientClosed) {
+				return nil, err
 			}
-			if retryAttempts < 1 {
-				if len(errMsgs) == 0 {
-					return nil, fmt.Errorf("client unavailable")
-				}
-				return nil, fmt.Errorf("%s", errMsgs)
+			errMsgs = append(errMsgs, err.Error())
+			lastErr = err
+			continue
+		}
+		req, err := cli.send(ctx, conn, method, param)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
 			}
-			retryAttempts--
+			// The connection failed before the request was sent. The
+			// request is sent over a new connection.
+			errMsgs = append(errMsgs, err.Error())
+			lastErr = err
+			continue
+		}
+		resp, err := cli.await(ctx, conn, req)
+		if err == nil || ctx.Err() != nil {
+			return resp, err
+		}
+		select {
+		case <-conn.done:
+			if !idempotentMethods[method] {
+				// The server may have executed the request, e.g.
+				// committed the transaction, before the failure.
+				return nil, err
+			}
+			// The request is sent again over a new connection.
+			errMsgs = append(errMsgs, err.Error())
+			lastErr = err
+		default:
+			return nil, err
+		}
+	}
+	return nil, &retryError{msgs: errMsgs, err: lastErr}
+}
+
+// idempotentMethods are the methods whose requests are safe to resend after
+// the loss of the connection. The monitors and the locks are re-established
+// by the reconnects, see replay.
+var idempotentMethods = map[string]bool{
+	"echo":       true,
+	"list_dbs":   true,
+	"get_schema": true,
+}
+
+// retryError reports the failures of the attempts to send a request. It
+// wraps the failure of the last attempt.
+type retryError struct {
+	msgs []string
+	err  error
+}
+
+func (e *retryError) Error() string { return fmt.Sprintf("%s", e.msgs) }
+
+func (e *retryError) Unwrap() error { return e.err }
+
+// Is reports the exhausted attempts as the loss of the connection.
+func (e *retryError) Is(target error) bool {
+	return target == ErrConnectionLost
+}
+
+// roundTrip sends a request over a connection and waits for the response.
+func (cli *Client) roundTrip(ctx context.Context, conn *connection, method string, param interface{}) (*Response, error) {
+	req, err := cli.send(ctx, conn, method, param)
+	if err != nil {
+		return nil, err
+	}
+	return cli.await(ctx, conn, req)
+}
+
+// send passes a request to the messenger of a connection. When it fails,
+// the request was not sent.
+func (cli *Client) send(ctx context.Context, conn *connection, method string, param interface{}) (Request, error) {
+	req := Request{
+		Method: method,
+		Params: param,
+		id:     atomic.AddUint64(&conn.counter, 1),
+		reply:  make(chan Response, 1),
+	}
+	select {
+	case conn.txQueue <- req:
+		return req, nil
+	case <-conn.done:
+		return req, conn.err
+	case <-ctx.Done():
+		return req, contextError(ctx)
+	}
+}
+
+// await waits for the response to a request sent over a connection.
+func (cli *Client) await(ctx context.Context, conn *connection, req Request) (*Response, error) {
+	select {
+	case resp := <-req.reply:
+		if resp.err != nil {
+			return nil, resp.err
 		}
+		if resp.Error.Message != "" && req.Method != "transact" {
+			respErr := resp.Error
+			return nil, fmt.Errorf("error in response body: %w", &respErr)
+		}
+		return &resp, nil
+	case <-conn.done:
+		return nil, conn.err
+	case <-ctx.Done():
+		return cli.cancel(ctx, conn, req)
 	}
-	return nil, fmt.Errorf("%s", errMsgs)
 }
 
-func (cli *Client) getColumns(db, table string) (map[string]string, error) {
-	if _, dbExists := cli.References[db]; dbExists {
-		if _, tblExists := cli.References[db][table]; tblExists {
-			return cli.References[db][table], nil
+// cancel asks the server to cancel a request and waits for the server to
+// complete it. The server replies to a canceled request with "canceled"
+// error. The server may have completed the request before receiving
+// "cancel", in which case the response is returned as is. When the server
+// does not reply in time, the response is discarded on arrival.
+func (cli *Client) cancel(ctx context.Context, conn *connection, req Request) (*Response, error) {
+	select {
+	case conn.txQueue <- Request{Method: "cancel", id: req.id}:
+	case <-conn.done:
+		return nil, fmt.Errorf("%w, %w", contextError(ctx), conn.err)
+	}
+	timer := time.NewTimer(cli.timeout())
+	defer timer.Stop()
+	select {
+	case <-conn.done:
+		return nil, fmt.Errorf("%w, %w", contextError(ctx), conn.err)
+	case resp := <-req.reply:
+		if resp.err != nil {
+			return nil, resp.err
 		}
+		if resp.Error.Message == "canceled" {
+			return nil, contextError(ctx)
+		}
+		if resp.Error.Message != "" && req.Method != "transact" {
+			respErr := resp.Error
+			return nil, fmt.Errorf("error in response body: %w", &respErr)
+		}
+		return &resp, nil
+	case <-timer.C:
+		return nil, contextError(ctx)
 	}
-	schema, err := cli.GetSchema(db)
+}
+
+func (cli *Client) getColumns(ctx context.Context, db, table string) (map[string]string, error) {
+	cli.schemaMux.RLock()
+	columns, exists := cli.References[db][table]
+	cli.schemaMux.RUnlock()
+	if exists {
+		return columns, nil
+	}
+	schema, err := cli.GetSchemaContext(ctx, db)
 	if err != nil {
 		return make(map[string]string), err
 	}
-	cli.References[db] = make(map[string]map[string]string)
-	columns, err := schema.GetColumnsTypes(table)
+	columns, err = schema.GetColumnsTypes(table)
 	if err != nil {
 		return columns, err
 	}
+	cli.schemaMux.Lock()
+	defer cli.schemaMux.Unlock()
+	if _, dbExists := cli.References[db]; !dbExists {
+		cli.References[db] = make(map[string]map[string]string)
+	}
 	cli.References[db][table] = columns
 	return columns, nil
 }
@@ -153,8 +421,9 @@ type ovsdbCodec struct {
 	pending map[uint64]string // map request id to method name
 }
 
-// newClientCodec returns a new rpc.ClientCodec using JSON-RPC on conn.
-func newClientCodec(conn io.ReadWriteCloser) rpc.ClientCodec {
+// newClientCodec returns a new codec using JSON-RPC on conn. The codec
+// implements rpc.ClientCodec.
+func newClientCodec(conn io.ReadWriteCloser) *ovsdbCodec {
 	return &ovsdbCodec{
 		dec:     json.NewDecoder(conn),
 		enc:     newOvsdbEncoder(conn),
@@ -171,15 +440,30 @@ type clientRequest struct {
 }
 
 func (c *ovsdbCodec) WriteRequest(r *rpc.Request, param interface{}) error {
+	b, err := c.EncodeRequest(r, param)
+	if err != nil {
+		return err
+	}
+	return c.WriteEncodedRequest(r, b)
+}
+
+// EncodeRequest encodes a request for WriteEncodedRequest. The encoding
+// errors do not affect the connection.
+func (c *ovsdbCodec) EncodeRequest(r *rpc.Request, param interface{}) ([]byte, error) {
 	c.req.Method = r.ServiceMethod
 	c.req.ID = r.Seq
 	c.req.Params[0] = param
+	return c.enc.Marshal(&c.req)
+}
+
+// WriteEncodedRequest writes a request encoded by EncodeRequest.
+func (c *ovsdbCodec) WriteEncodedRequest(r *rpc.Request, b []byte) error {
 	if r.ServiceMethod != "echo" && r.Seq != 0 {
 		c.mutex.Lock()
 		c.pending[r.Seq] = r.ServiceMethod
 		c.mutex.Unlock()
 	}
-	return c.enc.Encode(&c.req)
+	return c.enc.Write(b)
 }
 
 type clientResponse struct {
@@ -187,20 +471,21 @@ type clientResponse struct {
 	ID     interface{}      `json:"id"`
 	Result *json.RawMessage `json:"result"`
 	Error  interface{}      `json:"error"`
+	Method string           `json:"method"`
+	Params *json.RawMessage `json:"params"`
 }
 
 func (r *clientResponse) reset() {
 	r.ID = 0
 	r.Result = nil
 	r.Error = nil
+	r.Method = ""
+	r.Params = nil
 }
 
 func (c *ovsdbCodec) ReadResponseHeader(r *rpc.Response) error {
 	c.resp.reset()
 	if err := c.dec.Decode(&c.resp); err != nil {
-		if err.Error() == "EOF" {
-			return nil
-		}
 		return fmt.Errorf("codec decode: %v", err)
 	}
 	//spew.Dump(c.resp)
@@ -213,15 +498,28 @@ func (c *ovsdbCodec) ReadResponseHeader(r *rpc.Response) error {
 		c.mutex.Unlock()
 	} else {
 		//spew.Dump(c)
-		r.ServiceMethod = "echo"
+		// The messages without a numeric id are either server echo
+		// requests or notifications, e.g. "update", "locked".
+		r.ServiceMethod = c.resp.Method
+		if r.ServiceMethod == "" {
+			r.ServiceMethod = "echo"
+		}
 		r.Seq = 0
 		return nil
 	}
 
 	r.Error = ""
 	if c.resp.Error != nil || c.resp.Result == nil {
-		x, ok := c.resp.Error.(string)
-		if !ok {
+		var x string
+		switch e := c.resp.Error.(type) {
+		case nil:
+		case string:
+			x = e
+		case map[string]interface{}:
+			// The <error> object, as described in
+			// https://tools.ietf.org/html/rfc7047#section-3.1.
+			x, _ = e["error"].(string)
+		default:
 			return fmt.Errorf("invalid error %v", c.resp.Error)
 		}
 		if x == "" {
@@ -232,6 +530,22 @@ func (c *ovsdbCodec) ReadResponseHeader(r *rpc.Response) error {
 	return nil
 }
 
+// ReadResponseError reads the error of a response received by
+// ReadResponseHeader, including the details of <error> object.
+func (c *ovsdbCodec) ReadResponseError(e *Error) {
+	switch x := c.resp.Error.(type) {
+	case string:
+		e.Message = x
+	case map[string]interface{}:
+		e.Message, _ = x["error"].(string)
+		e.Details, _ = x["details"].(string)
+		e.Syntax, _ = x["syntax"].(string)
+	}
+	if e.Message == "" {
+		e.Message = "unspecified error"
+	}
+}
+
 func (c *ovsdbCodec) ReadResponseBody(x interface{}) error {
 	if x == nil {
 		return nil
@@ -239,85 +553,180 @@ func (c *ovsdbCodec) ReadResponseBody(x interface{}) error {
 	return json.Unmarshal(*c.resp.Result, x)
 }
 
+// ReadNotification reads the parameters of a notification received by
+// ReadResponseHeader.
+func (c *ovsdbCodec) ReadNotification(n *Notification) error {
+	n.Method = c.resp.Method
+	n.Params = nil
+	if c.resp.Params == nil {
+		return nil
+	}
+	n.Params = append(json.RawMessage{}, *c.resp.Params...)
+	return nil
+}
+
 func (c *ovsdbCodec) Close() error {
 	return c.c.Close()
 }
 
-func ovsdbMessenger(s string, t int, rxQueue <-chan Request, txQueue chan<- Response, errQueue chan<- error) {
-	var counter uint64 = 1
-	var resp rpc.Response
-	var respMsg Response
-	if t == 0 {
-		t = 2
-	}
-	serverProto, serverAddr, err := parseSocket(s)
-	if err != nil {
-		errQueue <- err
-		return
-	}
-	dialer := net.Dialer{
-		Timeout: time.Second * time.Duration(t),
+// ovsdbMessage is a message received from the server by ovsdbReader.
+type ovsdbMessage struct {
+	header       rpc.Response
+	response     Response
+	notification Notification
+	err          error
+}
+
+// ovsdbReader reads the messages arriving on a connection and passes them
+// to the messenger. The server may send notifications at any time, e.g.
+// monitor updates, therefore the reads are not tied to the requests.
+func ovsdbReader(cli *ovsdbCodec, msgQueue chan<- ovsdbMessage, done <-chan struct{}) {
+	for {
+		var msg ovsdbMessage
+		if err := cli.ReadResponseHeader(&msg.header); err != nil {
+			msg.err = err
+		} else {
+			switch {
+			case msg.header.Seq == 0 && msg.header.ServiceMethod == "echo":
+			case msg.header.Seq == 0:
+				msg.err = cli.ReadNotification(&msg.notification)
+			case msg.header.Error == "":
+				if err := cli.ReadResponseBody(&msg.response); err != nil {
+					msg.err = fmt.Errorf("decode body error: %v", err)
+				}
+			default:
+				cli.ReadResponseError(&msg.response.Error)
+			}
+		}
+		select {
+		case msgQueue <- msg:
+		case <-done:
+			return
+		}
+		if msg.err != nil {
+			return
+		}
 	}
-	conn, err := dialer.Dial(serverProto, serverAddr)
-	if err != nil {
-		errQueue <- err
-		return
+}
+
+// ovsdbMessenger serves a connection. It writes the requests received via
+// txQueue of the connection and dispatches the responses to the requests
+// by their ids, in the order of arrival. When the connection fails, the
+// messenger records the error and closes done channel of the connection,
+// failing the requests awaiting their responses.
+func ovsdbMessenger(c net.Conn, conn *connection, notifier *notificationHandler, probe time.Duration, logf func(string, ...interface{})) {
+	cli := newClientCodec(c)
+	msgQueue := make(chan ovsdbMessage)
+	done := make(chan struct{})
+	defer close(done)
+	go ovsdbReader(cli, msgQueue, done)
+	p := newProber(probe)
+	defer p.stop()
+	fail := func(err error) {
+		cli.Close()
+		conn.err = &ConnectionError{Remote: conn.remote, Err: err}
+		close(conn.done)
 	}
-	errQueue <- nil
-	cli := newClientCodec(conn)
+	// pending holds the requests awaiting responses, by id.
+	pending := make(map[uint64]Request)
 	for {
 		select {
-		case reqMsg := <-rxQueue:
+		case reqMsg := <-conn.txQueue:
 			var req rpc.Request
-			if reqMsg.Method == "shutdown" {
-				cli.Close()
-				errQueue <- nil
+			switch reqMsg.Method {
+			case "shutdown":
+				// The params may hold the reason, e.g. the loss of
+				// the leadership.
+				if err, ok := reqMsg.Params.(error); ok {
+					fail(err)
+					return
+				}
+				fail(ErrClientClosed)
 				return
+			case "cancel":
+				if _, exists := pending[reqMsg.id]; !exists {
+					// The response has already arrived.
+					continue
+				}
+				req.ServiceMethod = reqMsg.Method
+				if err := cli.WriteRequest(&req, reqMsg.id); err != nil {
+					fail(err)
+					return
+				}
+				continue
 			}
 			req.ServiceMethod = reqMsg.Method
-			req.Seq = counter
-			if err := cli.WriteRequest(&req, reqMsg.Params); err != nil {
-				errQueue <- err
+			req.Seq = reqMsg.id
+			b, err := cli.EncodeRequest(&req, reqMsg.Params)
+			if err != nil {
+				// The request is invalid, e.g. its params cannot be
+				// encoded, but the connection is intact. Only the
+				// caller of the request fails.
+				reqMsg.reply <- Response{Seq: reqMsg.id, err: err}
+				continue
+			}
+			if err := cli.WriteEncodedRequest(&req, b); err != nil {
+				fail(err)
 				return
 			}
-		}
-		if err := cli.ReadResponseHeader(&resp); err != nil {
-			errQueue <- err
-			return
-		}
-		if resp.ServiceMethod == "echo" && resp.Seq == 0 {
-			// handling server echo
-			var req rpc.Request
-			req.ServiceMethod = resp.ServiceMethod
-			req.Seq = resp.Seq
-			if err := cli.WriteRequest(&req, nil); err != nil {
-				errQueue <- err
+			pending[reqMsg.id] = reqMsg
+		case <-p.expired():
+			send, err := p.expire()
+			if err != nil {
+				fail(err)
 				return
 			}
-			if err := cli.ReadResponseHeader(&resp); err != nil {
-				errQueue <- err
+			if send {
+				// The reply to the probe is discarded.
+				probeReq := Request{
+					Method: "echo",
+					id:     atomic.AddUint64(&conn.counter, 1),
+					reply:  make(chan Response, 1),
+				}
+				req := rpc.Request{ServiceMethod: probeReq.Method, Seq: probeReq.id}
+				if err := cli.WriteRequest(&req, ""); err != nil {
+					fail(err)
+					return
+				}
+				pending[probeReq.id] = probeReq
+			}
+		case msg := <-msgQueue:
+			if msg.err != nil {
+				fail(msg.err)
 				return
 			}
-		} else {
-			if resp.Seq != counter {
-				errQueue <- fmt.Errorf("sequence mismatch: %v (request) vs. %v (response)", counter, resp.Seq)
+			p.received()
+			resp := msg.header
+			if resp.Seq == 0 {
+				if resp.ServiceMethod == "echo" {
+					// handling server echo
+					var req rpc.Request
+					req.ServiceMethod = resp.ServiceMethod
+					req.Seq = resp.Seq
+					if err := cli.WriteRequest(&req, nil); err != nil {
+						fail(err)
+						return
+					}
+					continue
+				}
+				if err := notifier.handle(msg.notification); err != nil {
+					logf("ovsdb: %s: %v", conn.remote, err)
+				}
+				continue
+			}
+			reqMsg, exists := pending[resp.Seq]
+			if !exists {
+				fail(fmt.Errorf("unexpected response id: %v", resp.Seq))
 				return
 			}
-			counter++
-		}
-		if resp.Error != "" {
-			errQueue <- fmt.Errorf("error in response header: %s", resp.Error)
-			return
-		}
-		if err := cli.ReadResponseBody(&respMsg); err != nil {
-			errQueue <- fmt.Errorf("decode body error: %v", err)
-			return
-		}
-		if respMsg.Error.Message != "" {
-			errQueue <- fmt.Errorf("error in response body: %s", respMsg.Error.String())
-			return
+			delete(pending, resp.Seq)
+			// The request may have failed, e.g. it was canceled, but
+			// the connection is intact. The error is read by the reader.
+			// The errors in the response body, e.g. a failed operation of
+			// a transaction, do not affect the connection and are
+			// handled by the callers.
+			msg.response.Seq = resp.Seq
+			reqMsg.reply <- msg.response
 		}
-		txQueue <- respMsg
 	}
-	return
 }
@@ -15,7 +15,15 @@
 package ovsdb
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestNewClient(t *testing.T) {
@@ -33,7 +41,7 @@ func TestNewClient(t *testing.T) {
 		{socket: "unix:/var/run/openvswitch/db.sock", shouldFail: false},
 		{socket: "unixd:/var/run/openvswitch/db.sock", shouldFail: true},
 	} {
-		cli, err := NewClient(test.socket, 0)
+		cli, err := NewClient(test.socket)
 		if err != nil {
 			if !test.shouldFail {
 				t.Logf("FAIL: Test %d: socket '%s', expected to pass, but failed with: %v", i, test.socket, err)
@@ -56,3 +64,263 @@ func TestNewClient(t *testing.T) {
 		t.Fatalf("Failed %d tests", testFailed)
 	}
 }
+
+func TestQueryContext(t *testing.T) {
+	pending := make(chan interface{}, 1)
+	srv := newTestServer(t, func(s *testServer, req testRequest) {
+		switch req.Method {
+		case "list_dbs":
+			// The server does not reply until the client cancels the
+			// request.
+			pending <- req.ID
+		case "cancel":
+			s.send(map[string]interface{}{"id": <-pending, "result": nil, "error": "canceled"})
+		case "echo":
+			s.reply(req, req.Params)
+		}
+	})
+	cli, err := NewClient(srv.Socket)
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = cli.DatabasesContext(ctx)
+	if !errors.Is(err, ErrTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("FAIL: expected timeout error, but received: %v", err)
+	}
+	var timeoutErr interface{ Timeout() bool }
+	if !errors.As(err, &timeoutErr) || !timeoutErr.Timeout() {
+		t.Fatalf("FAIL: expected the error to report a timeout: %v", err)
+	}
+	t.Logf("PASS: request timed out: %v", err)
+
+	// The requests do not wait for an outstanding request.
+	done := make(chan error)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		defer cancel()
+		_, err := cli.DatabasesContext(ctx)
+		done <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := cli.EchoContext(ctx, "test message"); err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	if err := <-done; !errors.Is(err, ErrTimeout) {
+		t.Fatalf("FAIL: expected timeout error, but received: %v", err)
+	}
+	t.Logf("PASS: outstanding request timed out")
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, err := cli.GetSchemaContext(ctx, "OVN_Northbound"); !errors.Is(err, context.Canceled) || errors.Is(err, ErrTimeout) {
+		t.Fatalf("FAIL: expected cancellation error, but received: %v", err)
+	}
+	t.Logf("PASS: canceled request failed")
+
+	if err := cli.EchoContext(context.Background(), "test message"); err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	t.Logf("PASS: client remains usable after the timeouts")
+}
+
+func TestConcurrentRequests(t *testing.T) {
+	const count = 10
+	var held []testRequest
+	srv := newTestServer(t, func(s *testServer, req testRequest) {
+		switch req.Method {
+		case "echo":
+			// The server replies once all requests arrive, in the reverse
+			// order of arrival.
+			held = append(held, req)
+			if len(held) < count {
+				return
+			}
+			for i := len(held) - 1; i >= 0; i-- {
+				s.reply(held[i], held[i].Params)
+			}
+			held = nil
+		case "get_schema":
+			s.reply(req, json.RawMessage(testNorthboundSchema))
+		}
+	})
+	cli, err := NewClient(srv.Socket)
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	defer cli.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*count)
+	for i := 0; i < count; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			errs <- cli.Echo(fmt.Sprintf("message %d", i))
+		}(i)
+		go func() {
+			defer wg.Done()
+			_, err := cli.GetSchema("OVN_Northbound")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("FAIL: %v", err)
+		}
+	}
+	t.Logf("PASS: concurrent requests completed successfully")
+}
+
+// testLogger collects the messages logged by a client.
+type testLogger struct {
+	mux      sync.Mutex
+	messages []string
+}
+
+func (l *testLogger) Printf(format string, v ...interface{}) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	l.messages = append(l.messages, fmt.Sprintf(format, v...))
+}
+
+func TestNewClientOptions(t *testing.T) {
+	srv := newTestServer(t, func(s *testServer, req testRequest) {
+		if req.Method == "echo" {
+			s.reply(req, req.Params)
+		}
+		// The server never replies to other requests.
+	})
+	var dialed string
+	logger := &testLogger{}
+	cli, err := NewClient("tcp:192.0.2.1:6641",
+		WithDialContext(func(ctx context.Context, network, addr string) (net.Conn, error) {
+			// The connections go to the test server, e.g. as if the
+			// dialer entered another network namespace.
+			dialed = network + ":" + addr
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", strings.TrimPrefix(srv.Socket, "unix:"))
+		}),
+		WithRequestTimeout(100*time.Millisecond),
+		WithLogger(logger),
+	)
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	defer cli.Close()
+	if dialed != "tcp:192.0.2.1:6641" {
+		t.Fatalf("FAIL: expected the dial hook to connect to tcp:192.0.2.1:6641, but got %q", dialed)
+	}
+	if err := cli.Echo("test message"); err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	t.Logf("PASS: connected with the dial hook")
+
+	if _, err := cli.Databases(); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("FAIL: expected the request to time out, but got: %v", err)
+	}
+	t.Logf("PASS: the request timed out: %v", err)
+
+	logger.mux.Lock()
+	defer logger.mux.Unlock()
+	if len(logger.messages) == 0 || !strings.Contains(logger.messages[0], "connected tcp:192.0.2.1:6641") {
+		t.Fatalf("FAIL: expected the connection to be logged, but got: %v", logger.messages)
+	}
+	t.Logf("PASS: logged the connection")
+}
+
+func TestConnectionLostError(t *testing.T) {
+	var srv *testServer
+	srv = newTestServer(t, func(s *testServer, req testRequest) {
+		switch req.Method {
+		case "echo":
+			s.reply(req, req.Params)
+		case "list_dbs":
+			// The server drops the connection instead of replying.
+			srv.disconnect()
+		case "get_schema":
+			s.send(map[string]interface{}{"id": req.ID, "result": nil, "error": map[string]interface{}{
+				"error":   "unknown database",
+				"details": "OVN_Southbound",
+			}})
+		}
+	})
+	cli, err := NewClient(srv.Socket, WithRetryPolicy(0, ReconnectPolicy{}))
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	defer cli.Close()
+
+	_, err = cli.Databases()
+	var connErr *ConnectionError
+	if !errors.Is(err, ErrConnectionLost) || !errors.As(err, &connErr) || connErr.Remote != srv.Socket {
+		t.Fatalf("FAIL: expected connection loss, but got: %v", err)
+	}
+	t.Logf("PASS: expected to fail, failed with: %v", err)
+
+	_, err = cli.GetSchema("OVN_Southbound")
+	var ovsdbErr *Error
+	if !errors.Is(err, ErrUnknownDatabase) || !errors.As(err, &ovsdbErr) || ovsdbErr.Details != "OVN_Southbound" {
+		t.Fatalf("FAIL: expected unknown database error, but got: %v", err)
+	}
+	if errors.Is(err, ErrConnectionLost) {
+		t.Fatalf("FAIL: expected the connection to be intact, but got: %v", err)
+	}
+	t.Logf("PASS: expected to fail, failed with: %v", err)
+}
+
+func TestEncodingErrorKeepsConnection(t *testing.T) {
+	transacts := make(chan testRequest, 1)
+	srv := newTestServer(t, func(s *testServer, req testRequest) {
+		switch req.Method {
+		case "echo":
+			s.reply(req, req.Params)
+		case "transact":
+			transacts <- req
+			s.reply(req, []interface{}{map[string]interface{}{"rows": []interface{}{}}})
+		}
+	})
+	var mux sync.Mutex
+	events := []ConnectionEvent{}
+	cli, err := NewClient(srv.Socket, WithConnectionEventHandler(func(event ConnectionEvent) {
+		mux.Lock()
+		defer mux.Unlock()
+		events = append(events, event)
+	}))
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	defer cli.Close()
+
+	op := Operation{
+		Name:       "select",
+		Table:      "Logical_Switch",
+		Conditions: []Condition{{Column: "name", Function: "==", Value: "ls1", Type: "unknown"}},
+	}
+	_, err = cli.TransactOperation("OVN_Northbound", op)
+	if err == nil || errors.Is(err, ErrConnectionLost) {
+		t.Fatalf("FAIL: expected encoding error, but got: %v", err)
+	}
+	t.Logf("PASS: expected to fail, failed with: %v", err)
+	if err := cli.Echo("ping"); err != nil {
+		t.Fatalf("FAIL: expected the connection to be intact, but failed: %v", err)
+	}
+	select {
+	case req := <-transacts:
+		t.Fatalf("FAIL: expected the invalid request not to be sent, but received: %v", req.Params)
+	default:
+	}
+	mux.Lock()
+	defer mux.Unlock()
+	if len(events) != 1 || events[0].State != StateConnected {
+		t.Fatalf("FAIL: expected a single connection, but got events: %v", events)
+	}
+	t.Logf("PASS: encoding error failed only its request")
+}
@@ -0,0 +1,110 @@
+// Copyright 2018 Paul Greenberg (greenpau@outlook.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command ovsdb-modelgen generates Go models of the tables of an OVSDB
+// database from its schema. The schema is read from an .ovsschema file,
+// from the output of `ovsdb-client get-schema`, or from a server:
+//
+//	ovsdb-modelgen -schema vswitch.ovsschema -package vswitch -o vswitch/models.go
+//	ovsdb-modelgen -endpoint unix:/run/ovn/ovnnb_db.sock -db OVN_Northbound -package nb
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/greenpau/ovsdb"
+)
+
+func main() {
+	var schemaPath, endpoint, db, pkg, output string
+	flag.StringVar(&schemaPath, "schema", "", "path to the schema file, or - for standard input")
+	flag.StringVar(&endpoint, "endpoint", "", "remote of the server providing the schema, e.g. unix:/var/run/openvswitch/db.sock")
+	flag.StringVar(&db, "db", "", "name of the database on the server")
+	flag.StringVar(&pkg, "package", "", "name of the generated package, defaults to the lowercase name of the database")
+	flag.StringVar(&output, "o", "", "path to the generated file, defaults to standard output")
+	flag.Parse()
+
+	schema, err := loadSchema(schemaPath, endpoint, db)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ovsdb-modelgen: %v\n", err)
+		os.Exit(1)
+	}
+	if pkg == "" {
+		pkg = strings.ToLower(strings.ReplaceAll(schema.Name, "_", ""))
+	}
+	src, err := ovsdb.GenerateModels(schema, pkg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ovsdb-modelgen: %v\n", err)
+		os.Exit(1)
+	}
+	if output == "" {
+		os.Stdout.Write(src)
+		return
+	}
+	if err := os.WriteFile(output, src, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "ovsdb-modelgen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func loadSchema(path, endpoint, db string) (ovsdb.Schema, error) {
+	switch {
+	case path != "" && endpoint != "":
+		return ovsdb.Schema{}, fmt.Errorf("-schema and -endpoint options are mutually exclusive")
+	case path != "":
+		return readSchema(path)
+	case endpoint != "":
+		if db == "" {
+			return ovsdb.Schema{}, fmt.Errorf("-db option is required with -endpoint option")
+		}
+		cli, err := ovsdb.NewClient(endpoint)
+		if err != nil {
+			return ovsdb.Schema{}, err
+		}
+		defer cli.Close()
+		return cli.GetSchema(db)
+	}
+	return ovsdb.Schema{}, fmt.Errorf("either -schema or -endpoint option is required")
+}
+
+// readSchema reads a schema, or a JSON-RPC response to "get_schema"
+// request, from a file.
+func readSchema(path string) (ovsdb.Schema, error) {
+	var schema ovsdb.Schema
+	var b []byte
+	var err error
+	if path == "-" {
+		b, err = io.ReadAll(os.Stdin)
+	} else {
+		b, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return schema, err
+	}
+	var response struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(b, &response); err == nil && len(response.Result) > 0 {
+		b = response.Result
+	}
+	if err := json.Unmarshal(b, &schema); err != nil {
+		return schema, fmt.Errorf("failed to decode schema %s: %v", path, err)
+	}
+	return schema, nil
+}
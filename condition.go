@@ -15,110 +15,343 @@
 package ovsdb
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
-	//"github.com/davecgh/go-spew/spew"
+	"strconv"
 	"strings"
 )
 
 // Condition represents condition for select operation,
 // as described in [Notation](https://tools.ietf.org/html/rfc7047#section-5.1) section.
-// The meaning of the <function> depends on the type of <column>.
+// The meaning of the <function> depends on the type of <column>: "==" and
+// "!=" apply to all columns, "includes" and "excludes" test the elements of
+// sets and the pairs of maps, and "<", "<=", ">", and ">=" compare integers
+// and reals.
+//
+// The Value is an atom, i.e. string, integer, real, bool, UUID, or
+// NamedUUID, a set, e.g. OvsSet or a slice, or a map, e.g. OvsMap or a Go
+// map. The Type, when not empty, converts the Value into "string", "uuid",
+// "boolean", "integer", "real", "set", or "map" value, e.g. a string holding
+// a UUID into UUID. When the schema of the database is cached, the client
+// converts the Value into the type of the column before sending it.
 type Condition struct {
 	Column   string
 	Function string
-	Value    string
+	Value    interface{}
 	Type     string
 }
 
-// NewCondition - DOCS-TBD
+// conditionFunctions are the <function>s of the conditions, as described
+// in https://tools.ietf.org/html/rfc7047#section-5.1.
+var conditionFunctions = map[string]bool{
+	"==":       true,
+	"!=":       true,
+	"includes": true,
+	"excludes": true,
+	"<":        true,
+	"<=":       true,
+	">":        true,
+	">=":       true,
+}
+
+// conditionOperators are the functions written as operators, ordered such
+// that the operators precede their prefixes.
+var conditionOperators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+// NewUUIDCondition returns a condition matching the row with the UUID,
+// e.g. for updating or deleting the row.
+func NewUUIDCondition(uuid string) Condition {
+	return Condition{
+		Column:   "_uuid",
+		Function: "==",
+		Value:    uuid,
+		Type:     "uuid",
+	}
+}
+
+// NewCondition returns a condition from the tokens of a query, e.g.
+// `name=="ls1"`, `tunnel_key>5`, `up==true`, or
+// `ports includes ["uuid","36bd4ba1-0d47-4d6a-b8b4-6ad5e7a1b3e1"]`. The
+// quoted values are strings, and the JSON arrays are <value>s, e.g. sets.
+// The other values are Barewords, converted into the type of the column,
+// when known, or into booleans, integers, reals, or strings otherwise.
 func NewCondition(s []string) (Condition, error) {
 	c := Condition{}
+	for i, token := range s {
+		if token != "includes" && token != "excludes" {
+			continue
+		}
+		if i == 0 || i == len(s)-1 {
+			return c, fmt.Errorf("invalid condition: '%s'", strings.Join(s, " "))
+		}
+		c.Column = strings.Join(s[:i], "")
+		c.Function = token
+		c.Value = strings.Join(s[i+1:], "")
+		c.parseValue()
+		return c, nil
+	}
 	if err := c.Parse(strings.Join(s, "")); err != nil {
 		return c, err
 	}
-	if strings.Contains(c.Column, "uuid") {
-		c.Type = "string"
-	}
-	if c.Value == "true" || c.Value == "false" {
-		c.Type = "bool"
-	}
-	if len(c.Value) >= 2 {
-		value := strings.Trim(c.Value, "\"")
-		if len(value) == len(c.Value)-2 {
-			c.Value = value
-			c.Type = "string"
-		}
-	}
 	return c, nil
 }
 
 // Parse - DOCS-TBD
 func (c *Condition) Parse(s string) error {
-	data := []byte(s)
-	functions := [][]byte{
-		[]byte("<="),
-		[]byte("=="),
-		[]byte("!="),
-		[]byte(">="),
-		[]byte("=~"),
-		[]byte(`>`),
-		[]byte(`<`),
-	}
-	for offset := range data {
-		for _, f := range functions {
-			n := len(f)
-			if offset < n {
+	for _, f := range []string{"includes", "excludes"} {
+		fields := strings.Fields(s)
+		for i, field := range fields {
+			if field == f && i > 0 && i < len(fields)-1 {
+				c.Column = strings.Join(fields[:i], "")
+				c.Function = f
+				c.Value = strings.Join(fields[i+1:], " ")
+				c.parseValue()
+				return nil
+			}
+		}
+	}
+	for offset := 1; offset < len(s); offset++ {
+		for _, f := range conditionOperators {
+			if !strings.HasPrefix(s[offset:], f) {
 				continue
 			}
-			if bytes.Equal(data[offset:offset+n], f) {
-				//spew.Dump("***")
-				//spew.Dump(data[n+1])
-				//spew.Dump(f)
-				//spew.Dump(data[offset : offset+n])
-				v := string(data[offset+n:])
-				if v == "" {
-					return fmt.Errorf("invalid condition: '%s'", s)
-				}
-				c.Column = string(data[0:offset])
-				c.Function = string(f[:])
-				c.Value = v
-				//spew.Dump(c)
-				return nil
+			v := s[offset+len(f):]
+			if v == "" {
+				return fmt.Errorf("invalid condition: '%s'", s)
 			}
+			c.Column = strings.TrimSpace(s[:offset])
+			c.Function = f
+			c.Value = strings.TrimSpace(v)
+			c.parseValue()
+			return nil
 		}
 	}
-	//spew.Dump(c)
 	return fmt.Errorf("invalid condition: '%s'", s)
 }
 
-// MarshalJSON - DOCS-TBD
-func (c Condition) MarshalJSON() ([]byte, error) {
-	b := bytes.NewBuffer([]byte("["))
-	column, err := json.Marshal(c.Column)
-	if err != nil {
-		return []byte{}, fmt.Errorf("marshal Condition.Column: %s", err)
+// parseValue converts the text of the value of the condition into a typed
+// value, see NewCondition.
+func (c *Condition) parseValue() {
+	text, ok := c.Value.(string)
+	if !ok {
+		return
 	}
-	b.Write(column)
-	b.WriteString(",")
-	function, err := json.Marshal(c.Function)
-	if err != nil {
-		return []byte{}, fmt.Errorf("marshal Condition.Function: %s", err)
+	if len(text) >= 2 && text[0] == '"' && text[len(text)-1] == '"' {
+		if s, err := strconv.Unquote(text); err == nil {
+			c.Value = s
+		} else {
+			c.Value = text[1 : len(text)-1]
+		}
+		c.Type = "string"
+		return
 	}
+	if strings.HasPrefix(text, "[") {
+		if datum, err := unmarshalDatum([]byte(text)); err == nil {
+			c.Value = datum
+		}
+		return
+	}
+	c.Value = Bareword(text)
+}
 
-	b.Write(function)
-	b.WriteString(",")
+// Datum returns the value of the condition in the typed model, see
+// DecodeDatum, converted into the Type, if any.
+func (c Condition) Datum() (interface{}, error) {
+	if w, ok := c.Value.(Bareword); ok && c.Type != "" {
+		c.Value = string(w)
+	}
 	switch c.Type {
+	case "":
+		return normalizeDatum(c.Value)
 	case "string":
-		value, err := json.Marshal(c.Value)
+		if s, ok := c.Value.(string); ok {
+			return s, nil
+		}
+	case "uuid":
+		switch v := c.Value.(type) {
+		case string:
+			return UUID(v), nil
+		case UUID, NamedUUID:
+			return v, nil
+		}
+	case "bool", "boolean":
+		switch v := c.Value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			if b, err := strconv.ParseBool(v); err == nil {
+				return b, nil
+			}
+		}
+	case "integer":
+		switch v := c.Value.(type) {
+		case string:
+			if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+				return i, nil
+			}
+		default:
+			if datum, err := normalizeDatum(v); err == nil {
+				if i, ok := datum.(int64); ok {
+					return i, nil
+				}
+			}
+		}
+	case "real":
+		switch v := c.Value.(type) {
+		case string:
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				return f, nil
+			}
+		default:
+			if datum, err := normalizeDatum(v); err == nil {
+				switch x := datum.(type) {
+				case int64:
+					return float64(x), nil
+				case float64:
+					return x, nil
+				}
+			}
+		}
+	case "set":
+		datum, err := normalizeDatum(c.Value)
 		if err != nil {
-			return []byte{}, fmt.Errorf("marshal Condition.Value: %s", err)
+			return nil, err
+		}
+		if _, ok := datum.(OvsMap); !ok {
+			return datumSet(datum), nil
+		}
+	case "map":
+		datum, err := normalizeDatum(c.Value)
+		if err != nil {
+			return nil, err
+		}
+		switch x := datum.(type) {
+		case OvsMap:
+			return x, nil
+		case OvsSet:
+			if len(x) == 0 {
+				return OvsMap{}, nil
+			}
 		}
-		b.Write(value)
 	default:
-		return []byte{}, fmt.Errorf("marshal Condition.Value: no support for '%s' type", c.Type)
+		return nil, fmt.Errorf("no support for '%s' type", c.Type)
+	}
+	return nil, fmt.Errorf("%v is not %s value", c.Value, c.Type)
+}
+
+// MarshalJSON encodes the condition as [column, function, value] array.
+func (c Condition) MarshalJSON() ([]byte, error) {
+	datum, err := c.Datum()
+	if err != nil {
+		return []byte{}, fmt.Errorf("marshal Condition.Value: %s", err)
+	}
+	value, err := encodeDatum(datum)
+	if err != nil {
+		return []byte{}, fmt.Errorf("marshal Condition.Value: %s", err)
+	}
+	return json.Marshal([]interface{}{c.Column, c.Function, value})
+}
+
+// resolveCondition converts the value of a condition into the type of its
+// column, see resolveDatum. The string values with empty Type are
+// converted into integers, reals, and booleans too.
+func resolveCondition(ct ColumnType, c Condition) Condition {
+	datum, err := c.Datum()
+	if err != nil {
+		return c
+	}
+	datum = resolveDatum(ct, datum, c.Type == "")
+	switch datum.(type) {
+	case OvsMap:
+		c.Type = "map"
+	case OvsSet:
+		c.Type = "set"
+	default:
+		c.Type = ""
+	}
+	c.Value = datum
+	return c
+}
+
+// resolveDatum converts a datum into the type of a column, see resolveAtom,
+// or the empty set into the empty map. The datum not matching the column
+// is returned as is.
+func resolveDatum(ct ColumnType, datum interface{}, untyped bool) interface{} {
+	switch x := datum.(type) {
+	case OvsMap:
+		if !ct.IsMap() {
+			return datum
+		}
+		m := OvsMap{}
+		for k, v := range x {
+			m[resolveAtom(ct.Key, k, untyped)] = resolveAtom(*ct.Value, v, untyped)
+		}
+		return m
+	case OvsSet:
+		if ct.IsMap() && len(x) == 0 {
+			return OvsMap{}
+		}
+		// The sets of the keys of the maps are valid values of "delete"
+		// mutator.
+		s := OvsSet{}
+		for _, e := range x {
+			s = append(s, resolveAtom(ct.Key, e, untyped))
+		}
+		return s
+	}
+	if ct.IsMap() {
+		return datum
+	}
+	return resolveAtom(ct.Key, datum, untyped)
+}
+
+// resolveAtom converts an atom into a base type, e.g. a string into UUID,
+// an integer into real, or the Bareword 007 into the string "007" or the
+// integer 7. When untyped, the strings are converted into integers, reals,
+// and booleans too. The integers, reals, and booleans are never converted
+// into strings, so that they fail the validation of the string columns.
+// The atom not matching the type is returned as is.
+func resolveAtom(b BaseType, atom interface{}, untyped bool) interface{} {
+	original := atom
+	if w, ok := atom.(Bareword); ok {
+		atom, untyped = string(w), true
+		switch b.Type {
+		case "string":
+			return string(w)
+		case "uuid":
+			return UUID(w)
+		}
+	}
+	switch b.Type {
+	case "uuid":
+		if s, ok := atom.(string); ok {
+			return UUID(s)
+		}
+	case "real":
+		if i, ok := atom.(int64); ok {
+			return float64(i)
+		}
+	}
+	if !untyped {
+		return atom
+	}
+	s, ok := atom.(string)
+	if !ok {
+		return atom
+	}
+	switch b.Type {
+	case "integer":
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i
+		}
+	case "real":
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if v, err := strconv.ParseBool(s); err == nil {
+			return v
+		}
 	}
-	b.WriteString("]")
-	return b.Bytes(), nil
+	return original
 }
@@ -15,7 +15,10 @@
 package ovsdb
 
 import (
+	"encoding/json"
+	"fmt"
 	//"github.com/davecgh/go-spew/spew"
+	"strings"
 	"testing"
 )
 
@@ -47,7 +50,7 @@ func TestConditionParse(t *testing.T) {
 				continue
 			}
 		}
-		if condition.Column != test.column || condition.Function != test.function || condition.Value != test.value {
+		if condition.Column != test.column || condition.Function != test.function || fmt.Sprint(condition.Value) != test.value {
 			if !test.shouldFail {
 				t.Logf("FAIL: Test %d: condition '%s', expected to fail, but passed: %v", i, test.condition, condition)
 			}
@@ -62,3 +65,215 @@ func TestConditionParse(t *testing.T) {
 		t.Fatalf("Failed %d tests", testFailed)
 	}
 }
+
+// marshalUnescaped encodes the value and decodes it back, such that the
+// escaped "<" and ">" compare equal to the expected JSON.
+func marshalUnescaped(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	enc := json.NewEncoder(&sb)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(decoded); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+func TestConditionValues(t *testing.T) {
+	for i, test := range []struct {
+		condition string
+		expected  string
+		shouldErr bool
+	}{
+		{condition: `name=="ls1"`, expected: `["name","==","ls1"]`},
+		{condition: `name!=ls1`, expected: `["name","!=","ls1"]`},
+		{condition: `up==true`, expected: `["up","==",true]`},
+		{condition: `tunnel_key>5`, expected: `["tunnel_key",">",5]`},
+		{condition: `tunnel_key<=5`, expected: `["tunnel_key","<=",5]`},
+		{condition: `priority>=1.5`, expected: `["priority",">=",1.5]`},
+		{condition: `name=="a==b"`, expected: `["name","==","a==b"]`},
+		{condition: `_uuid==["uuid","36bd4ba1-0d47-4d6a-b8b4-6ad5e7a1b3e1"]`, expected: `["_uuid","==",["uuid","36bd4ba1-0d47-4d6a-b8b4-6ad5e7a1b3e1"]]`},
+		{condition: `addresses includes ["set",["router"]]`, expected: `["addresses","includes",["set",["router"]]]`},
+		{condition: `external_ids excludes ["map",[["owner","neutron"]]]`, expected: `["external_ids","excludes",["map",[["owner","neutron"]]]]`},
+		{condition: `name=~ls`, shouldErr: true},
+		{condition: `==ls1`, shouldErr: true},
+		{condition: `name`, shouldErr: true},
+	} {
+		condition, err := NewCondition([]string{test.condition})
+		if err != nil {
+			if !test.shouldErr {
+				t.Fatalf("FAIL: Test %d: condition '%s', expected to pass, but threw error: %v", i, test.condition, err)
+			}
+			t.Logf("PASS: Test %d: condition '%s', expected to throw error, threw: %v", i, test.condition, err)
+			continue
+		}
+		if test.shouldErr {
+			t.Fatalf("FAIL: Test %d: condition '%s', expected to throw error, but passed: %v", i, test.condition, condition)
+		}
+		b, err := marshalUnescaped(condition)
+		if err != nil {
+			t.Fatalf("FAIL: Test %d: condition '%s': %v", i, test.condition, err)
+		}
+		if b != test.expected {
+			t.Fatalf("FAIL: Test %d: condition '%s', expected %s, but got %s", i, test.condition, test.expected, b)
+		}
+		t.Logf("PASS: Test %d: condition '%s' encoded as %s", i, test.condition, b)
+	}
+
+	b, err := json.Marshal(Condition{Column: "tag", Function: "==", Value: "5", Type: "integer"})
+	if err != nil || string(b) != `["tag","==",5]` {
+		t.Fatalf("FAIL: expected the value converted into integer, but got %s: %v", b, err)
+	}
+	if _, err := json.Marshal(Condition{Column: "tag", Function: "==", Value: "five", Type: "integer"}); err == nil {
+		t.Fatalf("FAIL: expected invalid integer to fail")
+	}
+	t.Logf("PASS: converted the value into the type of the condition")
+}
+
+func TestResolveCondition(t *testing.T) {
+	schema := newTestSchema(t, testNorthboundSchema)
+	for i, test := range []struct {
+		table     string
+		condition Condition
+		expected  string
+	}{
+		{
+			table:     "Logical_Switch_Port",
+			condition: Condition{Column: "_uuid", Function: "==", Value: "36bd4ba1-0d47-4d6a-b8b4-6ad5e7a1b3e1", Type: "string"},
+			expected:  `["_uuid","==",["uuid","36bd4ba1-0d47-4d6a-b8b4-6ad5e7a1b3e1"]]`,
+		},
+		{
+			table:     "Logical_Switch_Port",
+			condition: Condition{Column: "up", Function: "==", Value: "true"},
+			expected:  `["up","==",true]`,
+		},
+		{
+			table:     "Logical_Switch_Port",
+			condition: Condition{Column: "tag", Function: "<", Value: "100"},
+			expected:  `["tag","<",100]`,
+		},
+		{
+			table:     "Logical_Switch",
+			condition: Condition{Column: "ports", Function: "includes", Value: []string{"36bd4ba1-0d47-4d6a-b8b4-6ad5e7a1b3e1"}},
+			expected:  `["ports","includes",["set",[["uuid","36bd4ba1-0d47-4d6a-b8b4-6ad5e7a1b3e1"]]]]`,
+		},
+		{
+			table:     "Logical_Switch",
+			condition: Condition{Column: "external_ids", Function: "==", Value: OvsSet{}},
+			expected:  `["external_ids","==",["map",[]]]`,
+		},
+		{
+			table:     "Logical_Switch",
+			condition: Condition{Column: "external_ids", Function: "includes", Value: map[string]string{"owner": "neutron"}},
+			expected:  `["external_ids","includes",["map",[["owner","neutron"]]]]`,
+		},
+	} {
+		ops := schema.resolveOperations([]Operation{{Name: "select", Table: test.table, Conditions: []Condition{test.condition}}})
+		b, err := marshalUnescaped(ops[0].Conditions[0])
+		if err != nil {
+			t.Fatalf("FAIL: Test %d: %v", i, err)
+		}
+		if b != test.expected {
+			t.Fatalf("FAIL: Test %d: expected %s, but got %s", i, test.expected, b)
+		}
+		if err := schema.ValidateOperation(ops[0]); err != nil {
+			t.Fatalf("FAIL: Test %d: expected the resolved condition to be valid: %v", i, err)
+		}
+		t.Logf("PASS: Test %d: resolved %v into %s", i, test.condition, b)
+	}
+	ops := schema.resolveOperations([]Operation{{
+		Name:       "select",
+		Table:      "Logical_Switch_Port",
+		Conditions: []Condition{{Column: "name", Function: "==", Value: int64(100)}},
+	}})
+	if b, err := marshalUnescaped(ops[0].Conditions[0]); err != nil || b != `["name","==",100]` {
+		t.Fatalf("FAIL: expected the integer to remain an integer, but got %s: %v", b, err)
+	}
+	if err := schema.ValidateOperation(ops[0]); err == nil {
+		t.Fatalf("FAIL: expected the integer in the string column to be invalid")
+	}
+	t.Logf("PASS: rejected the integer in the string column")
+	for i, test := range []struct {
+		query    string
+		expected string
+	}{
+		{query: "SELECT * FROM Logical_Switch WHERE name==123", expected: `["name","==","123"]`},
+		{query: "SELECT * FROM Logical_Switch WHERE name==1.50", expected: `["name","==","1.50"]`},
+		{query: "SELECT * FROM Logical_Switch WHERE name==007", expected: `["name","==","007"]`},
+		{query: "SELECT * FROM Logical_Switch_Port WHERE tag==007", expected: `["tag","==",7]`},
+		{query: "SELECT * FROM Logical_Switch WHERE name==true", expected: `["name","==","true"]`},
+		{query: "SELECT * FROM Logical_Switch WHERE external_ids includes {a=1}", expected: `["external_ids","includes",["map",[["a","1"]]]]`},
+		{query: "SELECT * FROM Logical_Switch_Port WHERE addresses includes [1, router]", expected: `["addresses","includes",["set",["1","router"]]]`},
+	} {
+		op, err := NewOperation(test.query)
+		if err != nil {
+			t.Fatalf("FAIL: Test %d: %v", i, err)
+		}
+		ops := schema.resolveOperations([]Operation{op})
+		b, err := marshalUnescaped(ops[0].Conditions[0])
+		if err != nil {
+			t.Fatalf("FAIL: Test %d: %v", i, err)
+		}
+		if b != test.expected {
+			t.Fatalf("FAIL: Test %d: expected %s, but got %s", i, test.expected, b)
+		}
+		if err := schema.ValidateOperation(ops[0]); err != nil {
+			t.Fatalf("FAIL: Test %d: expected the resolved condition to be valid: %v", i, err)
+		}
+		t.Logf("PASS: Test %d: resolved '%s' into %s", i, test.query, b)
+	}
+}
+
+func TestMatchCachedRow(t *testing.T) {
+	row := Row{
+		"name":      "lsp1",
+		"tag":       float64(100),
+		"up":        []interface{}{"set", []interface{}{}},
+		"addresses": []interface{}{"set", []interface{}{"router", "dynamic"}},
+		"external_ids": []interface{}{"map", []interface{}{
+			[]interface{}{"owner", "neutron"},
+			[]interface{}{"zone", "az1"},
+		}},
+	}
+	for i, test := range []struct {
+		condition Condition
+		expected  bool
+	}{
+		{condition: Condition{Column: "name", Function: "==", Value: "lsp1"}, expected: true},
+		{condition: Condition{Column: "name", Function: "!=", Value: "lsp1"}, expected: false},
+		{condition: Condition{Column: "tag", Function: "==", Value: int64(100)}, expected: true},
+		{condition: Condition{Column: "tag", Function: ">", Value: int64(99)}, expected: true},
+		{condition: Condition{Column: "tag", Function: "<=", Value: 99.5}, expected: false},
+		{condition: Condition{Column: "up", Function: "==", Value: true}, expected: false},
+		{condition: Condition{Column: "up", Function: "==", Value: OvsSet{}}, expected: true},
+		{condition: Condition{Column: "addresses", Function: "==", Value: []string{"dynamic", "router"}}, expected: true},
+		{condition: Condition{Column: "addresses", Function: "includes", Value: "router"}, expected: true},
+		{condition: Condition{Column: "addresses", Function: "excludes", Value: []string{"router", "unknown"}}, expected: false},
+		{condition: Condition{Column: "external_ids", Function: "includes", Value: OvsMap{"zone": "az1"}}, expected: true},
+		{condition: Condition{Column: "external_ids", Function: "excludes", Value: OvsMap{"zone": "az2"}}, expected: true},
+		{condition: Condition{Column: "external_ids", Function: "==", Value: OvsMap{"zone": "az1"}}, expected: false},
+		{condition: Condition{Column: "name", Function: "==", Value: Bareword("lsp1")}, expected: true},
+		{condition: Condition{Column: "tag", Function: ">=", Value: Bareword("0100")}, expected: true},
+		{condition: Condition{Column: "external_ids", Function: "includes", Value: OvsMap{Bareword("zone"): Bareword("az1")}}, expected: true},
+	} {
+		matched, ok := matchCachedRow(row, test.condition)
+		if !ok {
+			t.Fatalf("FAIL: Test %d: expected %v to be evaluated", i, test.condition)
+		}
+		if matched != test.expected {
+			t.Fatalf("FAIL: Test %d: expected %v to match %t, but got %t", i, test.condition, test.expected, matched)
+		}
+		t.Logf("PASS: Test %d: %v matched %t", i, test.condition, matched)
+	}
+	if _, ok := matchCachedRow(row, Condition{Column: "name", Function: "<", Value: int64(1)}); ok {
+		t.Fatalf("FAIL: expected the comparison of strings not to be evaluated")
+	}
+	t.Logf("PASS: the comparison of strings was not evaluated")
+}
@@ -15,6 +15,7 @@
 package ovsdb
 
 import (
+	"context"
 	"fmt"
 )
 
@@ -48,21 +49,33 @@ type OvsDatabase struct {
 
 // Databases - DOCS-TBD
 func (c *Client) Databases() ([]string, error) {
+	return c.DatabasesContext(context.Background())
+}
+
+// DatabasesContext is like Databases, but honors the cancellation and the
+// deadline of the context.
+func (c *Client) DatabasesContext(ctx context.Context) ([]string, error) {
 	method := "list_dbs"
-	response, err := c.query(method, nil)
+	response, err := c.queryContext(ctx, method, nil)
 	if err != nil {
-		return nil, fmt.Errorf("'%s' method failed: %v", method, err)
+		return nil, fmt.Errorf("'%s' method failed: %w", method, err)
 	}
 	dbs, err := response.Databases()
 	if err != nil {
-		return nil, fmt.Errorf("'%s' method failed: %v", method, err)
+		return nil, fmt.Errorf("'%s' method failed: %w", method, err)
 	}
 	return dbs, nil
 }
 
 // DatabaseExists - DOCS-TBD
 func (c *Client) DatabaseExists(dbName string) error {
-	databases, err := c.Databases()
+	return c.DatabaseExistsContext(context.Background(), dbName)
+}
+
+// DatabaseExistsContext is like DatabaseExists, but honors the
+// cancellation and the deadline of the context.
+func (c *Client) DatabaseExistsContext(ctx context.Context, dbName string) error {
+	databases, err := c.DatabasesContext(ctx)
 	if err != nil {
 		return err
 	}
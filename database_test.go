@@ -19,7 +19,7 @@ import (
 )
 
 func TestListDatabasesMethod(t *testing.T) {
-	cli, err := NewClient("unix:/var/run/openvswitch/db.sock", 0)
+	cli, err := NewClient("unix:/var/run/openvswitch/db.sock")
 	if err != nil {
 		t.Fatalf("FAIL: %v", err)
 	}
@@ -39,7 +39,7 @@ func TestListDatabasesMethod(t *testing.T) {
 }
 
 func TestDatabaseExist(t *testing.T) {
-	cli, err := NewClient("unix:/var/run/openvswitch/db.sock", 0)
+	cli, err := NewClient("unix:/var/run/openvswitch/db.sock")
 	if err != nil {
 		t.Fatalf("FAIL: %v", err)
 	}
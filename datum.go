@@ -0,0 +1,400 @@
+// Copyright 2018 Paul Greenberg (greenpau@outlook.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovsdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// UUID is a reference to a row, encoded as ["uuid", "<uuid>"].
+type UUID string
+
+// MarshalJSON encodes the UUID per RFC 7047 section 5.1.
+func (u UUID) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]string{"uuid", string(u)})
+}
+
+// UnmarshalJSON decodes ["uuid", "<uuid>"].
+func (u *UUID) UnmarshalJSON(b []byte) error {
+	s, err := unmarshalTagged(b, "uuid")
+	if err != nil {
+		return err
+	}
+	*u = UUID(s)
+	return nil
+}
+
+// NamedUUID is a reference to a row inserted by an operation with
+// the same "uuid-name" in the same transaction, encoded as
+// ["named-uuid", "<id>"].
+type NamedUUID string
+
+// MarshalJSON encodes the NamedUUID per RFC 7047 section 5.1.
+func (u NamedUUID) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]string{"named-uuid", string(u)})
+}
+
+// UnmarshalJSON decodes ["named-uuid", "<id>"].
+func (u *NamedUUID) UnmarshalJSON(b []byte) error {
+	s, err := unmarshalTagged(b, "named-uuid")
+	if err != nil {
+		return err
+	}
+	*u = NamedUUID(s)
+	return nil
+}
+
+// Bareword is an unquoted atom of a query, e.g. 100, true, or br-int. It
+// keeps the text of the atom, e.g. 007, until the client converts it into
+// the type of its column. Without the type of the column, it is encoded as
+// the boolean, the integer, or the real it looks like, or as a string.
+type Bareword string
+
+// MarshalJSON encodes the Bareword as the atom it looks like.
+func (w Bareword) MarshalJSON() ([]byte, error) {
+	return json.Marshal(barewordValue(string(w)))
+}
+
+func unmarshalTagged(b []byte, tag string) (string, error) {
+	var v []string
+	if err := json.Unmarshal(b, &v); err != nil || len(v) != 2 || v[0] != tag {
+		return "", fmt.Errorf("invalid %s: %s", tag, b)
+	}
+	return v[1], nil
+}
+
+// OvsSet represents <set>, as described in RFC 7047 section 5.1. The
+// elements are atoms: string, int64, float64, bool, UUID, or NamedUUID.
+type OvsSet []interface{}
+
+// MarshalJSON encodes the set as ["set", [<atom>...]].
+func (s OvsSet) MarshalJSON() ([]byte, error) {
+	elements := []interface{}{}
+	for _, e := range s {
+		atom, err := encodeAtom(e)
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, atom)
+	}
+	return json.Marshal([]interface{}{"set", elements})
+}
+
+// UnmarshalJSON decodes ["set", [<atom>...]], or a single atom, which
+// represents a set with exactly one element.
+func (s *OvsSet) UnmarshalJSON(b []byte) error {
+	v, err := unmarshalDatum(b)
+	if err != nil {
+		return err
+	}
+	switch d := v.(type) {
+	case OvsSet:
+		*s = d
+	case OvsMap:
+		return fmt.Errorf("invalid set: %s", b)
+	default:
+		*s = OvsSet{d}
+	}
+	return nil
+}
+
+// OvsMap represents <map>, as described in RFC 7047 section 5.1. The keys
+// and the values are atoms: string, int64, float64, bool, UUID, or
+// NamedUUID.
+type OvsMap map[interface{}]interface{}
+
+// MarshalJSON encodes the map as ["map", [[<atom>, <atom>]...]]. The
+// pairs are sorted by their keys.
+func (m OvsMap) MarshalJSON() ([]byte, error) {
+	keys := []interface{}{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+	})
+	pairs := []interface{}{}
+	for _, k := range keys {
+		ek, err := encodeAtom(k)
+		if err != nil {
+			return nil, err
+		}
+		ev, err := encodeAtom(m[k])
+		if err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, []interface{}{ek, ev})
+	}
+	return json.Marshal([]interface{}{"map", pairs})
+}
+
+// UnmarshalJSON decodes ["map", [[<atom>, <atom>]...]].
+func (m *OvsMap) UnmarshalJSON(b []byte) error {
+	v, err := unmarshalDatum(b)
+	if err != nil {
+		return err
+	}
+	d, ok := v.(OvsMap)
+	if !ok {
+		return fmt.Errorf("invalid map: %s", b)
+	}
+	*m = d
+	return nil
+}
+
+// unmarshalDatum decodes <value>, preserving the integers.
+func unmarshalDatum(b []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return DecodeDatum(v)
+}
+
+// DecodeDatum converts <value>, as decoded by encoding/json, e.g. the
+// value of a column of a Row, into the typed model: an atom, OvsSet, or
+// OvsMap. The atoms are string, int64, float64, bool, UUID, or NamedUUID.
+// The numbers without a fractional part are integers.
+func DecodeDatum(value interface{}) (interface{}, error) {
+	v, ok := value.([]interface{})
+	if !ok {
+		return decodeAtom(value)
+	}
+	if len(v) != 2 {
+		return nil, fmt.Errorf("invalid value: %v", value)
+	}
+	tag, _ := v[0].(string)
+	switch tag {
+	case "set":
+		elements, ok := v[1].([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid set: %v", value)
+		}
+		s := OvsSet{}
+		for _, e := range elements {
+			atom, err := decodeAtom(e)
+			if err != nil {
+				return nil, err
+			}
+			s = append(s, atom)
+		}
+		return s, nil
+	case "map":
+		pairs, ok := v[1].([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid map: %v", value)
+		}
+		m := OvsMap{}
+		for _, p := range pairs {
+			pair, ok := p.([]interface{})
+			if !ok || len(pair) != 2 {
+				return nil, fmt.Errorf("invalid map pair: %v", p)
+			}
+			k, err := decodeAtom(pair[0])
+			if err != nil {
+				return nil, err
+			}
+			e, err := decodeAtom(pair[1])
+			if err != nil {
+				return nil, err
+			}
+			m[k] = e
+		}
+		return m, nil
+	}
+	return decodeAtom(value)
+}
+
+// decodeAtom converts <atom>.
+func decodeAtom(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string, bool, int64, UUID, NamedUUID:
+		return v, nil
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v), nil
+		}
+		return v, nil
+	case int:
+		return int64(v), nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, nil
+		}
+		return v.Float64()
+	case []interface{}:
+		if len(v) == 2 {
+			tag, _ := v[0].(string)
+			id, ok := v[1].(string)
+			if ok {
+				switch tag {
+				case "uuid":
+					return UUID(id), nil
+				case "named-uuid":
+					return NamedUUID(id), nil
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("unsupported atom: %v", value)
+}
+
+// datumSet returns the elements of a set. An atom is a set with exactly
+// one element.
+func datumSet(datum interface{}) OvsSet {
+	if s, ok := datum.(OvsSet); ok {
+		return s
+	}
+	return OvsSet{datum}
+}
+
+// datumUUIDs returns the sorted distinct UUIDs of a value, i.e. of the
+// elements of a set, or of the keys and the values of a map. With the
+// type of a reference column, only the keys and the values of the maps
+// referring to the rows of refTable are included.
+func datumUUIDs(value interface{}, ct *ColumnType, refTable string) []string {
+	if value == nil {
+		return nil
+	}
+	datum, err := DecodeDatum(value)
+	if err != nil {
+		return nil
+	}
+	var atoms []interface{}
+	if m, ok := datum.(OvsMap); ok {
+		for k, v := range m {
+			if ct == nil || ct.Key.RefTable == refTable {
+				atoms = append(atoms, k)
+			}
+			if ct == nil || ct.Value != nil && ct.Value.RefTable == refTable {
+				atoms = append(atoms, v)
+			}
+		}
+	} else {
+		atoms = datumSet(datum)
+	}
+	seen := make(map[string]bool)
+	uuids := []string{}
+	for _, atom := range atoms {
+		if id, ok := atom.(UUID); ok && !seen[string(id)] {
+			seen[string(id)] = true
+			uuids = append(uuids, string(id))
+		}
+	}
+	sort.Strings(uuids)
+	return uuids
+}
+
+// Datum returns the value of a column in the typed model. See DecodeDatum.
+func (r Row) Datum(column string) (interface{}, error) {
+	value, exists := r[column]
+	if !exists {
+		return nil, fmt.Errorf("column %s not found", column)
+	}
+	return DecodeDatum(value)
+}
+
+// encodeRow encodes the values of a row per RFC 7047 section 5.1.
+func encodeRow(row Row) (map[string]interface{}, error) {
+	r := make(map[string]interface{})
+	for column, value := range row {
+		v, err := encodeDatum(value)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %s", column, err)
+		}
+		r[column] = v
+	}
+	return r, nil
+}
+
+// encodeDatum encodes a Go value as <value>, as described in RFC 7047
+// section 5.1. The atoms are encoded as is. The slices are encoded as
+// sets and the maps are encoded as maps. The values already encoded,
+// e.g. []interface{}{"uuid", "..."}, are passed through. The UUID and
+// NamedUUID values are encoded as references, and the OvsSet and OvsMap
+// values encode themselves.
+func encodeDatum(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, fmt.Errorf("unsupported nil value")
+	case UUID, NamedUUID, Bareword, OvsSet, OvsMap:
+		return v, nil
+	case string, bool, float32, float64, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return v, nil
+	case []interface{}:
+		if len(v) == 2 {
+			if tag, ok := v[0].(string); ok {
+				switch tag {
+				case "uuid", "named-uuid", "set", "map":
+					return v, nil
+				}
+			}
+		}
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		elements := []interface{}{}
+		for i := 0; i < rv.Len(); i++ {
+			e, err := encodeAtom(rv.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, e)
+		}
+		return []interface{}{"set", elements}, nil
+	case reflect.Map:
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		pairs := []interface{}{}
+		for _, k := range keys {
+			ek, err := encodeAtom(k.Interface())
+			if err != nil {
+				return nil, err
+			}
+			ev, err := encodeAtom(rv.MapIndex(k).Interface())
+			if err != nil {
+				return nil, err
+			}
+			pairs = append(pairs, []interface{}{ek, ev})
+		}
+		return []interface{}{"map", pairs}, nil
+	}
+	return nil, fmt.Errorf("unsupported value type %T: %v", value, value)
+}
+
+// encodeAtom encodes an element of a set or a map.
+func encodeAtom(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case UUID, NamedUUID, Bareword, string, bool, float32, float64, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return v, nil
+	case []interface{}:
+		if len(v) == 2 {
+			if tag, ok := v[0].(string); ok && (tag == "uuid" || tag == "named-uuid") {
+				return v, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unsupported atom type %T: %v", value, value)
+}
@@ -0,0 +1,121 @@
+// Copyright 2018 Paul Greenberg (greenpau@outlook.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovsdb
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDatumJSON(t *testing.T) {
+	for i, test := range []struct {
+		value    interface{}
+		expected string
+	}{
+		{value: UUID("36bd4ba1-0d47-4d6a-b8b4-6ad5e7a1b3e1"), expected: `["uuid","36bd4ba1-0d47-4d6a-b8b4-6ad5e7a1b3e1"]`},
+		{value: NamedUUID("new_lsp"), expected: `["named-uuid","new_lsp"]`},
+		{value: OvsSet{}, expected: `["set",[]]`},
+		{value: OvsSet{int64(1), int64(2)}, expected: `["set",[1,2]]`},
+		{value: OvsSet{1.5, true, "a"}, expected: `["set",[1.5,true,"a"]]`},
+		{value: OvsSet{UUID("u1"), NamedUUID("n1")}, expected: `["set",[["uuid","u1"],["named-uuid","n1"]]]`},
+		{value: OvsMap{"b": "2", "a": "1"}, expected: `["map",[["a","1"],["b","2"]]]`},
+		{value: OvsMap{int64(10): UUID("u1")}, expected: `["map",[[10,["uuid","u1"]]]]`},
+	} {
+		b, err := json.Marshal(test.value)
+		if err != nil {
+			t.Fatalf("FAIL: Test %d: %v", i, err)
+		}
+		if string(b) != test.expected {
+			t.Fatalf("FAIL: Test %d: expected %s, but got %s", i, test.expected, b)
+		}
+		decoded := reflect.New(reflect.TypeOf(test.value))
+		if err := json.Unmarshal(b, decoded.Interface()); err != nil {
+			t.Fatalf("FAIL: Test %d: %v", i, err)
+		}
+		if !reflect.DeepEqual(decoded.Elem().Interface(), test.value) {
+			t.Fatalf("FAIL: Test %d: expected %#v, but decoded %#v", i, test.value, decoded.Elem().Interface())
+		}
+		t.Logf("PASS: Test %d: %s", i, b)
+	}
+
+	// A set with exactly one element may be encoded as the element.
+	var s OvsSet
+	if err := json.Unmarshal([]byte(`["uuid","u1"]`), &s); err != nil || !reflect.DeepEqual(s, OvsSet{UUID("u1")}) {
+		t.Fatalf("FAIL: expected a set with one element, but got %#v: %v", s, err)
+	}
+	var m OvsMap
+	if err := json.Unmarshal([]byte(`["set",[]]`), &m); err == nil {
+		t.Fatalf("FAIL: expected a set not to decode as a map")
+	}
+	t.Logf("PASS: decoded the short forms")
+}
+
+func TestRowDatum(t *testing.T) {
+	var result Result
+	b := []byte(`{"rows":[{
+		"_uuid":["uuid","u1"],
+		"name":"ls1",
+		"tag":["set",[100,200]],
+		"ports":["set",[["uuid","p1"],["uuid","p2"]]],
+		"port_map":["map",[["lsp1",["uuid","p1"]]]],
+		"load":0.5
+	}]}`)
+	if err := json.Unmarshal(b, &result); err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	row := result.Rows[0]
+	for column, expected := range map[string]interface{}{
+		"_uuid":    UUID("u1"),
+		"name":     "ls1",
+		"tag":      OvsSet{int64(100), int64(200)},
+		"ports":    OvsSet{UUID("p1"), UUID("p2")},
+		"port_map": OvsMap{"lsp1": UUID("p1")},
+		"load":     0.5,
+	} {
+		v, err := row.Datum(column)
+		if err != nil {
+			t.Fatalf("FAIL: column %s: %v", column, err)
+		}
+		if !reflect.DeepEqual(v, expected) {
+			t.Fatalf("FAIL: column %s: expected %#v, but got %#v", column, expected, v)
+		}
+	}
+	t.Logf("PASS: decoded the columns of a row")
+
+	v, dt, err := row.GetColumnValue("tag", nil)
+	if err != nil || dt != "[]integer" || !reflect.DeepEqual(v, []int{100, 200}) {
+		t.Fatalf("FAIL: expected a set of integers, but got %v (%s): %v", v, dt, err)
+	}
+	v, dt, err = row.GetColumnValue("port_map", nil)
+	if err != nil || dt != "map[string]string" || !reflect.DeepEqual(v, map[string]string{"lsp1": "p1"}) {
+		t.Fatalf("FAIL: expected a map with uuid values, but got %v (%s): %v", v, dt, err)
+	}
+	t.Logf("PASS: GetColumnValue decoded sets of integers and maps with uuid values")
+
+	op, err := NewInsertOperation("Logical_Switch", Row{"name": "ls2", "ports": OvsSet{NamedUUID("new_lsp")}, "other_config": OvsMap{"subnet": "10.0.0.0/24"}}, "")
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	b, err = json.Marshal(op)
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	expected := `{"op":"insert","table":"Logical_Switch","row":{"name":"ls2","other_config":["map",[["subnet","10.0.0.0/24"]]],"ports":["set",[["named-uuid","new_lsp"]]]}}`
+	if string(b) != expected {
+		t.Fatalf("FAIL: expected %s, but got %s", expected, b)
+	}
+	t.Logf("PASS: encoded the typed values in a write operation")
+}
@@ -15,23 +15,30 @@
 package ovsdb
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 )
 
 // Echo - TODO
 func (c *Client) Echo(s string) error {
+	return c.EchoContext(context.Background(), s)
+}
+
+// EchoContext is like Echo, but honors the cancellation and the deadline
+// of the context.
+func (c *Client) EchoContext(ctx context.Context, s string) error {
 	method := "echo"
 	js, err := encodeString(s)
 	if err != nil {
 		fmt.Errorf("'%s' method failed: %v", method, err)
 	}
-	response, err := c.query(method, js)
+	response, err := c.queryContext(ctx, method, js)
 	if err != nil {
-		return fmt.Errorf("'%s' method failed: %v", method, err)
+		return fmt.Errorf("'%s' method failed: %w", method, err)
 	}
 	if err := matchRequestResponse(s, response); err != nil {
-		return fmt.Errorf("'%s' method failed: %v", method, err)
+		return fmt.Errorf("'%s' method failed: %w", method, err)
 	}
 	return nil
 }
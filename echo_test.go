@@ -19,7 +19,7 @@ import (
 )
 
 func TestEchoMethod(t *testing.T) {
-	cli, err := NewClient("unix:/var/run/openvswitch/db.sock", 0)
+	cli, err := NewClient("unix:/var/run/openvswitch/db.sock")
 	if err != nil {
 		t.Fatalf("FAIL: %v", err)
 	}
@@ -16,6 +16,7 @@ package ovsdb
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	//"github.com/davecgh/go-spew/spew"
 	"io"
@@ -32,6 +33,15 @@ var methods = map[string]method{
 	"list_dbs":             {Name: "list_dbs"},
 	"get_schema":           {Name: "get_schema"},
 	"transact":             {Name: "transact"},
+	"cancel":               {Name: "cancel"},
+	"monitor":              {Name: "monitor"},
+	"monitor_cond":         {Name: "monitor_cond"},
+	"monitor_cond_change":  {Name: "monitor_cond_change"},
+	"monitor_cond_since":   {Name: "monitor_cond_since"},
+	"monitor_cancel":       {Name: "monitor_cancel"},
+	"lock":                 {Name: "lock"},
+	"steal":                {Name: "steal"},
+	"unlock":               {Name: "unlock"},
 	"list-commands":        {Name: "list-commands"},
 	"coverage/show":        {Name: "coverage/show"},
 	"memory/show":          {Name: "memory/show"},
@@ -72,21 +82,33 @@ func newEncodeState() *encodeState {
 
 func (enc *ovsdbEncoder) Encode(v interface{}) error {
 	//spew.Dump("ovsdbEncoder.Encode() - Start")
-	if enc.err != nil {
-		return enc.err
+	b, err := enc.Marshal(v)
+	if err != nil {
+		return err
 	}
+	return enc.Write(b)
+}
+
+// Marshal encodes a request without writing it. Unlike the failed writes,
+// the encoding errors, e.g. the invalid params of a request, do not affect
+// the subsequent requests.
+func (enc *ovsdbEncoder) Marshal(v interface{}) ([]byte, error) {
 	e := newEncodeState()
+	defer encodeStatePool.Put(e)
 	r := v.(*clientRequest)
 	if _, exists := methods[r.Method]; !exists {
-		err := fmt.Errorf("encoding error: unsupported method: %s", r.Method)
-		enc.err = err
-		return err
+		return nil, fmt.Errorf("encoding error: unsupported method: %s", r.Method)
 	}
 	//spew.Dump(r)
 	e.WriteByte('{')
 	if r.Method == "echo" && r.ID == 0 {
 		// handle inactivity probe
 		e.WriteString("\"id\":\"echo\",\"error\":null,\"result\":[]")
+	} else if r.Method == "cancel" {
+		// The "cancel" method is a notification. The params hold the id
+		// of the request being canceled.
+		e.WriteString("\"method\":\"cancel\",\"id\":null,")
+		e.WriteString("\"params\":[" + strconv.FormatUint(r.Params[0].(uint64), 10) + "]")
 	} else {
 		e.WriteString("\"method\":\"" + r.Method + "\",")
 		e.WriteString("\"id\":" + strconv.FormatUint(r.ID, 10) + ",")
@@ -106,10 +128,17 @@ func (enc *ovsdbEncoder) Encode(v interface{}) error {
 			t := r.Params[0].(Transaction)
 			s, err := t.ToString()
 			if err != nil {
-				return fmt.Errorf("encoding error: params handler: %s", r.Method)
+				return nil, fmt.Errorf("encoding error: params handler: %s: %v", r.Method, err)
 			}
 			e.WriteString(s)
 			// e.WriteString("\"Open_vSwitch\",{\"op\":\"select\",\"table\":\"Open_vSwitch\",\"where\":[]}")
+		case "monitor", "monitor_cond", "monitor_cond_change", "monitor_cond_since", "monitor_cancel", "lock", "steal", "unlock":
+			b, err := json.Marshal(r.Params[0])
+			if err != nil {
+				return nil, fmt.Errorf("encoding error: params handler: %s: %v", r.Method, err)
+			}
+			// The params are a JSON array, e.g. [db, id, requests].
+			e.Write(b[1 : len(b)-1])
 		case "list-commands":
 		case "coverage/show":
 		case "memory/show":
@@ -121,17 +150,24 @@ func (enc *ovsdbEncoder) Encode(v interface{}) error {
 			s := r.Params[0].(string)
 			e.WriteString("\"" + s + "\"")
 		default:
-			return fmt.Errorf("encoding error: params handler: %s", r.Method)
+			return nil, fmt.Errorf("encoding error: params handler: %s", r.Method)
 		}
 		e.WriteString("]")
 	}
 	e.WriteByte('}')
-	b := e.Bytes()
-	//spew.Dump(b)
-	_, err := enc.w.Write(b)
-	if err != nil {
+	//spew.Dump(e.Bytes())
+	return append([]byte{}, e.Bytes()...), nil
+}
+
+// Write writes an encoded request. Once a write fails, the encoder fails
+// all the subsequent writes.
+func (enc *ovsdbEncoder) Write(b []byte) error {
+	if enc.err != nil {
+		return enc.err
+	}
+	if _, err := enc.w.Write(b); err != nil {
 		enc.err = err
+		return err
 	}
-	encodeStatePool.Put(e)
-	return err
+	return nil
 }
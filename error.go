@@ -15,10 +15,85 @@
 package ovsdb
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"strings"
 )
 
-// Error - TODO
+// ErrTimeout is returned when the deadline of a request expires before the
+// server replies. It also matches context.DeadlineExceeded via errors.Is.
+var ErrTimeout error = timeoutError{}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string { return "request timed out" }
+
+// Timeout reports the error as a timeout, similar to net.Error.
+func (timeoutError) Timeout() bool { return true }
+
+func (timeoutError) Is(target error) bool {
+	return target == context.DeadlineExceeded
+}
+
+// contextError returns the error of a done context, with the deadline
+// expiration reported as ErrTimeout.
+func contextError(ctx context.Context) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return ErrTimeout
+	}
+	return ctx.Err()
+}
+
+// ErrConnectionLost is matched by the errors of the requests failed due to
+// the loss of the connection, e.g. a dead peer, via errors.Is.
+var ErrConnectionLost = errors.New("connection lost")
+
+// ErrClientClosed is the reason of the loss of the connection closed by
+// Client.Close. The requests made after Close fail with ConnectionError
+// wrapping it, rather than reconnecting.
+var ErrClientClosed = errors.New("client closed")
+
+// ConnectionError reports the loss of the connection to a remote.
+type ConnectionError struct {
+	Remote string
+	Err    error
+}
+
+func (e *ConnectionError) Error() string {
+	return fmt.Sprintf("connection to %s lost: %v", e.Remote, e.Err)
+}
+
+// Unwrap returns the reason of the loss of the connection.
+func (e *ConnectionError) Unwrap() error { return e.Err }
+
+// Is reports the error as ErrConnectionLost.
+func (e *ConnectionError) Is(target error) bool {
+	return target == ErrConnectionLost
+}
+
+// The errors reported by the server, as described in
+// https://tools.ietf.org/html/rfc7047#section-3.1 and
+// https://tools.ietf.org/html/rfc7047#section-5.2. They match the errors
+// with the same tag via errors.Is.
+var (
+	ErrReferentialIntegrity = &Error{Message: "referential integrity violation"}
+	ErrConstraintViolation  = &Error{Message: "constraint violation"}
+	ErrResourcesExhausted   = &Error{Message: "resources exhausted"}
+	ErrIO                   = &Error{Message: "I/O error"}
+	ErrDuplicateUUIDName    = &Error{Message: "duplicate uuid-name"}
+	ErrDomain               = &Error{Message: "domain error"}
+	ErrRange                = &Error{Message: "range error"}
+	ErrTimedOut             = &Error{Message: "timed out"}
+	ErrNotSupported         = &Error{Message: "not supported"}
+	ErrAborted              = &Error{Message: "aborted"}
+	ErrNotOwner             = &Error{Message: "not owner"}
+	ErrUnknownDatabase      = &Error{Message: "unknown database"}
+)
+
+// Error represents <error> object, as described in
+// https://tools.ietf.org/html/rfc7047#section-3.1. The Message holds the
+// error tag, e.g. "constraint violation".
 type Error struct {
 	Message string `json:"error"`
 	Details string `json:"details"`
@@ -38,3 +113,60 @@ func (e *Error) String() string {
 	}
 	return s.String()
 }
+
+func (e *Error) Error() string {
+	return e.String()
+}
+
+// Is reports whether the target has the same error tag.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Message == e.Message
+}
+
+// OperationError reports the failure of an operation of a transaction.
+// The Index is the position of the operation in the transaction.
+type OperationError struct {
+	Index     int
+	Operation string
+	Err       *Error
+}
+
+func (e *OperationError) Error() string {
+	return fmt.Sprintf("operation %d (%s) failed: %s", e.Index, e.Operation, e.Err.String())
+}
+
+// Unwrap returns the error reported by the server.
+func (e *OperationError) Unwrap() error { return e.Err }
+
+// ErrInvalidOperation is matched by the errors of the operations rejected
+// by the client, before sending them to the server, via errors.Is.
+var ErrInvalidOperation = errors.New("invalid operation")
+
+// ValidationError reports an operation of a transaction violating the
+// schema of the database. The Index is the position of the operation in
+// the transaction. The Column is empty when the violation concerns the
+// table, e.g. its "maxRows" constraint.
+type ValidationError struct {
+	Index     int
+	Operation string
+	Table     string
+	Column    string
+	Reason    string
+}
+
+func (e *ValidationError) Error() string {
+	var s strings.Builder
+	fmt.Fprintf(&s, "operation %d (%s) is invalid: table %s", e.Index, e.Operation, e.Table)
+	if e.Column != "" {
+		fmt.Fprintf(&s, " column %s", e.Column)
+	}
+	s.WriteString(": ")
+	s.WriteString(e.Reason)
+	return s.String()
+}
+
+// Is reports the error as ErrInvalidOperation.
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalidOperation
+}
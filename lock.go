@@ -0,0 +1,179 @@
+// Copyright 2018 Paul Greenberg (greenpau@outlook.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovsdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// LockEvent reports a change in the ownership of a lock, received via
+// "locked" notification, as described in
+// https://tools.ietf.org/html/rfc7047#section-4.1.9, or "stolen"
+// notification, as described in
+// https://tools.ietf.org/html/rfc7047#section-4.1.10.
+type LockEvent struct {
+	ID string
+	// Locked is true when the client acquired the lock, and false when
+	// another client stole the lock.
+	Locked bool
+}
+
+// lockEventQueue delivers lock events without blocking the messenger.
+type lockEventQueue struct {
+	events  chan LockEvent
+	mux     sync.Mutex
+	cond    *sync.Cond
+	pending []LockEvent
+	closed  bool
+}
+
+func newLockEventQueue() *lockEventQueue {
+	q := &lockEventQueue{
+		events: make(chan LockEvent),
+	}
+	q.cond = sync.NewCond(&q.mux)
+	go q.run()
+	return q
+}
+
+func (q *lockEventQueue) push(event LockEvent) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	if q.closed {
+		return
+	}
+	q.pending = append(q.pending, event)
+	q.cond.Signal()
+}
+
+func (q *lockEventQueue) close() {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	q.closed = true
+	q.cond.Signal()
+}
+
+func (q *lockEventQueue) run() {
+	defer close(q.events)
+	for {
+		q.mux.Lock()
+		for len(q.pending) == 0 && !q.closed {
+			q.cond.Wait()
+		}
+		if len(q.pending) == 0 {
+			q.mux.Unlock()
+			return
+		}
+		event := q.pending[0]
+		q.pending = q.pending[1:]
+		q.mux.Unlock()
+		q.events <- event
+	}
+}
+
+// Lock requests a lock, as described in
+// https://tools.ietf.org/html/rfc7047#section-4.1.8. It returns true
+// when the client acquired the lock. Otherwise, the server grants the lock
+// later and the client receives LockEvent via LockEvents.
+func (cli *Client) Lock(id string) (bool, error) {
+	return cli.LockContext(context.Background(), id)
+}
+
+// LockContext is like Lock, but honors the cancellation and the deadline
+// of the context.
+func (cli *Client) LockContext(ctx context.Context, id string) (bool, error) {
+	return cli.lock(ctx, "lock", id)
+}
+
+// Steal acquires a lock, regardless of whether another client holds it.
+// The previous owner of the lock receives "stolen" notification.
+func (cli *Client) Steal(id string) error {
+	return cli.StealContext(context.Background(), id)
+}
+
+// StealContext is like Steal, but honors the cancellation and the deadline
+// of the context.
+func (cli *Client) StealContext(ctx context.Context, id string) error {
+	_, err := cli.lock(ctx, "steal", id)
+	return err
+}
+
+// Unlock releases a lock, or cancels a pending lock request.
+func (cli *Client) Unlock(id string) error {
+	return cli.UnlockContext(context.Background(), id)
+}
+
+// UnlockContext is like Unlock, but honors the cancellation and the
+// deadline of the context.
+func (cli *Client) UnlockContext(ctx context.Context, id string) error {
+	method := "unlock"
+	if cli == nil {
+		return fmt.Errorf("client was not initialized")
+	}
+	if _, err := cli.queryContext(ctx, method, []interface{}{id}); err != nil {
+		return fmt.Errorf("'%s' method failed for '%s' lock: %w", method, id, err)
+	}
+	cli.notifier.removeLock(id)
+	return nil
+}
+
+// HasLock returns true when the client owns the lock.
+func (cli *Client) HasLock(id string) bool {
+	if cli == nil || cli.notifier == nil {
+		return false
+	}
+	return cli.notifier.hasLock(id)
+}
+
+// LockEvents returns the channel delivering the changes in the ownership
+// of the locks requested by the client. The channel is closed when the
+// client closes.
+func (cli *Client) LockEvents() <-chan LockEvent {
+	return cli.notifier.lockEvents()
+}
+
+func (cli *Client) lock(ctx context.Context, method, id string) (bool, error) {
+	if cli == nil {
+		return false, fmt.Errorf("client was not initialized")
+	}
+	if id == "" {
+		return false, fmt.Errorf("'%s' method failed: empty lock id", method)
+	}
+	// The lock is registered before the request is sent, because the
+	// server may send "locked" notification right after the reply.
+	if !cli.notifier.requestLock(id) && method == "lock" {
+		return false, fmt.Errorf("'%s' method failed for '%s' lock: already requested", method, id)
+	}
+	response, err := cli.queryContext(ctx, method, []interface{}{id})
+	if err != nil {
+		if method == "lock" {
+			cli.notifier.removeLock(id)
+		}
+		return false, fmt.Errorf("'%s' method failed for '%s' lock: %w", method, id, err)
+	}
+	var result struct {
+		Locked bool `json:"locked"`
+	}
+	if err := json.Unmarshal(response.Result, &result); err != nil {
+		return false, fmt.Errorf("'%s' method failed for '%s' lock: %w", method, id, err)
+	}
+	if result.Locked {
+		cli.notifier.setLock(id, true)
+	}
+	return result.Locked, nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2018 Paul Greenberg (greenpau@outlook.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovsdb
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestLockMethods(t *testing.T) {
+	srv := newTestServer(t, func(s *testServer, req testRequest) {
+		var id string
+		json.Unmarshal(req.Params[0], &id)
+		switch req.Method {
+		case "lock":
+			s.reply(req, map[string]interface{}{"locked": false})
+			s.notify("locked", id)
+			s.notify("stolen", id)
+		case "steal":
+			s.reply(req, map[string]interface{}{"locked": true})
+		case "unlock":
+			s.reply(req, map[string]interface{}{})
+		}
+	})
+	cli, err := NewClient(srv.Socket)
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	defer cli.Close()
+	events := cli.LockEvents()
+
+	locked, err := cli.Lock("ovn_northd")
+	if err != nil {
+		t.Fatalf("FAIL: expected to request the lock, but failed: %v", err)
+	}
+	if locked {
+		t.Fatalf("FAIL: expected the lock to be pending")
+	}
+	if _, err := cli.Lock("ovn_northd"); err == nil {
+		t.Fatalf("FAIL: expected the duplicate lock request to fail")
+	}
+	for i, expected := range []LockEvent{
+		{ID: "ovn_northd", Locked: true},
+		{ID: "ovn_northd", Locked: false},
+	} {
+		select {
+		case event := <-events:
+			if event != expected {
+				t.Fatalf("FAIL: Event %d: unexpected lock event: %v", i, event)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("FAIL: Event %d: expected to receive a lock event, but timed out", i)
+		}
+	}
+	if cli.HasLock("ovn_northd") {
+		t.Fatalf("FAIL: expected the lock to be stolen")
+	}
+	if err := cli.Steal("ovn_northd"); err != nil {
+		t.Fatalf("FAIL: expected to steal the lock, but failed: %v", err)
+	}
+	if !cli.HasLock("ovn_northd") {
+		t.Fatalf("FAIL: expected to own the lock")
+	}
+	if err := cli.Unlock("ovn_northd"); err != nil {
+		t.Fatalf("FAIL: expected to release the lock, but failed: %v", err)
+	}
+	if cli.HasLock("ovn_northd") {
+		t.Fatalf("FAIL: expected the lock to be released")
+	}
+	t.Logf("PASS: lock methods completed successfully")
+}
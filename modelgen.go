@@ -0,0 +1,232 @@
+// Copyright 2018 Paul Greenberg (greenpau@outlook.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovsdb
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// goInitialisms are the words of table and column names spelled in upper
+// case in Go identifiers.
+var goInitialisms = map[string]string{
+	"acl":   "ACL",
+	"api":   "API",
+	"bfd":   "BFD",
+	"cfm":   "CFM",
+	"cpu":   "CPU",
+	"dhcp":  "DHCP",
+	"dns":   "DNS",
+	"fdb":   "FDB",
+	"ha":    "HA",
+	"id":    "ID",
+	"ids":   "IDs",
+	"ip":    "IP",
+	"ipv4":  "IPv4",
+	"ipv6":  "IPv6",
+	"lacp":  "LACP",
+	"lb":    "LB",
+	"mac":   "MAC",
+	"mtu":   "MTU",
+	"nat":   "NAT",
+	"nb":    "NB",
+	"qos":   "QOS",
+	"rstp":  "RSTP",
+	"sb":    "SB",
+	"ssl":   "SSL",
+	"stp":   "STP",
+	"tcp":   "TCP",
+	"udp":   "UDP",
+	"url":   "URL",
+	"uuid":  "UUID",
+	"vlan":  "VLAN",
+	"vlans": "VLANs",
+}
+
+// goName converts a table, column, or enum value name, e.g. external_ids,
+// into an exported Go identifier, e.g. ExternalIDs.
+func goName(s string) string {
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var b strings.Builder
+	for _, w := range words {
+		if initialism, exists := goInitialisms[strings.ToLower(w)]; exists {
+			b.WriteString(initialism)
+			continue
+		}
+		runes := []rune(w)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+	return b.String()
+}
+
+// baseEnum returns the values of the enum of a base type of "string" type,
+// if any.
+func baseEnum(b BaseType) []string {
+	if b.Type != "string" {
+		return nil
+	}
+	values := []string{}
+	for _, e := range b.Enum {
+		if s, ok := e.(string); ok {
+			values = append(values, s)
+		}
+	}
+	sort.Strings(values)
+	return values
+}
+
+// modelGenerator holds the state of the generation of the models of
+// a schema.
+type modelGenerator struct {
+	schema Schema
+	enums  bytes.Buffer
+	models bytes.Buffer
+}
+
+// GenerateModels returns the source of a Go package with the models, see
+// Model, of the tables of a schema. The package holds a struct per table
+// and a string type with constants per column of string enum type.
+func GenerateModels(schema Schema, pkg string) ([]byte, error) {
+	if schema.Name == "" {
+		return nil, fmt.Errorf("schema has no name")
+	}
+	if pkg == "" {
+		return nil, fmt.Errorf("package name is empty")
+	}
+	g := &modelGenerator{schema: schema}
+	for _, table := range schema.GetTables() {
+		if err := g.generateModel(table); err != nil {
+			return nil, err
+		}
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by ovsdb-modelgen from %s %s schema. DO NOT EDIT.\n\n", schema.Name, schema.Version)
+	fmt.Fprintf(&b, "// Package %s holds the models of the tables of %s database.\n", pkg, schema.Name)
+	fmt.Fprintf(&b, "package %s\n\n", pkg)
+	fmt.Fprintf(&b, "// DatabaseName is the name of the database of the models.\n")
+	fmt.Fprintf(&b, "const DatabaseName = %q\n\n", schema.Name)
+	fmt.Fprintf(&b, "// SchemaVersion is the version of the schema of the models.\n")
+	fmt.Fprintf(&b, "const SchemaVersion = %q\n\n", schema.Version)
+	b.Write(g.enums.Bytes())
+	b.Write(g.models.Bytes())
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format models of %s schema: %v", schema.Name, err)
+	}
+	return src, nil
+}
+
+func (g *modelGenerator) generateModel(table string) error {
+	name := goName(table)
+	if name == "" {
+		return fmt.Errorf("table %s has no valid Go name", table)
+	}
+	fields := map[string]bool{"Database": true, "Table": true}
+	w := &g.models
+	fmt.Fprintf(w, "// %s is a row of %s table.\n", name, table)
+	fmt.Fprintf(w, "type %s struct {\n", name)
+	fmt.Fprintf(w, "UUID string `ovsdb:\"_uuid\"`\n")
+	fields["UUID"] = true
+	for _, column := range g.schema.GetColumns(table) {
+		fieldName := goName(column)
+		for fields[fieldName] {
+			fieldName += "Column"
+		}
+		fields[fieldName] = true
+		fieldType, err := g.fieldType(name+fieldName, fmt.Sprintf("%s column of %s table", column, table), g.schema.Tables[table].Columns[column])
+		if err != nil {
+			return fmt.Errorf("table %s column %s: %v", table, column, err)
+		}
+		fmt.Fprintf(w, "%s %s `ovsdb:%q`\n", fieldName, fieldType, column)
+	}
+	fmt.Fprintf(w, "}\n\n")
+	fmt.Fprintf(w, "// Database returns the name of the database of %s table.\n", table)
+	fmt.Fprintf(w, "func (%s) Database() string { return DatabaseName }\n\n", name)
+	fmt.Fprintf(w, "// Table returns the name of %s table.\n", table)
+	fmt.Fprintf(w, "func (%s) Table() string { return %q }\n\n", name, table)
+	return nil
+}
+
+// fieldType returns the Go type of a column. The enum types are named
+// after the table and the column.
+func (g *modelGenerator) fieldType(enumName, doc string, column Column) (string, error) {
+	c := column.Type
+	keyDoc := doc
+	if c.IsMap() {
+		keyDoc = "the keys of " + doc
+	}
+	keyType, err := g.atomType(enumName, keyDoc, c.Key.Type, baseEnum(c.Key))
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case c.IsMap():
+		valueType, err := g.atomType(enumName+"Value", "the values of "+doc, c.Value.Type, baseEnum(*c.Value))
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("map[%s]%s", keyType, valueType), nil
+	case c.IsOptional():
+		return "*" + keyType, nil
+	case c.IsSet():
+		return "[]" + keyType, nil
+	}
+	return keyType, nil
+}
+
+func (g *modelGenerator) atomType(enumName, doc, base string, enum []string) (string, error) {
+	switch base {
+	case "string":
+		if len(enum) > 0 {
+			g.generateEnum(enumName, doc, enum)
+			return enumName, nil
+		}
+		return "string", nil
+	case "uuid":
+		return "string", nil
+	case "integer":
+		return "int", nil
+	case "real":
+		return "float64", nil
+	case "boolean":
+		return "bool", nil
+	}
+	return "", fmt.Errorf("unsupported atomic type: %q", base)
+}
+
+func (g *modelGenerator) generateEnum(name, doc string, values []string) {
+	w := &g.enums
+	fmt.Fprintf(w, "// %s is the type of %s.\n", name, doc)
+	fmt.Fprintf(w, "type %s string\n\n", name)
+	fmt.Fprintf(w, "// The values of %s.\n", name)
+	fmt.Fprintf(w, "const (\n")
+	constants := map[string]bool{}
+	for _, v := range values {
+		constant := name + goName(v)
+		for constants[constant] {
+			constant += "_"
+		}
+		constants[constant] = true
+		fmt.Fprintf(w, "%s %s = %q\n", constant, name, v)
+	}
+	fmt.Fprintf(w, ")\n\n")
+}
@@ -0,0 +1,188 @@
+// Copyright 2018 Paul Greenberg (greenpau@outlook.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovsdb
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var testServerSchema = []byte(`{
+  "name": "_Server",
+  "version": "1.2.0",
+  "tables": {
+    "Database": {
+      "columns": {
+        "name": {"type": "string"},
+        "model": {"type": {"key": {"type": "string", "enum": ["set", ["standalone", "clustered", "relay"]]}}},
+        "connected": {"type": "boolean"},
+        "leader": {"type": "boolean"},
+        "schema": {"type": {"key": {"type": "string"}, "min": 0, "max": 1}},
+        "cid": {"type": {"key": {"type": "uuid"}, "min": 0, "max": 1}},
+        "sid": {"type": {"key": {"type": "uuid"}, "min": 0, "max": 1}},
+        "index": {"type": {"key": {"type": "integer"}, "min": 0, "max": 1}}
+      },
+      "isRoot": true
+    }
+  }
+}`)
+
+var testVswitchSchema = []byte(`{
+  "name": "Open_vSwitch",
+  "version": "8.3.0",
+  "tables": {
+    "Interface": {
+      "columns": {
+        "name": {"type": "string", "mutable": false},
+        "type": {"type": "string"},
+        "ofport": {"type": {"key": "integer", "min": 0, "max": 1}},
+        "admin_state": {"type": {"key": {"type": "string", "enum": ["set", ["up", "down"]]}, "min": 0, "max": 1}},
+        "statistics": {"type": {"key": "string", "value": "integer", "min": 0, "max": "unlimited"}, "ephemeral": true},
+        "bfd_status": {"type": {"key": "string", "value": "string", "min": 0, "max": "unlimited"}},
+        "mac_in_use": {"type": {"key": {"type": "string"}, "min": 0, "max": 1}},
+        "link_speed": {"type": {"key": "integer", "min": 0, "max": 1}},
+        "ingress_policing_rate": {"type": {"key": {"type": "integer", "minInteger": 0}}},
+        "ingress_policing_burst": {"type": {"key": {"type": "integer", "minInteger": 0}}}
+      }
+    },
+    "Bridge": {
+      "columns": {
+        "name": {"type": "string", "mutable": false},
+        "ports": {"type": {"key": {"type": "uuid", "refTable": "Port"}, "min": 0, "max": "unlimited"}},
+        "fail_mode": {"type": {"key": {"type": "string", "enum": ["set", ["standalone", "secure"]]}, "min": 0, "max": 1}},
+        "protocols": {"type": {"key": {"type": "string", "enum": ["set", ["OpenFlow10", "OpenFlow11", "OpenFlow12", "OpenFlow13", "OpenFlow14", "OpenFlow15"]]}, "min": 0, "max": "unlimited"}},
+        "flood_vlans": {"type": {"key": {"type": "integer", "minInteger": 0, "maxInteger": 4095}, "min": 0, "max": 4096}},
+        "flow_tables": {"type": {"key": {"type": "integer", "minInteger": 0, "maxInteger": 254}, "value": {"type": "uuid", "refTable": "Flow_Table"}, "min": 0, "max": "unlimited"}},
+        "mcast_snooping_enable": {"type": "boolean"},
+        "datapath_version": {"type": "string"}
+      },
+      "isRoot": true
+    },
+    "Open_vSwitch": {
+      "columns": {
+        "bridges": {"type": {"key": {"type": "uuid", "refTable": "Bridge"}, "min": 0, "max": "unlimited"}},
+        "next_cfg": {"type": "integer"},
+        "other_config": {"type": {"key": "string", "value": "string", "min": 0, "max": "unlimited"}}
+      },
+      "isRoot": true,
+      "maxRows": 1
+    }
+  }
+}`)
+
+func TestGoName(t *testing.T) {
+	for input, expected := range map[string]string{
+		"Logical_Switch_Port": "LogicalSwitchPort",
+		"external_ids":        "ExternalIDs",
+		"_uuid":               "UUID",
+		"Open_vSwitch":        "OpenVSwitch",
+		"ACL":                 "ACL",
+		"NB_Global":           "NBGlobal",
+		"allow-related":       "AllowRelated",
+		"802.1q":              "8021q",
+	} {
+		if name := goName(input); name != expected {
+			t.Fatalf("FAIL: %s: expected %s, but got %s", input, expected, name)
+		}
+	}
+	t.Logf("PASS: converted the names into Go identifiers")
+}
+
+func TestGenerateModels(t *testing.T) {
+	for i, test := range []struct {
+		schema   []byte
+		pkg      string
+		expected []string
+	}{
+		{
+			schema: testNorthboundSchema,
+			pkg:    "nb",
+			expected: []string{
+				`type LogicalSwitch struct`,
+				`Ports []string ` + "`" + `ovsdb:"ports"` + "`",
+				`ExternalIDs map[string]string ` + "`" + `ovsdb:"external_ids"` + "`",
+				`Up *bool ` + "`" + `ovsdb:"up"` + "`",
+				`Tag *int ` + "`" + `ovsdb:"tag"` + "`",
+				`func (LogicalSwitchPort) Table() string { return "Logical_Switch_Port" }`,
+			},
+		},
+		{
+			schema: testServerSchema,
+			pkg:    "server",
+			expected: []string{
+				`const DatabaseName = "_Server"`,
+				`type DatabaseModel string`,
+				`DatabaseModelClustered DatabaseModel = "clustered"`,
+				`Model DatabaseModel ` + "`" + `ovsdb:"model"` + "`",
+				`Sid *string ` + "`" + `ovsdb:"sid"` + "`",
+				`func (Database) Database() string { return DatabaseName }`,
+			},
+		},
+		{
+			schema: testVswitchSchema,
+			pkg:    "vswitch",
+			expected: []string{
+				`FailMode *BridgeFailMode ` + "`" + `ovsdb:"fail_mode"` + "`",
+				`Protocols []BridgeProtocols ` + "`" + `ovsdb:"protocols"` + "`",
+				`BridgeProtocolsOpenFlow13 BridgeProtocols = "OpenFlow13"`,
+				`FloodVLANs []int ` + "`" + `ovsdb:"flood_vlans"` + "`",
+				`FlowTables map[int]string ` + "`" + `ovsdb:"flow_tables"` + "`",
+				`AdminState *InterfaceAdminState ` + "`" + `ovsdb:"admin_state"` + "`",
+				`Statistics map[string]int ` + "`" + `ovsdb:"statistics"` + "`",
+				`MACInUse *string ` + "`" + `ovsdb:"mac_in_use"` + "`",
+				`IngressPolicingRate int ` + "`" + `ovsdb:"ingress_policing_rate"` + "`",
+			},
+		},
+	} {
+		schema := newTestSchema(t, test.schema)
+		src, err := GenerateModels(schema, test.pkg)
+		if err != nil {
+			t.Fatalf("FAIL: Test %d: %v", i, err)
+		}
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, "models.go", src, parser.ParseComments)
+		if err != nil {
+			t.Fatalf("FAIL: Test %d: generated invalid source: %v\n%s", i, err, src)
+		}
+		if _, err := (&types.Config{}).Check(test.pkg, fset, []*ast.File{f}, nil); err != nil {
+			t.Fatalf("FAIL: Test %d: generated source does not compile: %v\n%s", i, err, src)
+		}
+		// The alignment of the fields depends on the neighbouring fields.
+		normalized := regexp.MustCompile(`[ \t]+`).ReplaceAllString(string(src), " ")
+		for _, s := range test.expected {
+			if !strings.Contains(normalized, s) {
+				t.Fatalf("FAIL: Test %d: expected %s in the generated source:\n%s", i, s, src)
+			}
+		}
+		for _, table := range schema.GetTables() {
+			if !strings.Contains(normalized, "type "+goName(table)+" struct") {
+				t.Fatalf("FAIL: Test %d: expected a model of %s table:\n%s", i, table, src)
+			}
+		}
+		t.Logf("PASS: Test %d: generated the models of %s schema", i, schema.Name)
+	}
+
+	if _, err := GenerateModels(Schema{Name: "test", Tables: map[string]Table{
+		"T": {Columns: map[string]Column{"c": {Type: ColumnType{Key: BaseType{Type: "blob"}, Min: 1, Max: 1}}}},
+	}}, "test"); err == nil {
+		t.Fatalf("FAIL: expected unsupported atomic type to fail")
+	}
+	t.Logf("PASS: rejected unsupported atomic type")
+}
@@ -0,0 +1,356 @@
+// Copyright 2018 Paul Greenberg (greenpau@outlook.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovsdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// MonitorRequest represents <monitor-request> object, as described in
+// https://tools.ietf.org/html/rfc7047#section-4.1.5
+type MonitorRequest struct {
+	Columns []string       `json:"columns,omitempty"`
+	Select  *MonitorSelect `json:"select,omitempty"`
+}
+
+// MonitorSelect specifies the kinds of changes reported by a monitor.
+// When omitted from MonitorRequest, all changes are reported.
+type MonitorSelect struct {
+	Initial bool `json:"initial"`
+	Insert  bool `json:"insert"`
+	Delete  bool `json:"delete"`
+	Modify  bool `json:"modify"`
+}
+
+// TableUpdates represents <table-updates> object. It maps table names
+// to the updates of the rows in the tables.
+type TableUpdates map[string]TableUpdate
+
+// TableUpdate represents <table-update> object. It maps row UUIDs
+// to the changes of the rows.
+type TableUpdate map[string]RowUpdate
+
+// RowUpdate represents <row-update> object. The Old is empty for
+// initial and inserted rows, and the New is empty for deleted rows.
+type RowUpdate struct {
+	Old Row `json:"old,omitempty"`
+	New Row `json:"new,omitempty"`
+}
+
+// Monitor represents a subscription to the changes in a database,
+// created with "monitor" method, as described in
+// https://tools.ietf.org/html/rfc7047#section-4.1.5, or with
+// "monitor_cond" and "monitor_cond_since" methods, as described in
+// ovsdb-server(7).
+type Monitor struct {
+	ID       string
+	Database string
+	Method   string
+	Requests map[string]MonitorRequest
+	// CondRequests holds the requests of conditional monitors.
+	CondRequests map[string]MonitorCondRequest
+	// Initial holds the contents of the monitored tables at the time
+	// the monitor was created with "monitor" method.
+	Initial TableUpdates
+	// Initial2 holds the contents of the monitored tables at the time
+	// the monitor was created with "monitor_cond" or "monitor_cond_since"
+	// methods.
+	Initial2 TableUpdates2
+	// Updates delivers "update" notifications. The channel is closed
+	// when the monitor stops.
+	Updates <-chan TableUpdates
+	// Updates2 delivers "update2" and "update3" notifications. The channel
+	// is closed when the monitor stops.
+	Updates2 <-chan TableUpdates2
+
+	updates   chan TableUpdates
+	updates2  chan TableUpdates2
+	mux       sync.Mutex
+	cond      *sync.Cond
+	pending   []monitorUpdate
+	lastTxnID string
+	closed    bool
+	// known holds the UUIDs of the rows delivered by the monitor, by
+	// table. It is used to find the rows deleted while the client was
+	// disconnected.
+	known map[string]map[string]bool
+	// internal monitors are used by the client itself and are not
+	// re-established after a reconnect.
+	internal bool
+	// replaying is set while the monitor is re-established after
+	// a reconnect. The notifications received meanwhile are held in
+	// buffered until the reply is queued.
+	replaying bool
+	buffered  []monitorUpdate
+}
+
+// monitorUpdate is a queued notification.
+type monitorUpdate struct {
+	updates  TableUpdates
+	updates2 TableUpdates2
+}
+
+func newMonitor(id, db, method string) *Monitor {
+	m := &Monitor{
+		ID:       id,
+		Database: db,
+		Method:   method,
+		updates:  make(chan TableUpdates),
+		updates2: make(chan TableUpdates2),
+		known:    make(map[string]map[string]bool),
+	}
+	m.Updates = m.updates
+	m.Updates2 = m.updates2
+	m.cond = sync.NewCond(&m.mux)
+	go m.run()
+	return m
+}
+
+// push queues updates for delivery. The queue is unbounded so that a slow
+// consumer does not block the messenger.
+func (m *Monitor) push(u monitorUpdate) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if m.closed {
+		return
+	}
+	if m.replaying {
+		m.buffered = append(m.buffered, u)
+		return
+	}
+	m.queue(u)
+}
+
+// queue tracks the rows of the updates and queues them for delivery. It
+// must be called with mux held.
+func (m *Monitor) queue(u monitorUpdate) {
+	m.track(u)
+	m.pending = append(m.pending, u)
+	m.cond.Signal()
+}
+
+// beginReplay starts buffering the notifications of the monitor. The
+// server may send notifications right after the reply re-establishing
+// the monitor, before the reply is processed.
+func (m *Monitor) beginReplay() {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.replaying = true
+}
+
+// endReplay queues the updates received in the reply re-establishing the
+// monitor, if any, followed by the notifications buffered since
+// beginReplay.
+func (m *Monitor) endReplay(u *monitorUpdate) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	buffered := m.buffered
+	m.replaying = false
+	m.buffered = nil
+	if m.closed {
+		return
+	}
+	if u != nil {
+		m.queue(*u)
+	}
+	for _, b := range buffered {
+		m.queue(b)
+	}
+}
+
+// record tracks the rows of the initial contents of the monitor.
+func (m *Monitor) record(u monitorUpdate) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.track(u)
+}
+
+// track updates the known rows. It must be called with mux held.
+func (m *Monitor) track(u monitorUpdate) {
+	for table, tableUpdate := range u.updates {
+		for uuid, rowUpdate := range tableUpdate {
+			m.setKnown(table, uuid, rowUpdate.New != nil)
+		}
+	}
+	for table, tableUpdate := range u.updates2 {
+		for uuid, rowUpdate := range tableUpdate {
+			m.setKnown(table, uuid, !rowUpdate.Delete)
+		}
+	}
+}
+
+func (m *Monitor) setKnown(table, uuid string, exists bool) {
+	if !exists {
+		delete(m.known[table], uuid)
+		return
+	}
+	if m.known[table] == nil {
+		m.known[table] = make(map[string]bool)
+	}
+	m.known[table][uuid] = true
+}
+
+// resync adds to the full contents of the monitored tables received after
+// a reconnect the deletions of the known rows missing from the contents,
+// so that the consumers of the monitor see the same changes as if the
+// client had not been disconnected.
+func (m *Monitor) resync(u monitorUpdate) monitorUpdate {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if m.Method == "monitor" {
+		if u.updates == nil {
+			u.updates = TableUpdates{}
+		}
+		for table, uuids := range m.known {
+			for uuid := range uuids {
+				if _, exists := u.updates[table][uuid]; exists {
+					continue
+				}
+				if u.updates[table] == nil {
+					u.updates[table] = TableUpdate{}
+				}
+				u.updates[table][uuid] = RowUpdate{Old: Row{}}
+			}
+		}
+	} else {
+		if u.updates2 == nil {
+			u.updates2 = TableUpdates2{}
+		}
+		for table, uuids := range m.known {
+			for uuid := range uuids {
+				if _, exists := u.updates2[table][uuid]; exists {
+					continue
+				}
+				if u.updates2[table] == nil {
+					u.updates2[table] = TableUpdate2{}
+				}
+				u.updates2[table][uuid] = RowUpdate2{Delete: true}
+			}
+		}
+	}
+	return u
+}
+
+// setLastTransactionID records the id of the last transaction seen by
+// the monitor.
+func (m *Monitor) setLastTransactionID(id string) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.lastTxnID = id
+}
+
+// LastTransactionID returns the id of the last transaction reported by
+// "monitor_cond_since" method or "update3" notification. It is suitable
+// for resuming the monitor with MonitorCondSince.
+func (m *Monitor) LastTransactionID() string {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return m.lastTxnID
+}
+
+func (m *Monitor) close() {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.closed = true
+	m.cond.Signal()
+}
+
+// run delivers queued updates to the Updates and Updates2 channels.
+func (m *Monitor) run() {
+	defer close(m.updates)
+	defer close(m.updates2)
+	for {
+		m.mux.Lock()
+		for len(m.pending) == 0 && !m.closed {
+			m.cond.Wait()
+		}
+		if len(m.pending) == 0 {
+			m.mux.Unlock()
+			return
+		}
+		u := m.pending[0]
+		m.pending = m.pending[1:]
+		m.mux.Unlock()
+		if u.updates2 != nil {
+			m.updates2 <- u.updates2
+			continue
+		}
+		m.updates <- u.updates
+	}
+}
+
+// Monitor subscribes to the changes in the tables of a database. The keys
+// of the requests are table names.
+func (cli *Client) Monitor(db string, requests map[string]MonitorRequest) (*Monitor, error) {
+	return cli.MonitorContext(context.Background(), db, requests)
+}
+
+// MonitorContext is like Monitor, but honors the cancellation and the
+// deadline of the context.
+func (cli *Client) MonitorContext(ctx context.Context, db string, requests map[string]MonitorRequest) (*Monitor, error) {
+	method := "monitor"
+	if cli == nil {
+		return nil, fmt.Errorf("client was not initialized")
+	}
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("'%s' method failed for '%s' database: no tables", method, db)
+	}
+	m := newMonitor(cli.notifier.nextID(), db, method)
+	m.Requests = requests
+	// The monitor is registered before the request is sent, because the
+	// server may send an update right after the reply.
+	cli.notifier.addMonitor(m)
+	response, err := cli.queryContext(ctx, method, []interface{}{db, m.ID, requests})
+	if err != nil {
+		cli.notifier.removeMonitor(m.ID)
+		return nil, fmt.Errorf("'%s' method failed for '%s' database: %w", method, db, err)
+	}
+	if err := json.Unmarshal(response.Result, &m.Initial); err != nil {
+		cli.notifier.removeMonitor(m.ID)
+		return nil, fmt.Errorf("'%s' method failed for '%s' database: %w", method, db, err)
+	}
+	m.record(monitorUpdate{updates: m.Initial})
+	return m, nil
+}
+
+// MonitorCancel stops a monitor, as described in
+// https://tools.ietf.org/html/rfc7047#section-4.1.7. The Updates and
+// Updates2 channels of the monitor are closed.
+func (cli *Client) MonitorCancel(m *Monitor) error {
+	return cli.MonitorCancelContext(context.Background(), m)
+}
+
+// MonitorCancelContext is like MonitorCancel, but honors the cancellation
+// and the deadline of the context.
+func (cli *Client) MonitorCancelContext(ctx context.Context, m *Monitor) error {
+	method := "monitor_cancel"
+	if cli == nil {
+		return fmt.Errorf("client was not initialized")
+	}
+	if m == nil {
+		return fmt.Errorf("'%s' method failed: no monitor", method)
+	}
+	// The server stops sending updates before the reply. The monitor is
+	// removed even when the request fails, e.g. because the server no
+	// longer knows the monitor.
+	defer cli.notifier.removeMonitor(m.ID)
+	if _, err := cli.queryContext(ctx, method, []interface{}{m.ID}); err != nil {
+		return fmt.Errorf("'%s' method failed for '%s' database: %w", method, m.Database, err)
+	}
+	return nil
+}
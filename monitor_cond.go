@@ -0,0 +1,221 @@
+// Copyright 2018 Paul Greenberg (greenpau@outlook.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovsdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// MonitorCondRequest represents <monitor-cond-request> object, as
+// described in ovsdb-server(7). The Where conditions limit the monitored
+// rows. When the Where is empty, all rows are monitored.
+type MonitorCondRequest struct {
+	Columns []string       `json:"columns,omitempty"`
+	Where   []Condition    `json:"where,omitempty"`
+	Select  *MonitorSelect `json:"select,omitempty"`
+}
+
+// MonitorCondUpdate represents <monitor-cond-update> object used by
+// "monitor_cond_change" method.
+type MonitorCondUpdate struct {
+	Columns []string    `json:"columns,omitempty"`
+	Where   []Condition `json:"where"`
+}
+
+// TableUpdates2 represents <table-updates2> object. It maps table names
+// to the updates of the rows in the tables.
+type TableUpdates2 map[string]TableUpdate2
+
+// TableUpdate2 represents <table-update2> object. It maps row UUIDs
+// to the changes of the rows.
+type TableUpdate2 map[string]RowUpdate2
+
+// RowUpdate2 represents <row-update2> object. Exactly one of the members
+// is set. The Modify holds only the columns that changed, as described
+// in ovsdb-server(7).
+type RowUpdate2 struct {
+	Initial Row
+	Insert  Row
+	Modify  Row
+	Delete  bool
+}
+
+// UnmarshalJSON decodes <row-update2> object.
+func (u *RowUpdate2) UnmarshalJSON(b []byte) error {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+	for k, v := range m {
+		var err error
+		switch k {
+		case "initial":
+			err = json.Unmarshal(v, &u.Initial)
+		case "insert":
+			err = json.Unmarshal(v, &u.Insert)
+		case "modify":
+			err = json.Unmarshal(v, &u.Modify)
+		case "delete":
+			u.Delete = true
+		default:
+			err = fmt.Errorf("unsupported row update: %s", k)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MonitorCond subscribes to the changes in the rows of a database matching
+// the conditions of the requests. The keys of the requests are table names.
+// The changes are delivered via Updates2 channel of the monitor.
+func (cli *Client) MonitorCond(db string, requests map[string]MonitorCondRequest) (*Monitor, error) {
+	return cli.MonitorCondContext(context.Background(), db, requests)
+}
+
+// MonitorCondContext is like MonitorCond, but honors the cancellation and
+// the deadline of the context.
+func (cli *Client) MonitorCondContext(ctx context.Context, db string, requests map[string]MonitorCondRequest) (*Monitor, error) {
+	method := "monitor_cond"
+	if cli == nil {
+		return nil, fmt.Errorf("client was not initialized")
+	}
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("'%s' method failed for '%s' database: no tables", method, db)
+	}
+	m := newMonitor(cli.notifier.nextID(), db, method)
+	m.CondRequests = requests
+	cli.notifier.addMonitor(m)
+	response, err := cli.queryContext(ctx, method, []interface{}{db, m.ID, requests})
+	if err != nil {
+		cli.notifier.removeMonitor(m.ID)
+		return nil, fmt.Errorf("'%s' method failed for '%s' database: %w", method, db, err)
+	}
+	if err := json.Unmarshal(response.Result, &m.Initial2); err != nil {
+		cli.notifier.removeMonitor(m.ID)
+		return nil, fmt.Errorf("'%s' method failed for '%s' database: %w", method, db, err)
+	}
+	m.record(monitorUpdate{updates2: m.Initial2})
+	return m, nil
+}
+
+// MonitorCondSince is similar to MonitorCond. However, when the server
+// still has the transaction with lastTxnID in its history, the Initial2 of
+// the returned monitor contains only the changes made after that
+// transaction, and the returned found flag is true. Otherwise, the Initial2
+// contains the full contents of the monitored rows. An empty lastTxnID
+// requests the full contents.
+func (cli *Client) MonitorCondSince(db string, requests map[string]MonitorCondRequest, lastTxnID string) (*Monitor, bool, error) {
+	return cli.MonitorCondSinceContext(context.Background(), db, requests, lastTxnID)
+}
+
+// MonitorCondSinceContext is like MonitorCondSince, but honors the
+// cancellation and the deadline of the context.
+func (cli *Client) MonitorCondSinceContext(ctx context.Context, db string, requests map[string]MonitorCondRequest, lastTxnID string) (*Monitor, bool, error) {
+	method := "monitor_cond_since"
+	if cli == nil {
+		return nil, false, fmt.Errorf("client was not initialized")
+	}
+	if len(requests) == 0 {
+		return nil, false, fmt.Errorf("'%s' method failed for '%s' database: no tables", method, db)
+	}
+	if lastTxnID == "" {
+		lastTxnID = "00000000-0000-0000-0000-000000000000"
+	}
+	m := newMonitor(cli.notifier.nextID(), db, method)
+	m.CondRequests = requests
+	cli.notifier.addMonitor(m)
+	response, err := cli.queryContext(ctx, method, []interface{}{db, m.ID, requests, lastTxnID})
+	if err != nil {
+		cli.notifier.removeMonitor(m.ID)
+		return nil, false, fmt.Errorf("'%s' method failed for '%s' database: %w", method, db, err)
+	}
+	var result []json.RawMessage
+	if err := json.Unmarshal(response.Result, &result); err != nil {
+		cli.notifier.removeMonitor(m.ID)
+		return nil, false, fmt.Errorf("'%s' method failed for '%s' database: %w", method, db, err)
+	}
+	if len(result) != 3 {
+		cli.notifier.removeMonitor(m.ID)
+		return nil, false, fmt.Errorf("'%s' method failed for '%s' database: unexpected response: %s", method, db, response.Result)
+	}
+	var found bool
+	var txnID string
+	if err := json.Unmarshal(result[0], &found); err != nil {
+		cli.notifier.removeMonitor(m.ID)
+		return nil, false, fmt.Errorf("'%s' method failed for '%s' database: %w", method, db, err)
+	}
+	if err := json.Unmarshal(result[1], &txnID); err != nil {
+		cli.notifier.removeMonitor(m.ID)
+		return nil, false, fmt.Errorf("'%s' method failed for '%s' database: %w", method, db, err)
+	}
+	if err := json.Unmarshal(result[2], &m.Initial2); err != nil {
+		cli.notifier.removeMonitor(m.ID)
+		return nil, false, fmt.Errorf("'%s' method failed for '%s' database: %w", method, db, err)
+	}
+	// The "update3" notifications following the reply may have already
+	// advanced the last transaction id.
+	m.mux.Lock()
+	if m.lastTxnID == "" {
+		m.lastTxnID = txnID
+	}
+	m.track(monitorUpdate{updates2: m.Initial2})
+	m.mux.Unlock()
+	return m, found, nil
+}
+
+// MonitorCondChange changes the columns and the conditions of a monitor
+// created with MonitorCond or MonitorCondSince. The keys of the requests
+// are table names.
+func (cli *Client) MonitorCondChange(m *Monitor, requests map[string]MonitorCondUpdate) error {
+	return cli.MonitorCondChangeContext(context.Background(), m, requests)
+}
+
+// MonitorCondChangeContext is like MonitorCondChange, but honors the
+// cancellation and the deadline of the context.
+func (cli *Client) MonitorCondChangeContext(ctx context.Context, m *Monitor, requests map[string]MonitorCondUpdate) error {
+	method := "monitor_cond_change"
+	if cli == nil {
+		return fmt.Errorf("client was not initialized")
+	}
+	if m == nil {
+		return fmt.Errorf("'%s' method failed: not a conditional monitor", method)
+	}
+	// The conditions are replayed by the reconnects, see replayMonitor,
+	// so they are accessed under the lock of the monitor.
+	m.mux.Lock()
+	conditional := m.CondRequests != nil
+	m.mux.Unlock()
+	if !conditional {
+		return fmt.Errorf("'%s' method failed: not a conditional monitor", method)
+	}
+	if _, err := cli.queryContext(ctx, method, []interface{}{m.ID, m.ID, requests}); err != nil {
+		return fmt.Errorf("'%s' method failed for '%s' monitor: %w", method, m.ID, err)
+	}
+	m.mux.Lock()
+	for table, req := range requests {
+		r := m.CondRequests[table]
+		if req.Columns != nil {
+			r.Columns = req.Columns
+		}
+		r.Where = req.Where
+		m.CondRequests[table] = r
+	}
+	m.mux.Unlock()
+	return nil
+}
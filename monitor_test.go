@@ -0,0 +1,208 @@
+// Copyright 2018 Paul Greenberg (greenpau@outlook.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovsdb
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestMonitorMethod(t *testing.T) {
+	srv := newTestServer(t, func(s *testServer, req testRequest) {
+		switch req.Method {
+		case "monitor":
+			var id string
+			json.Unmarshal(req.Params[1], &id)
+			s.reply(req, map[string]interface{}{
+				"Bridge": map[string]interface{}{
+					"4d1b8a7e-0000-0000-0000-000000000001": map[string]interface{}{
+						"new": map[string]interface{}{"name": "br-int"},
+					},
+				},
+			})
+			s.notify("update", id, map[string]interface{}{
+				"Bridge": map[string]interface{}{
+					"4d1b8a7e-0000-0000-0000-000000000001": map[string]interface{}{
+						"old": map[string]interface{}{"name": "br-int"},
+						"new": map[string]interface{}{"name": "br-ex"},
+					},
+				},
+			})
+		case "echo":
+			s.reply(req, req.Params)
+		}
+	})
+	cli, err := NewClient(srv.Socket)
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	defer cli.Close()
+
+	m, err := cli.Monitor("Open_vSwitch", map[string]MonitorRequest{
+		"Bridge": {Columns: []string{"name"}},
+	})
+	if err != nil {
+		t.Fatalf("FAIL: expected to create a monitor, but failed: %v", err)
+	}
+	row := m.Initial["Bridge"]["4d1b8a7e-0000-0000-0000-000000000001"].New
+	if row["name"] != "br-int" {
+		t.Fatalf("FAIL: unexpected initial contents: %v", m.Initial)
+	}
+	select {
+	case updates := <-m.Updates:
+		rowUpdate := updates["Bridge"]["4d1b8a7e-0000-0000-0000-000000000001"]
+		if rowUpdate.Old["name"] != "br-int" || rowUpdate.New["name"] != "br-ex" {
+			t.Fatalf("FAIL: unexpected update: %v", updates)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("FAIL: expected to receive an update, but timed out")
+	}
+
+	// The requests continue to work after receiving a notification.
+	if err := cli.Echo("test message"); err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	t.Logf("PASS: 'monitor' method completed successfully")
+}
+
+func TestMonitorCondSinceMethod(t *testing.T) {
+	requests := make(chan testRequest, 4)
+	srv := newTestServer(t, func(s *testServer, req testRequest) {
+		requests <- req
+		switch req.Method {
+		case "monitor_cond_since":
+			var id string
+			json.Unmarshal(req.Params[1], &id)
+			s.reply(req, []interface{}{
+				true,
+				"a2b3c4d5-0000-0000-0000-000000000002",
+				map[string]interface{}{
+					"Port_Binding": map[string]interface{}{
+						"9c1e6a3b-0000-0000-0000-000000000001": map[string]interface{}{
+							"insert": map[string]interface{}{"logical_port": "lsp1"},
+						},
+					},
+				},
+			})
+			s.notify("update3", id, "a2b3c4d5-0000-0000-0000-000000000003", map[string]interface{}{
+				"Port_Binding": map[string]interface{}{
+					"9c1e6a3b-0000-0000-0000-000000000001": map[string]interface{}{
+						"delete": nil,
+					},
+				},
+			})
+		case "monitor_cond_change":
+			s.reply(req, map[string]interface{}{})
+		}
+	})
+	cli, err := NewClient(srv.Socket)
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	defer cli.Close()
+
+	cond, err := NewCondition([]string{"chassis_name==\"hv1\""})
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	m, found, err := cli.MonitorCondSince("OVN_Southbound", map[string]MonitorCondRequest{
+		"Port_Binding": {
+			Columns: []string{"logical_port"},
+			Where:   []Condition{cond},
+		},
+	}, "a2b3c4d5-0000-0000-0000-000000000001")
+	if err != nil {
+		t.Fatalf("FAIL: expected to create a monitor, but failed: %v", err)
+	}
+	req := <-requests
+	expected := `{"Port_Binding":{"columns":["logical_port"],"where":[["chassis_name","==","hv1"]]}}`
+	if string(req.Params[2]) != expected {
+		t.Fatalf("FAIL: unexpected monitor requests: %s vs. %s (expected)", req.Params[2], expected)
+	}
+	if !found {
+		t.Fatalf("FAIL: expected the transaction to be found")
+	}
+	if m.Initial2["Port_Binding"]["9c1e6a3b-0000-0000-0000-000000000001"].Insert["logical_port"] != "lsp1" {
+		t.Fatalf("FAIL: unexpected initial contents: %v", m.Initial2)
+	}
+	select {
+	case updates := <-m.Updates2:
+		if !updates["Port_Binding"]["9c1e6a3b-0000-0000-0000-000000000001"].Delete {
+			t.Fatalf("FAIL: unexpected update: %v", updates)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("FAIL: expected to receive an update, but timed out")
+	}
+	if m.LastTransactionID() != "a2b3c4d5-0000-0000-0000-000000000003" {
+		t.Fatalf("FAIL: unexpected last transaction id: %s", m.LastTransactionID())
+	}
+
+	if err := cli.MonitorCondChange(m, map[string]MonitorCondUpdate{
+		"Port_Binding": {Where: []Condition{}},
+	}); err != nil {
+		t.Fatalf("FAIL: expected to change the monitor, but failed: %v", err)
+	}
+	req = <-requests
+	if req.Method != "monitor_cond_change" || string(req.Params[2]) != `{"Port_Binding":{"where":[]}}` {
+		t.Fatalf("FAIL: unexpected request: %s %s", req.Method, req.Params)
+	}
+	t.Logf("PASS: 'monitor_cond_since' method completed successfully")
+}
+
+func TestMonitorCancelMethod(t *testing.T) {
+	srv := newTestServer(t, func(s *testServer, req testRequest) {
+		switch req.Method {
+		case "monitor":
+			s.reply(req, map[string]interface{}{})
+		case "monitor_cancel":
+			var id string
+			json.Unmarshal(req.Params[0], &id)
+			if id != "monitor-1" {
+				s.send(map[string]interface{}{"id": req.ID, "result": nil, "error": "unknown monitor"})
+				return
+			}
+			s.reply(req, map[string]interface{}{})
+		}
+	})
+	cli, err := NewClient(srv.Socket)
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	defer cli.Close()
+
+	m, err := cli.Monitor("Open_vSwitch", map[string]MonitorRequest{
+		"Bridge": {Columns: []string{"name"}},
+	})
+	if err != nil {
+		t.Fatalf("FAIL: expected to create a monitor, but failed: %v", err)
+	}
+	if err := cli.MonitorCancel(m); err != nil {
+		t.Fatalf("FAIL: expected to cancel the monitor, but failed: %v", err)
+	}
+	select {
+	case _, ok := <-m.Updates:
+		if ok {
+			t.Fatalf("FAIL: expected the updates channel to be closed")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("FAIL: expected the updates channel to be closed, but timed out")
+	}
+	m.ID = "monitor-2"
+	if err := cli.MonitorCancel(m); err == nil {
+		t.Fatalf("FAIL: expected to fail canceling an unknown monitor")
+	}
+	t.Logf("PASS: 'monitor_cancel' method completed successfully")
+}
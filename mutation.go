@@ -0,0 +1,73 @@
+// Copyright 2018 Paul Greenberg (greenpau@outlook.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovsdb
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Mutation represents <mutation>, as described in
+// [Notation](https://tools.ietf.org/html/rfc7047#section-5.1) section.
+// The Value is encoded the same way as the values of Operation.Row.
+type Mutation struct {
+	Column  string
+	Mutator string
+	Value   interface{}
+}
+
+var mutators = map[string]bool{
+	"+=":     true,
+	"-=":     true,
+	"*=":     true,
+	"/=":     true,
+	"%=":     true,
+	"insert": true,
+	"delete": true,
+}
+
+// NewMutation returns a mutation, e.g. inserting a key-value pair into
+// external_ids column.
+func NewMutation(column, mutator string, value interface{}) (Mutation, error) {
+	m := Mutation{
+		Column:  column,
+		Mutator: mutator,
+		Value:   value,
+	}
+	if err := m.Validate(); err != nil {
+		return m, err
+	}
+	return m, nil
+}
+
+// Validate checks the mutator and the column of the mutation.
+func (m *Mutation) Validate() error {
+	if m.Column == "" {
+		return fmt.Errorf("validation error: mutation has no column")
+	}
+	if _, exists := mutators[m.Mutator]; !exists {
+		return fmt.Errorf("validation error: unsupported mutator: %s", m.Mutator)
+	}
+	return nil
+}
+
+// MarshalJSON encodes the mutation as [column, mutator, value] array.
+func (m Mutation) MarshalJSON() ([]byte, error) {
+	value, err := encodeDatum(m.Value)
+	if err != nil {
+		return []byte{}, fmt.Errorf("marshal Mutation.Value: %s", err)
+	}
+	return json.Marshal([]interface{}{m.Column, m.Mutator, value})
+}
@@ -0,0 +1,266 @@
+// Copyright 2018 Paul Greenberg (greenpau@outlook.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovsdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Notification is a JSON-RPC request sent by the server without an id,
+// e.g. "update".
+type Notification struct {
+	Method string
+	Params json.RawMessage
+}
+
+// notificationHandler routes the notifications received by the messenger
+// to the monitors and the locks of a client.
+type notificationHandler struct {
+	mux      sync.Mutex
+	counter  uint64
+	monitors map[string]*Monitor
+	locks    map[string]bool
+	events   *lockEventQueue
+}
+
+func newNotificationHandler() *notificationHandler {
+	return &notificationHandler{
+		monitors: make(map[string]*Monitor),
+		locks:    make(map[string]bool),
+	}
+}
+
+// nextID returns a new identifier for a monitor.
+func (h *notificationHandler) nextID() string {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	h.counter++
+	return "monitor-" + strconv.FormatUint(h.counter, 10)
+}
+
+func (h *notificationHandler) addMonitor(m *Monitor) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	h.monitors[m.ID] = m
+}
+
+func (h *notificationHandler) getMonitor(id string) *Monitor {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	return h.monitors[id]
+}
+
+func (h *notificationHandler) removeMonitor(id string) {
+	h.mux.Lock()
+	m, exists := h.monitors[id]
+	delete(h.monitors, id)
+	h.mux.Unlock()
+	if exists {
+		m.close()
+	}
+}
+
+// replayableMonitors returns the monitors to re-establish after
+// a reconnect.
+func (h *notificationHandler) replayableMonitors() []*Monitor {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	monitors := []*Monitor{}
+	for _, m := range h.monitors {
+		if !m.internal {
+			monitors = append(monitors, m)
+		}
+	}
+	sort.Slice(monitors, func(i, j int) bool {
+		return monitors[i].ID < monitors[j].ID
+	})
+	return monitors
+}
+
+// closeAll stops all monitors and lock event delivery. The locks held
+// by a client are released when the connection closes.
+func (h *notificationHandler) closeAll() {
+	h.mux.Lock()
+	monitors := h.monitors
+	h.monitors = make(map[string]*Monitor)
+	h.locks = make(map[string]bool)
+	events := h.events
+	h.events = nil
+	h.mux.Unlock()
+	for _, m := range monitors {
+		m.close()
+	}
+	if events != nil {
+		events.close()
+	}
+}
+
+// requestLock records a lock request. It returns false when the lock has
+// already been requested. The locks requested, but not yet acquired, have
+// ownership false.
+func (h *notificationHandler) requestLock(id string) bool {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	if _, exists := h.locks[id]; exists {
+		return false
+	}
+	h.locks[id] = false
+	return true
+}
+
+// setLock records the ownership of a lock.
+func (h *notificationHandler) setLock(id string, owned bool) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	h.locks[id] = owned
+}
+
+func (h *notificationHandler) removeLock(id string) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	delete(h.locks, id)
+}
+
+// lockIDs returns the ids of the requested locks.
+func (h *notificationHandler) lockIDs() []string {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	ids := []string{}
+	for id := range h.locks {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// dropLocks records the loss of the locks held over a closed connection.
+// The locks remain requested.
+func (h *notificationHandler) dropLocks() {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	for id, owned := range h.locks {
+		if !owned {
+			continue
+		}
+		h.locks[id] = false
+		if h.events != nil {
+			h.events.push(LockEvent{ID: id, Locked: false})
+		}
+	}
+}
+
+func (h *notificationHandler) hasLock(id string) bool {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	return h.locks[id]
+}
+
+// lockEvents returns the channel delivering lock events, creating it
+// when necessary.
+func (h *notificationHandler) lockEvents() <-chan LockEvent {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	if h.events == nil {
+		h.events = newLockEventQueue()
+	}
+	return h.events.events
+}
+
+// handleLock processes "locked" and "stolen" notifications.
+func (h *notificationHandler) handleLock(method, id string) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	if _, exists := h.locks[id]; !exists {
+		return
+	}
+	event := LockEvent{ID: id, Locked: method == "locked"}
+	h.locks[id] = event.Locked
+	if h.events != nil {
+		h.events.push(event)
+	}
+}
+
+// handle processes a notification. It never blocks on the consumers of
+// the notification.
+func (h *notificationHandler) handle(n Notification) error {
+	var params []json.RawMessage
+	if err := json.Unmarshal(n.Params, &params); err != nil {
+		return fmt.Errorf("'%s' notification: %v", n.Method, err)
+	}
+	switch n.Method {
+	case "locked", "stolen":
+		if len(params) != 1 {
+			return fmt.Errorf("'%s' notification: invalid number of parameters: %d", n.Method, len(params))
+		}
+		var id string
+		if err := json.Unmarshal(params[0], &id); err != nil {
+			return fmt.Errorf("'%s' notification: unsupported lock id: %s", n.Method, params[0])
+		}
+		h.handleLock(n.Method, id)
+		return nil
+	case "update", "update2":
+		if len(params) != 2 {
+			return fmt.Errorf("'%s' notification: invalid number of parameters: %d", n.Method, len(params))
+		}
+	case "update3":
+		if len(params) != 3 {
+			return fmt.Errorf("'%s' notification: invalid number of parameters: %d", n.Method, len(params))
+		}
+	default:
+		return fmt.Errorf("unsupported notification: %s", n.Method)
+	}
+	var id string
+	if err := json.Unmarshal(params[0], &id); err != nil {
+		return fmt.Errorf("'%s' notification: unsupported monitor id: %s", n.Method, params[0])
+	}
+	m := h.getMonitor(id)
+	if m == nil {
+		return fmt.Errorf("'%s' notification: monitor '%s' not found", n.Method, id)
+	}
+	var u monitorUpdate
+	switch n.Method {
+	case "update":
+		if err := json.Unmarshal(params[1], &u.updates); err != nil {
+			return fmt.Errorf("'%s' notification: %v", n.Method, err)
+		}
+	case "update2":
+		if err := json.Unmarshal(params[1], &u.updates2); err != nil {
+			return fmt.Errorf("'%s' notification: %v", n.Method, err)
+		}
+	case "update3":
+		var txnID string
+		if err := json.Unmarshal(params[1], &txnID); err != nil {
+			return fmt.Errorf("'%s' notification: %v", n.Method, err)
+		}
+		if err := json.Unmarshal(params[2], &u.updates2); err != nil {
+			return fmt.Errorf("'%s' notification: %v", n.Method, err)
+		}
+		m.setLastTransactionID(txnID)
+	}
+	if u.updates == nil && u.updates2 == nil {
+		// The server sent "null" or an empty object.
+		if m.Method == "monitor" {
+			u.updates = TableUpdates{}
+		} else {
+			u.updates2 = TableUpdates2{}
+		}
+	}
+	m.push(u)
+	return nil
+}
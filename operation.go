@@ -15,10 +15,11 @@
 package ovsdb
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	//"github.com/davecgh/go-spew/spew"
 	"strings"
-	"text/scanner"
 )
 
 // Operation represents Transact Method, as described in
@@ -28,85 +29,293 @@ type Operation struct {
 	Table      string      `json:"table"`
 	Conditions []Condition `json:"where"`
 	Columns    []string    `json:"columns,omitempty"`
+	Row        Row         `json:"row,omitempty"`
+	Mutations  []Mutation  `json:"mutations,omitempty"`
+	UUIDName   string      `json:"uuid-name,omitempty"`
+	// Timeout is the number of milliseconds "wait" operation waits for
+	// the condition to become true. When nil, the operation waits forever.
+	Timeout *int   `json:"timeout,omitempty"`
+	Until   string `json:"until,omitempty"`
+	Rows    []Row  `json:"rows,omitempty"`
+	Durable bool   `json:"durable,omitempty"`
+	Comment string `json:"comment,omitempty"`
+	Lock    string `json:"lock,omitempty"`
 }
 
-// NewOperation - TODO
-func NewOperation(query string) (Operation, error) {
-	t := Operation{}
-	t.Conditions = []Condition{}
-	if err := t.Parse(query); err != nil {
+// NewInsertOperation returns "insert" operation, as described in
+// https://tools.ietf.org/html/rfc7047#section-5.2.1. The uuidName,
+// if not empty, allows referring to the inserted row in the other
+// operations of the same transaction.
+func NewInsertOperation(table string, row Row, uuidName string) (Operation, error) {
+	t := Operation{
+		Name:     "insert",
+		Table:    table,
+		Row:      row,
+		UUIDName: uuidName,
+	}
+	if err := t.Validate(); err != nil {
 		return t, err
 	}
+	return t, nil
+}
+
+// NewUpdateOperation returns "update" operation, as described in
+// https://tools.ietf.org/html/rfc7047#section-5.2.3.
+func NewUpdateOperation(table string, row Row, conditions ...Condition) (Operation, error) {
+	t := Operation{
+		Name:       "update",
+		Table:      table,
+		Row:        row,
+		Conditions: conditions,
+	}
 	if err := t.Validate(); err != nil {
 		return t, err
 	}
 	return t, nil
 }
 
-// Parse - TODO
-func (t *Operation) Parse(i string) error {
-	var s scanner.Scanner
-	s.Init(strings.NewReader(i))
-	var tok rune
-	stage := "operation"
-	conditions := []string{}
-	for tok != scanner.EOF {
-		tok = s.Scan()
-		if s.TokenText() == "" {
+// NewMutateOperation returns "mutate" operation, as described in
+// https://tools.ietf.org/html/rfc7047#section-5.2.4.
+func NewMutateOperation(table string, mutations []Mutation, conditions ...Condition) (Operation, error) {
+	t := Operation{
+		Name:       "mutate",
+		Table:      table,
+		Mutations:  mutations,
+		Conditions: conditions,
+	}
+	if err := t.Validate(); err != nil {
+		return t, err
+	}
+	return t, nil
+}
+
+// NewDeleteOperation returns "delete" operation, as described in
+// https://tools.ietf.org/html/rfc7047#section-5.2.5. Without conditions,
+// the operation deletes all rows in the table.
+func NewDeleteOperation(table string, conditions ...Condition) (Operation, error) {
+	t := Operation{
+		Name:       "delete",
+		Table:      table,
+		Conditions: conditions,
+	}
+	if err := t.Validate(); err != nil {
+		return t, err
+	}
+	return t, nil
+}
+
+// NewWaitOperation returns "wait" operation, as described in
+// https://tools.ietf.org/html/rfc7047#section-5.2.6. The operation
+// waits until the columns of the rows matching the conditions are equal
+// ("==") or not equal ("!=") to the rows. A negative timeout means
+// waiting forever.
+func NewWaitOperation(table string, timeout int, until string, columns []string, rows []Row, conditions ...Condition) (Operation, error) {
+	t := Operation{
+		Name:       "wait",
+		Table:      table,
+		Conditions: conditions,
+		Columns:    columns,
+		Until:      until,
+		Rows:       rows,
+	}
+	if timeout >= 0 {
+		t.Timeout = &timeout
+	}
+	if err := t.Validate(); err != nil {
+		return t, err
+	}
+	return t, nil
+}
+
+// NewCommitOperation returns "commit" operation, as described in
+// https://tools.ietf.org/html/rfc7047#section-5.2.7. When durable is
+// true, the transaction is not committed until the changes are written
+// to the disk.
+func NewCommitOperation(durable bool) (Operation, error) {
+	t := Operation{
+		Name:    "commit",
+		Durable: durable,
+	}
+	if err := t.Validate(); err != nil {
+		return t, err
+	}
+	return t, nil
+}
+
+// NewAbortOperation returns "abort" operation, as described in
+// https://tools.ietf.org/html/rfc7047#section-5.2.8. The operation
+// always fails, aborting the transaction.
+func NewAbortOperation() (Operation, error) {
+	t := Operation{
+		Name: "abort",
+	}
+	if err := t.Validate(); err != nil {
+		return t, err
+	}
+	return t, nil
+}
+
+// NewCommentOperation returns "comment" operation, as described in
+// https://tools.ietf.org/html/rfc7047#section-5.2.9. The server logs
+// the comment with the transaction.
+func NewCommentOperation(comment string) (Operation, error) {
+	t := Operation{
+		Name:    "comment",
+		Comment: comment,
+	}
+	if err := t.Validate(); err != nil {
+		return t, err
+	}
+	return t, nil
+}
+
+// NewAssertOperation returns "assert" operation, as described in
+// https://tools.ietf.org/html/rfc7047#section-5.2.10. The transaction
+// fails unless the client owns the lock.
+func NewAssertOperation(lock string) (Operation, error) {
+	t := Operation{
+		Name: "assert",
+		Lock: lock,
+	}
+	if err := t.Validate(); err != nil {
+		return t, err
+	}
+	return t, nil
+}
+
+// MarshalJSON encodes the members of the operation supported by
+// the operation type.
+func (t Operation) MarshalJSON() ([]byte, error) {
+	op, exists := operations[t.Name]
+	if !exists {
+		return nil, fmt.Errorf("marshal Operation: unsupported operation: %s", t.Name)
+	}
+	b := bytes.NewBufferString("{")
+	for _, name := range operationMembers {
+		m, exists := op.Members[name]
+		if !exists {
 			continue
 		}
-		switch stage {
-		case "operation":
-			t.Name = strings.ToLower(s.TokenText())
-			stage = "columns"
-		case "columns":
-			if s.TokenText() == "FROM" {
-				stage = "table"
+		var v interface{}
+		switch name {
+		case "op":
+			v = t.Name
+		case "table":
+			v = t.Table
+		case "uuid-name":
+			if t.UUIDName == "" {
 				continue
 			}
-			if s.TokenText() != "," && s.TokenText() != "*" {
-				t.Columns = append(t.Columns, s.TokenText())
+			v = t.UUIDName
+		case "row":
+			row, err := encodeRow(t.Row)
+			if err != nil {
+				return nil, fmt.Errorf("marshal Operation.Row: %s", err)
 			}
-		case "table":
-			t.Table = s.TokenText()
-			stage = "where"
+			v = row
 		case "where":
-			if s.TokenText() != "WHERE" {
-				return fmt.Errorf("parser error: expected WHERE clause")
+			if t.Conditions == nil {
+				v = []Condition{}
+			} else {
+				v = t.Conditions
 			}
-			stage = "conditions"
-		case "conditions":
-			if t.Table == "" {
-				return fmt.Errorf("parser error: expected FROM clause followed by a table name")
+		case "mutations":
+			if t.Mutations == nil {
+				v = []Mutation{}
+			} else {
+				v = t.Mutations
 			}
-			if s.TokenText() == "LIMIT" {
-				stage = "lmits"
+		case "columns":
+			if len(t.Columns) == 0 && !m.Required {
 				continue
 			}
-			if s.TokenText() == "," {
-				cond, err := NewCondition(conditions)
+			v = t.Columns
+		case "timeout":
+			if t.Timeout == nil {
+				continue
+			}
+			v = *t.Timeout
+		case "until":
+			v = t.Until
+		case "rows":
+			rows := []map[string]interface{}{}
+			for _, row := range t.Rows {
+				r, err := encodeRow(row)
 				if err != nil {
-					return fmt.Errorf("parser error: %s for: %s", err, i)
+					return nil, fmt.Errorf("marshal Operation.Rows: %s", err)
 				}
-				t.Conditions = append(t.Conditions, cond)
-				conditions = conditions[:0]
-				continue
+				rows = append(rows, r)
 			}
-			conditions = append(conditions, s.TokenText())
-		case "limits":
-			return fmt.Errorf("parser error: unsupported LIMIT clause")
-		default:
-			return fmt.Errorf("parser error: unknown stage: %s", stage)
+			v = rows
+		case "durable":
+			v = t.Durable
+		case "comment":
+			v = t.Comment
+		case "lock":
+			v = t.Lock
 		}
-	}
-	if len(conditions) > 0 {
-		cond, err := NewCondition(conditions)
+		value, err := json.Marshal(v)
 		if err != nil {
-			return fmt.Errorf("parser error: invalid condition: %s", conditions)
+			return nil, err
+		}
+		if b.Len() > 1 {
+			b.WriteString(",")
 		}
-		t.Conditions = append(t.Conditions, cond)
+		b.WriteString("\"" + name + "\":")
+		b.Write(value)
 	}
-	//spew.Dump(t)
+	b.WriteString("}")
+	return b.Bytes(), nil
+}
+
+// NewOperation - TODO
+func NewOperation(query string) (Operation, error) {
+	t := Operation{}
+	t.Conditions = []Condition{}
+	if err := t.Parse(query); err != nil {
+		return t, err
+	}
+	if err := t.Validate(); err != nil {
+		return t, err
+	}
+	return t, nil
+}
+
+// Parse parses a query of the following SQL-like language into the
+// operation:
+//
+//	SELECT * | column, ... FROM table [WHERE condition, ...]
+//	INSERT INTO table (column, ...) VALUES (value, ...)
+//	UPDATE table SET column=value, ... [WHERE condition, ...]
+//	DELETE FROM table [WHERE condition, ...]
+//	MUTATE table SET column mutator value, ... [WHERE condition, ...]
+//
+// The keywords are case-insensitive, and the identifiers clashing with
+// them are quoted with backquotes, e.g. `from`. The conditions, separated
+// by commas or AND, are "column function value", where the function is
+// one of the functions of RFC 7047, e.g. "==" or "includes". The mutators
+// are "+=", "-=", "*=", "/=", "%=", "insert", and "delete".
+//
+// The values are the strings quoted with double or single quotes, the
+// sets, e.g. [a, b], the maps, e.g. {key=value}, and the unquoted atoms,
+// e.g. true, 100, or br-int, parsed as Barewords. When the schema of the
+// database is known, the client converts the values into the types of the
+// columns, e.g. the strings into UUIDs, and the Bareword 007 into the
+// string "007" or the integer 7. Otherwise, the Barewords are sent as the
+// booleans, the integers, or the reals they look like, or as strings.
+//
+// The errors report the line and the column of the offending token. The
+// clauses executed by the client, e.g. JOIN or LIMIT, require ParseQuery.
+func (t *Operation) Parse(i string) error {
+	q := Query{Operation: *t, Limit: -1}
+	p := newQueryParser(i)
+	if err := p.parse(&q); err != nil {
+		return err
+	}
+	if p.clause != "" {
+		return errorAt(p.clausePos, "%s clause is executed by the client, see ParseQuery", p.clause)
+	}
+	*t = q.Operation
 	return nil
 }
 
@@ -139,6 +348,38 @@ func (t *Operation) Validate() error {
 			if len(t.Columns) == 0 && m.Required {
 				return fmt.Errorf("validation error: no columns")
 			}
+		case "row":
+			if t.Row == nil && m.Required {
+				return fmt.Errorf("validation error: no row")
+			}
+		case "mutations":
+			if len(t.Mutations) == 0 && m.Required {
+				return fmt.Errorf("validation error: no mutations")
+			}
+			for _, mutation := range t.Mutations {
+				if err := mutation.Validate(); err != nil {
+					return err
+				}
+			}
+		case "uuid-name":
+		case "timeout":
+			if t.Timeout != nil && *t.Timeout < 0 {
+				return fmt.Errorf("validation error: negative timeout")
+			}
+		case "until":
+			if t.Until != "==" && t.Until != "!=" {
+				return fmt.Errorf("validation error: unsupported until: %s", t.Until)
+			}
+		case "rows":
+		case "durable":
+		case "comment":
+			if t.Comment == "" && m.Required {
+				return fmt.Errorf("validation error: no comment")
+			}
+		case "lock":
+			if t.Lock == "" && m.Required {
+				return fmt.Errorf("validation error: no lock")
+			}
 		default:
 			return fmt.Errorf("validation error: unsupported transaction member: %s", m.Name)
 		}
@@ -157,6 +398,12 @@ type operationConfiguration struct {
 	Members map[string]member
 }
 
+// operationMembers is the order of the members of the encoded operations.
+var operationMembers = []string{
+	"op", "table", "uuid-name", "row", "where", "mutations", "columns",
+	"timeout", "until", "rows", "durable", "comment", "lock",
+}
+
 var operations = map[string]operationConfiguration{
 	"select": {
 		Name: "select",
@@ -180,4 +427,169 @@ var operations = map[string]operationConfiguration{
 			},
 		},
 	},
+	"insert": {
+		Name: "insert",
+		Members: map[string]member{
+			"op": {
+				Name:     "op",
+				Required: true,
+			},
+			"table": {
+				Name:     "table",
+				Required: true,
+			},
+			"row": {
+				Name:     "row",
+				Required: true,
+			},
+			"uuid-name": {
+				Name:     "uuid-name",
+				Required: false,
+			},
+		},
+	},
+	"update": {
+		Name: "update",
+		Members: map[string]member{
+			"op": {
+				Name:     "op",
+				Required: true,
+			},
+			"table": {
+				Name:     "table",
+				Required: true,
+			},
+			"where": {
+				Name:     "where",
+				Required: true,
+				Autofill: true,
+			},
+			"row": {
+				Name:     "row",
+				Required: true,
+			},
+		},
+	},
+	"mutate": {
+		Name: "mutate",
+		Members: map[string]member{
+			"op": {
+				Name:     "op",
+				Required: true,
+			},
+			"table": {
+				Name:     "table",
+				Required: true,
+			},
+			"where": {
+				Name:     "where",
+				Required: true,
+				Autofill: true,
+			},
+			"mutations": {
+				Name:     "mutations",
+				Required: true,
+			},
+		},
+	},
+	"delete": {
+		Name: "delete",
+		Members: map[string]member{
+			"op": {
+				Name:     "op",
+				Required: true,
+			},
+			"table": {
+				Name:     "table",
+				Required: true,
+			},
+			"where": {
+				Name:     "where",
+				Required: true,
+				Autofill: true,
+			},
+		},
+	},
+	"wait": {
+		Name: "wait",
+		Members: map[string]member{
+			"op": {
+				Name:     "op",
+				Required: true,
+			},
+			"table": {
+				Name:     "table",
+				Required: true,
+			},
+			"where": {
+				Name:     "where",
+				Required: true,
+				Autofill: true,
+			},
+			"columns": {
+				Name:     "columns",
+				Required: true,
+			},
+			"timeout": {
+				Name:     "timeout",
+				Required: false,
+			},
+			"until": {
+				Name:     "until",
+				Required: true,
+			},
+			"rows": {
+				Name:     "rows",
+				Required: true,
+			},
+		},
+	},
+	"commit": {
+		Name: "commit",
+		Members: map[string]member{
+			"op": {
+				Name:     "op",
+				Required: true,
+			},
+			"durable": {
+				Name:     "durable",
+				Required: true,
+			},
+		},
+	},
+	"abort": {
+		Name: "abort",
+		Members: map[string]member{
+			"op": {
+				Name:     "op",
+				Required: true,
+			},
+		},
+	},
+	"comment": {
+		Name: "comment",
+		Members: map[string]member{
+			"op": {
+				Name:     "op",
+				Required: true,
+			},
+			"comment": {
+				Name:     "comment",
+				Required: true,
+			},
+		},
+	},
+	"assert": {
+		Name: "assert",
+		Members: map[string]member{
+			"op": {
+				Name:     "op",
+				Required: true,
+			},
+			"lock": {
+				Name:     "lock",
+				Required: true,
+			},
+		},
+	},
 }
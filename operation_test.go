@@ -23,7 +23,7 @@ import (
 
 func TestNewOperation(t *testing.T) {
 	sock := "unix:/var/run/openvswitch/db.sock"
-	cli, err := NewClient(sock, 0)
+	cli, err := NewClient(sock)
 	if err != nil {
 		t.Fatalf("FAIL: expected to connect to %s, but failed with: %v", sock, err)
 	}
@@ -63,7 +63,7 @@ func TestNewOperation(t *testing.T) {
 
 func TestMarshalOperation(t *testing.T) {
 	sock := "unix:/var/run/openvswitch/db.sock"
-	cli, err := NewClient(sock, 0)
+	cli, err := NewClient(sock)
 	if err != nil {
 		t.Fatalf("FAIL: expected to connect to %s, but failed with: %v", sock, err)
 	}
@@ -109,3 +109,142 @@ func TestMarshalOperation(t *testing.T) {
 		t.Fatalf("Failed %d tests", testFailed)
 	}
 }
+
+func TestMarshalWriteOperation(t *testing.T) {
+	testFailed := 0
+	newMutation := func(column, mutator string, value interface{}) Mutation {
+		m, err := NewMutation(column, mutator, value)
+		if err != nil {
+			t.Fatalf("FAIL: %v", err)
+		}
+		return m
+	}
+	for i, test := range []struct {
+		op         func() (Operation, error)
+		response   []byte
+		shouldFail bool
+	}{
+		{
+			op: func() (Operation, error) {
+				return NewInsertOperation("Logical_Switch", Row{
+					"name":         "ls1",
+					"external_ids": map[string]string{"owner": "ovsdb", "env": "test"},
+					"ports":        []interface{}{},
+				}, "new_ls")
+			},
+			response: []byte(`{"op":"insert","table":"Logical_Switch","uuid-name":"new_ls","row":{"external_ids":["map",[["env","test"],["owner","ovsdb"]]],"name":"ls1","ports":["set",[]]}}`),
+		},
+		{
+			op: func() (Operation, error) {
+				return NewUpdateOperation("Logical_Switch", Row{"name": "ls2"}, NewUUIDCondition("a8f2c1e0-5f4c-4d6e-9b1a-3c2d1e0f9a8b"))
+			},
+			response: []byte(`{"op":"update","table":"Logical_Switch","row":{"name":"ls2"},"where":[["_uuid","==",["uuid","a8f2c1e0-5f4c-4d6e-9b1a-3c2d1e0f9a8b"]]]}`),
+		},
+		{
+			op: func() (Operation, error) {
+				return NewMutateOperation("Logical_Switch", []Mutation{
+					newMutation("external_ids", "insert", map[string]string{"owner": "ovsdb"}),
+					newMutation("ports", "delete", []interface{}{[]interface{}{"uuid", "0c3e1b2a-7d6f-4e5a-8b9c-1d2e3f4a5b6c"}}),
+				})
+			},
+			response: []byte(`{"op":"mutate","table":"Logical_Switch","where":[],"mutations":[["external_ids","insert",["map",[["owner","ovsdb"]]]],["ports","delete",["set",[["uuid","0c3e1b2a-7d6f-4e5a-8b9c-1d2e3f4a5b6c"]]]]]}`),
+		},
+		{
+			op: func() (Operation, error) {
+				return NewDeleteOperation("Logical_Switch_Port", NewUUIDCondition("0c3e1b2a-7d6f-4e5a-8b9c-1d2e3f4a5b6c"))
+			},
+			response: []byte(`{"op":"delete","table":"Logical_Switch_Port","where":[["_uuid","==",["uuid","0c3e1b2a-7d6f-4e5a-8b9c-1d2e3f4a5b6c"]]]}`),
+		},
+		{
+			op: func() (Operation, error) {
+				return NewWaitOperation("Logical_Switch", 1000, "==", []string{"name"}, []Row{{"name": "ls1"}}, NewUUIDCondition("a8f2c1e0-5f4c-4d6e-9b1a-3c2d1e0f9a8b"))
+			},
+			response: []byte(`{"op":"wait","table":"Logical_Switch","where":[["_uuid","==",["uuid","a8f2c1e0-5f4c-4d6e-9b1a-3c2d1e0f9a8b"]]],"columns":["name"],"timeout":1000,"until":"==","rows":[{"name":"ls1"}]}`),
+		},
+		{
+			op: func() (Operation, error) {
+				return NewWaitOperation("Logical_Switch", -1, "!=", []string{"name"}, []Row{})
+			},
+			response: []byte(`{"op":"wait","table":"Logical_Switch","where":[],"columns":["name"],"until":"!=","rows":[]}`),
+		},
+		{
+			op: func() (Operation, error) {
+				return NewWaitOperation("Logical_Switch", 0, "=", []string{"name"}, []Row{})
+			},
+			shouldFail: true,
+		},
+		{
+			op: func() (Operation, error) {
+				return NewCommitOperation(true)
+			},
+			response: []byte(`{"op":"commit","durable":true}`),
+		},
+		{
+			op: func() (Operation, error) {
+				return NewAbortOperation()
+			},
+			response: []byte(`{"op":"abort"}`),
+		},
+		{
+			op: func() (Operation, error) {
+				return NewCommentOperation("ovsdb: remove stale ports")
+			},
+			response: []byte(`{"op":"comment","comment":"ovsdb: remove stale ports"}`),
+		},
+		{
+			op: func() (Operation, error) {
+				return NewAssertOperation("controller")
+			},
+			response: []byte(`{"op":"assert","lock":"controller"}`),
+		},
+		{
+			op: func() (Operation, error) {
+				return NewAssertOperation("")
+			},
+			shouldFail: true,
+		},
+		{
+			op: func() (Operation, error) {
+				return NewMutateOperation("Logical_Switch", []Mutation{})
+			},
+			shouldFail: true,
+		},
+		{
+			op: func() (Operation, error) {
+				return NewInsertOperation("", Row{}, "")
+			},
+			shouldFail: true,
+		},
+	} {
+		op, err := test.op()
+		if err != nil {
+			if !test.shouldFail {
+				t.Logf("FAIL: Test %d: expected to create an operation, but failed: %v", i, err)
+				testFailed++
+				continue
+			}
+			t.Logf("PASS: Test %d: expected to fail, failed with: %v", i, err)
+			continue
+		}
+		if test.shouldFail {
+			t.Logf("FAIL: Test %d: expected to fail, but passed: %v", i, op)
+			testFailed++
+			continue
+		}
+		response, err := json.Marshal(op)
+		if err != nil {
+			t.Logf("FAIL: Test %d: expected to marshal, but failed: %v", i, err)
+			testFailed++
+			continue
+		}
+		if !bytes.Equal(response, test.response) {
+			t.Logf("FAIL: Test %d: the expected and actual responses do not match: '%s' vs. '%s'", i, test.response, response)
+			testFailed++
+			continue
+		}
+		t.Logf("PASS: Test %d: operation '%s', expected to pass, passed", i, op.Name)
+	}
+	if testFailed > 0 {
+		t.Fatalf("Failed %d tests", testFailed)
+	}
+}
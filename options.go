@@ -0,0 +1,167 @@
+// Copyright 2018 Paul Greenberg (greenpau@outlook.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovsdb
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+const (
+	// DefaultDialTimeout is the time allowed for connecting to a remote.
+	DefaultDialTimeout = 2 * time.Second
+	// DefaultMaxRetries is the number of times a request is resent after
+	// the connection fails.
+	DefaultMaxRetries = 2
+)
+
+// Logger logs the connection events and the failures the client recovers
+// from. It is satisfied by *log.Logger.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// DialContextFunc connects to an address, e.g. via a custom net.Dialer,
+// in another network namespace, or over a test pipe. The network is
+// "tcp" or "unix".
+type DialContextFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// Option configures a client created with NewClient.
+type Option func(*Client)
+
+// WithDialTimeout sets the time allowed for connecting to a remote,
+// including TLS handshake. The default is DefaultDialTimeout.
+func WithDialTimeout(d time.Duration) Option {
+	return func(cli *Client) {
+		cli.dialTimeout = d
+	}
+}
+
+// WithRequestTimeout sets the deadline of the requests whose contexts
+// have no deadline. It also limits the time the client waits for the
+// server to cancel a request. By default, the requests have no deadline.
+func WithRequestTimeout(d time.Duration) Option {
+	return func(cli *Client) {
+		cli.requestTimeout = d
+	}
+}
+
+// WithTLS sets the options of the connections to "ssl:" remotes.
+func WithTLS(opts *TLSOptions) Option {
+	return func(cli *Client) {
+		cli.tls = opts
+	}
+}
+
+// WithLogger sets the logger of the client. By default, the client does
+// not log.
+func WithLogger(logger Logger) Option {
+	return func(cli *Client) {
+		cli.logger = logger
+	}
+}
+
+// WithDialer makes the client connect to the remotes with a dialer.
+func WithDialer(dialer *net.Dialer) Option {
+	return func(cli *Client) {
+		cli.dialContext = dialer.DialContext
+	}
+}
+
+// WithDialContext makes the client connect to the remotes with a function.
+// The "ssl:" remotes are connected with the function, too, and then
+// secured with TLS.
+func WithDialContext(dial DialContextFunc) Option {
+	return func(cli *Client) {
+		cli.dialContext = dial
+	}
+}
+
+// WithRetryPolicy sets the number of times a request is resent after the
+// connection fails, and the delays between the attempts to reconnect.
+func WithRetryPolicy(maxRetries int, policy ReconnectPolicy) Option {
+	return func(cli *Client) {
+		cli.maxRetries = maxRetries
+		cli.reconnect = policy
+	}
+}
+
+// WithRandomRemotes makes the client try the remotes of the endpoint in
+// random order, rather than in the order of the endpoint.
+func WithRandomRemotes() Option {
+	return func(cli *Client) {
+		cli.randomRemotes = true
+	}
+}
+
+// WithLeaderOnly makes the client connect to the cluster leader of the
+// databases dbs only, and reconnect when the leadership moves. Without
+// databases, the client requires the leadership of all the clustered
+// databases of the server.
+func WithLeaderOnly(dbs ...string) Option {
+	return func(cli *Client) {
+		cli.leaderOnly = true
+		cli.leaderDatabases = make(map[string]bool)
+		for _, db := range dbs {
+			cli.leaderDatabases[db] = true
+		}
+	}
+}
+
+// WithInactivityProbe sets the interval of the inactivity probes. When the
+// server sends nothing for the interval, the client sends "echo" request,
+// and when the server sends nothing for another interval, the client
+// reconnects. By default, the probes are disabled.
+func WithInactivityProbe(d time.Duration) Option {
+	return func(cli *Client) {
+		cli.inactivityProbe = d
+	}
+}
+
+// WithConnectionEventHandler sets the function called when the client
+// connects, disconnects, or starts reconnecting. The function is called
+// synchronously and must not call the methods of the client.
+func WithConnectionEventHandler(handler func(ConnectionEvent)) Option {
+	return func(cli *Client) {
+		cli.onConnectionEvent = handler
+	}
+}
+
+// logf logs a message, when the client has a logger.
+func (cli *Client) logf(format string, v ...interface{}) {
+	if cli.logger != nil {
+		cli.logger.Printf(format, v...)
+	}
+}
+
+// timeout returns the time allowed for the requests made by the client
+// itself, e.g. the replay of the monitors.
+func (cli *Client) timeout() time.Duration {
+	if cli.requestTimeout > 0 {
+		return cli.requestTimeout
+	}
+	return DefaultDialTimeout
+}
+
+// withTimeoutSeconds sets the dial timeout in seconds, as configured in
+// OvnClient and OvsClient. Zero means the default.
+func withTimeoutSeconds(t int) Option {
+	return func(cli *Client) {
+		if t > 0 {
+			cli.dialTimeout = time.Duration(t) * time.Second
+		}
+	}
+}
@@ -0,0 +1,468 @@
+// Copyright 2018 Paul Greenberg (greenpau@outlook.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovsdb
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// Model is implemented by the structs mapped to the rows of a table. The
+// fields of a model are mapped to the columns with "ovsdb" tags, e.g.
+//
+//	type LogicalSwitch struct {
+//		UUID  string   `ovsdb:"_uuid"`
+//		Name  string   `ovsdb:"name"`
+//		Ports []string `ovsdb:"ports"`
+//	}
+//
+//	func (LogicalSwitch) Database() string { return "OVN_Northbound" }
+//	func (LogicalSwitch) Table() string    { return "Logical_Switch" }
+//
+// The atoms map to string, UUID, integer, float, and bool fields. The
+// strings hold the UUIDs of the references. The sets map to slices, the
+// maps map to Go maps, and the optional values, i.e. the sets of at most
+// one element, map to pointers, slices, or the atoms, with the zero value
+// standing for the empty set.
+type Model interface {
+	Database() string
+	Table() string
+}
+
+// modelField is a field of a model mapped to a column.
+type modelField struct {
+	index  int
+	name   string
+	column string
+}
+
+var (
+	uuidType   = reflect.TypeOf(UUID(""))
+	ovsSetType = reflect.TypeOf(OvsSet{})
+	ovsMapType = reflect.TypeOf(OvsMap{})
+)
+
+// modelFields returns the fields of a model type with "ovsdb" tags.
+func modelFields(t reflect.Type) ([]modelField, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("model %s is not a struct", t)
+	}
+	fields := []modelField{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		column := f.Tag.Get("ovsdb")
+		if column == "" || column == "-" {
+			continue
+		}
+		if f.PkgPath != "" {
+			return nil, fmt.Errorf("model %s: field %s is not exported", t, f.Name)
+		}
+		fields = append(fields, modelField{index: i, name: f.Name, column: column})
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("model %s has no fields with ovsdb tags", t)
+	}
+	return fields, nil
+}
+
+// validateModel verifies that the fields of a model match the columns of
+// its table in the schema.
+func validateModel(schema Schema, table string, t reflect.Type, fields []modelField) error {
+	tableSchema, exists := schema.Tables[table]
+	if !exists {
+		return fmt.Errorf("model %s: table %s not found in %s schema", t, table, schema.Name)
+	}
+	for _, f := range fields {
+		ft := t.Field(f.index).Type
+		if f.column == "_uuid" || f.column == "_version" {
+			if ft.Kind() != reflect.String {
+				return fmt.Errorf("model %s: field %s: column %s requires string or UUID type, but got %s", t, f.name, f.column, ft)
+			}
+			continue
+		}
+		column, exists := tableSchema.Columns[f.column]
+		if !exists {
+			return fmt.Errorf("model %s: field %s: column %s not found in %s table", t, f.name, f.column, table)
+		}
+		if err := validateField(ft, column.Type); err != nil {
+			return fmt.Errorf("model %s: field %s: column %s: %v", t, f.name, f.column, err)
+		}
+	}
+	return nil
+}
+
+func validateField(ft reflect.Type, c ColumnType) error {
+	switch {
+	case ft == ovsMapType:
+		if !c.IsMap() {
+			return fmt.Errorf("OvsMap requires map column")
+		}
+		return nil
+	case ft == ovsSetType:
+		if c.IsMap() {
+			return fmt.Errorf("OvsSet requires set column")
+		}
+		return nil
+	}
+	switch ft.Kind() {
+	case reflect.Map:
+		if !c.IsMap() {
+			return fmt.Errorf("%s requires map column", ft)
+		}
+		if err := validateAtom(ft.Key(), c.Key.Type); err != nil {
+			return err
+		}
+		return validateAtom(ft.Elem(), c.Value.Type)
+	case reflect.Slice:
+		if !c.IsSet() {
+			return fmt.Errorf("%s requires set column", ft)
+		}
+		return validateAtom(ft.Elem(), c.Key.Type)
+	case reflect.Ptr:
+		if !c.IsOptional() {
+			return fmt.Errorf("%s requires optional column", ft)
+		}
+		return validateAtom(ft.Elem(), c.Key.Type)
+	}
+	if c.IsMap() || c.Max != 1 {
+		return fmt.Errorf("%s requires atomic or optional column", ft)
+	}
+	return validateAtom(ft, c.Key.Type)
+}
+
+func validateAtom(t reflect.Type, base string) error {
+	ok := false
+	switch t.Kind() {
+	case reflect.String:
+		ok = base == "string" || base == "uuid"
+		if t == uuidType {
+			ok = base == "uuid"
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		ok = base == "integer"
+	case reflect.Float32, reflect.Float64:
+		ok = base == "real" || base == "integer"
+	case reflect.Bool:
+		ok = base == "boolean"
+	case reflect.Interface:
+		ok = t.NumMethod() == 0
+	}
+	if !ok {
+		return fmt.Errorf("%s does not match %s type", t, base)
+	}
+	return nil
+}
+
+// decodeField sets a field to the value of a column.
+func decodeField(fv reflect.Value, value interface{}) error {
+	datum, err := DecodeDatum(value)
+	if err != nil {
+		return err
+	}
+	switch {
+	case fv.Type() == ovsSetType:
+		fv.Set(reflect.ValueOf(datumSet(datum)))
+		return nil
+	case fv.Type() == ovsMapType:
+		m, ok := datum.(OvsMap)
+		if !ok {
+			return fmt.Errorf("%v is not a map", value)
+		}
+		fv.Set(reflect.ValueOf(m))
+		return nil
+	}
+	switch fv.Kind() {
+	case reflect.Map:
+		m, ok := datum.(OvsMap)
+		if !ok {
+			return fmt.Errorf("%v is not a map", value)
+		}
+		mv := reflect.MakeMapWithSize(fv.Type(), len(m))
+		for k, v := range m {
+			kv := reflect.New(fv.Type().Key()).Elem()
+			if err := decodeModelAtom(kv, k); err != nil {
+				return err
+			}
+			vv := reflect.New(fv.Type().Elem()).Elem()
+			if err := decodeModelAtom(vv, v); err != nil {
+				return err
+			}
+			mv.SetMapIndex(kv, vv)
+		}
+		fv.Set(mv)
+		return nil
+	case reflect.Slice:
+		elements := datumSet(datum)
+		sv := reflect.MakeSlice(fv.Type(), len(elements), len(elements))
+		for i, e := range elements {
+			if err := decodeModelAtom(sv.Index(i), e); err != nil {
+				return err
+			}
+		}
+		fv.Set(sv)
+		return nil
+	case reflect.Ptr:
+		elements := datumSet(datum)
+		if len(elements) == 0 {
+			fv.Set(reflect.Zero(fv.Type()))
+			return nil
+		}
+		pv := reflect.New(fv.Type().Elem())
+		if err := decodeModelAtom(pv.Elem(), elements[0]); err != nil {
+			return err
+		}
+		fv.Set(pv)
+		return nil
+	}
+	elements := datumSet(datum)
+	switch len(elements) {
+	case 0:
+		fv.Set(reflect.Zero(fv.Type()))
+		return nil
+	case 1:
+		return decodeModelAtom(fv, elements[0])
+	}
+	return fmt.Errorf("%v has more than one element", value)
+}
+
+// decodeModelAtom sets a value to an atom.
+func decodeModelAtom(v reflect.Value, atom interface{}) error {
+	switch v.Kind() {
+	case reflect.String:
+		switch a := atom.(type) {
+		case string:
+			v.SetString(a)
+			return nil
+		case UUID:
+			v.SetString(string(a))
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if a, ok := atom.(int64); ok {
+			v.SetInt(a)
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		switch a := atom.(type) {
+		case float64:
+			v.SetFloat(a)
+			return nil
+		case int64:
+			v.SetFloat(float64(a))
+			return nil
+		}
+	case reflect.Bool:
+		if a, ok := atom.(bool); ok {
+			v.SetBool(a)
+			return nil
+		}
+	case reflect.Interface:
+		v.Set(reflect.ValueOf(atom))
+		return nil
+	}
+	return fmt.Errorf("cannot assign %v to %s", atom, v.Type())
+}
+
+// encodeField returns the value of a column from a field.
+func encodeField(fv reflect.Value, c ColumnType) (interface{}, error) {
+	switch {
+	case fv.Type() == ovsSetType, fv.Type() == ovsMapType:
+		return fv.Interface(), nil
+	}
+	switch fv.Kind() {
+	case reflect.Map:
+		m := OvsMap{}
+		iter := fv.MapRange()
+		for iter.Next() {
+			m[encodeModelAtom(iter.Key(), c.Key.Type)] = encodeModelAtom(iter.Value(), c.Value.Type)
+		}
+		return m, nil
+	case reflect.Slice:
+		s := OvsSet{}
+		for i := 0; i < fv.Len(); i++ {
+			s = append(s, encodeModelAtom(fv.Index(i), c.Key.Type))
+		}
+		return s, nil
+	case reflect.Ptr:
+		if fv.IsNil() {
+			return OvsSet{}, nil
+		}
+		return encodeModelAtom(fv.Elem(), c.Key.Type), nil
+	}
+	if c.Min == 0 && fv.IsZero() {
+		// The zero value of an optional column is the empty set.
+		return OvsSet{}, nil
+	}
+	return encodeModelAtom(fv, c.Key.Type), nil
+}
+
+// encodeModelAtom converts a value into an atom of a base type.
+func encodeModelAtom(v reflect.Value, base string) interface{} {
+	switch v.Kind() {
+	case reflect.String:
+		if a, ok := v.Interface().(NamedUUID); ok {
+			return a
+		}
+		if base == "uuid" {
+			return UUID(v.String())
+		}
+		return v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Float32, reflect.Float64:
+		if base == "integer" {
+			return int64(v.Float())
+		}
+		return v.Float()
+	}
+	return v.Interface()
+}
+
+// modelOf returns the model and the struct type of the value pointed to by
+// a pointer, or of the elements of a slice pointed to by a pointer.
+func modelOf(t reflect.Type) (Model, reflect.Type, error) {
+	model, ok := reflect.New(t).Elem().Interface().(Model)
+	if !ok {
+		model, ok = reflect.New(t).Interface().(Model)
+	}
+	if !ok {
+		return nil, nil, fmt.Errorf("%s does not implement Model", t)
+	}
+	return model, t, nil
+}
+
+// modelSchema returns the fields of a model validated against the schema
+// of its database.
+func (cli *Client) modelSchema(ctx context.Context, model Model, t reflect.Type) ([]modelField, Table, error) {
+	fields, err := modelFields(t)
+	if err != nil {
+		return nil, Table{}, err
+	}
+	schema, err := cli.GetSchemaContext(ctx, model.Database())
+	if err != nil {
+		return nil, Table{}, err
+	}
+	if err := validateModel(schema, model.Table(), t, fields); err != nil {
+		return nil, Table{}, err
+	}
+	return fields, schema.Tables[model.Table()], nil
+}
+
+// Select fetches the rows of a table matching the conditions into
+// a slice of models, e.g. &[]LogicalSwitch{}. Only the columns mapped by
+// the model are fetched.
+func (cli *Client) Select(ctx context.Context, models interface{}, conditions ...Condition) error {
+	method := "select"
+	if cli == nil {
+		return fmt.Errorf("client was not initialized")
+	}
+	rv := reflect.ValueOf(models)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("'%s' operation failed: expected a pointer to a slice of models, but got %T", method, models)
+	}
+	elemType := rv.Elem().Type().Elem()
+	structType := elemType
+	if elemType.Kind() == reflect.Ptr {
+		structType = elemType.Elem()
+	}
+	model, _, err := modelOf(structType)
+	if err != nil {
+		return fmt.Errorf("'%s' operation failed: %w", method, err)
+	}
+	fields, _, err := cli.modelSchema(ctx, model, structType)
+	if err != nil {
+		return fmt.Errorf("'%s' operation failed for '%s' table: %w", method, model.Table(), err)
+	}
+	op := Operation{
+		Name:       method,
+		Table:      model.Table(),
+		Conditions: conditions,
+	}
+	for _, f := range fields {
+		op.Columns = append(op.Columns, f.column)
+	}
+	results, err := cli.transact(ctx, model.Database(), []Operation{op})
+	if err != nil {
+		return fmt.Errorf("'%s' operation failed for '%s' table: %w", method, model.Table(), err)
+	}
+	sv := reflect.MakeSlice(rv.Elem().Type(), 0, len(results[0].Rows))
+	for _, row := range results[0].Rows {
+		mv := reflect.New(structType)
+		for _, f := range fields {
+			value, exists := row[f.column]
+			if !exists {
+				continue
+			}
+			if err := decodeField(mv.Elem().Field(f.index), value); err != nil {
+				return fmt.Errorf("'%s' operation failed for '%s' table: column %s: %w", method, model.Table(), f.column, err)
+			}
+		}
+		if elemType.Kind() == reflect.Ptr {
+			sv = reflect.Append(sv, mv)
+		} else {
+			sv = reflect.Append(sv, mv.Elem())
+		}
+	}
+	rv.Elem().Set(sv)
+	return nil
+}
+
+// Insert inserts a row from a model, e.g. &LogicalSwitch{Name: "ls1"}.
+// The field mapped to "_uuid" column, if any, receives the UUID of the
+// inserted row.
+func (cli *Client) Insert(ctx context.Context, m interface{}) error {
+	method := "insert"
+	if cli == nil {
+		return fmt.Errorf("client was not initialized")
+	}
+	rv := reflect.ValueOf(m)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("'%s' operation failed: expected a pointer to a model, but got %T", method, m)
+	}
+	model, structType, err := modelOf(rv.Elem().Type())
+	if err != nil {
+		return fmt.Errorf("'%s' operation failed: %w", method, err)
+	}
+	fields, table, err := cli.modelSchema(ctx, model, structType)
+	if err != nil {
+		return fmt.Errorf("'%s' operation failed for '%s' table: %w", method, model.Table(), err)
+	}
+	row := Row{}
+	for _, f := range fields {
+		if f.column == "_uuid" || f.column == "_version" {
+			continue
+		}
+		value, err := encodeField(rv.Elem().Field(f.index), table.Columns[f.column].Type)
+		if err != nil {
+			return fmt.Errorf("'%s' operation failed for '%s' table: column %s: %w", method, model.Table(), f.column, err)
+		}
+		row[f.column] = value
+	}
+	op, err := NewInsertOperation(model.Table(), row, "")
+	if err != nil {
+		return fmt.Errorf("'%s' operation failed for '%s' table: %w", method, model.Table(), err)
+	}
+	results, err := cli.transact(ctx, model.Database(), []Operation{op})
+	if err != nil {
+		return fmt.Errorf("'%s' operation failed for '%s' table: %w", method, model.Table(), err)
+	}
+	for _, f := range fields {
+		if f.column == "_uuid" {
+			rv.Elem().Field(f.index).SetString(results[0].UUID)
+		}
+	}
+	return nil
+}
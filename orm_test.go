@@ -0,0 +1,185 @@
+// Copyright 2018 Paul Greenberg (greenpau@outlook.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovsdb
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testLogicalSwitch struct {
+	UUID        string            `ovsdb:"_uuid"`
+	Name        string            `ovsdb:"name"`
+	Ports       []string          `ovsdb:"ports"`
+	ExternalIDs map[string]string `ovsdb:"external_ids"`
+}
+
+func (testLogicalSwitch) Database() string { return "OVN_Northbound" }
+func (testLogicalSwitch) Table() string    { return "Logical_Switch" }
+
+type testLogicalSwitchPort struct {
+	UUID      UUID     `ovsdb:"_uuid"`
+	Name      string   `ovsdb:"name"`
+	Up        *bool    `ovsdb:"up"`
+	Tag       int      `ovsdb:"tag"`
+	Addresses []string `ovsdb:"addresses"`
+}
+
+func (testLogicalSwitchPort) Database() string { return "OVN_Northbound" }
+func (testLogicalSwitchPort) Table() string    { return "Logical_Switch_Port" }
+
+type testBadModel struct {
+	Name string `ovsdb:"name"`
+	Tag  string `ovsdb:"tag"`
+}
+
+func (testBadModel) Database() string { return "OVN_Northbound" }
+func (testBadModel) Table() string    { return "Logical_Switch_Port" }
+
+func TestModelSelectInsert(t *testing.T) {
+	ops := make(chan json.RawMessage, 10)
+	srv := newTestServer(t, func(s *testServer, req testRequest) {
+		switch req.Method {
+		case "get_schema":
+			s.reply(req, json.RawMessage(testNorthboundSchema))
+		case "transact":
+			ops <- req.Params[1]
+			var op map[string]interface{}
+			json.Unmarshal(req.Params[1], &op)
+			switch op["table"] {
+			case "Logical_Switch":
+				s.reply(req, []interface{}{
+					map[string]interface{}{"rows": []json.RawMessage{
+						json.RawMessage(`{"_uuid":["uuid","u1"],"name":"ls1","ports":["set",[["uuid","p1"],["uuid","p2"]]],"external_ids":["map",[["k1","v1"]]]}`),
+						json.RawMessage(`{"_uuid":["uuid","u2"],"name":"ls2","ports":["uuid","p3"],"external_ids":["map",[]]}`),
+					}},
+				})
+			case "Logical_Switch_Port":
+				s.reply(req, []interface{}{
+					map[string]interface{}{"uuid": []string{"uuid", "5b3a7c1e-2d4f-4a6b-8c9d-0e1f2a3b4c5d"}},
+				})
+			}
+		}
+	})
+	cli, err := NewClient(srv.Socket)
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	defer cli.Close()
+	ctx := context.Background()
+
+	switches := []testLogicalSwitch{}
+	if err := cli.Select(ctx, &switches, NewUUIDCondition("u1")); err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	expected := []testLogicalSwitch{
+		{UUID: "u1", Name: "ls1", Ports: []string{"p1", "p2"}, ExternalIDs: map[string]string{"k1": "v1"}},
+		{UUID: "u2", Name: "ls2", Ports: []string{"p3"}, ExternalIDs: map[string]string{}},
+	}
+	if !reflect.DeepEqual(switches, expected) {
+		t.Fatalf("FAIL: expected %v, but got %v", expected, switches)
+	}
+	op := <-ops
+	if !strings.Contains(string(op), `"columns":["_uuid","name","ports","external_ids"]`) {
+		t.Fatalf("FAIL: expected to select the columns of the model, but got %s", op)
+	}
+	t.Logf("PASS: selected the rows into models")
+
+	up := true
+	lsp := testLogicalSwitchPort{Name: "lsp1", Up: &up, Addresses: []string{"dynamic"}}
+	if err := cli.Insert(ctx, &lsp); err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	if lsp.UUID != "5b3a7c1e-2d4f-4a6b-8c9d-0e1f2a3b4c5d" {
+		t.Fatalf("FAIL: expected the UUID of the inserted row, but got %s", lsp.UUID)
+	}
+	op = <-ops
+	expectedOp := `{"op":"insert","table":"Logical_Switch_Port","row":{"addresses":["set",["dynamic"]],"name":"lsp1","tag":["set",[]],"up":true}}`
+	if string(op) != expectedOp {
+		t.Fatalf("FAIL: expected %s, but got %s", expectedOp, op)
+	}
+	t.Logf("PASS: inserted a row from a model")
+
+	if err := cli.Insert(ctx, &testBadModel{Name: "lsp2"}); err == nil {
+		t.Fatalf("FAIL: expected the model not to match the schema")
+	} else if !strings.Contains(err.Error(), "field Tag: column tag") {
+		t.Fatalf("FAIL: unexpected error: %v", err)
+	} else {
+		t.Logf("PASS: expected to fail, failed with: %v", err)
+	}
+	if err := cli.Select(ctx, &[]string{}); err == nil {
+		t.Fatalf("FAIL: expected a slice of strings not to be a slice of models")
+	}
+	select {
+	case op := <-ops:
+		t.Fatalf("FAIL: expected invalid models not to be sent, but got %s", op)
+	default:
+	}
+	t.Logf("PASS: rejected the invalid models")
+}
+
+func TestValidateModel(t *testing.T) {
+	schema := newTestSchema(t, testNorthboundSchema)
+	for i, test := range []struct {
+		table      string
+		model      interface{}
+		shouldFail bool
+	}{
+		{table: "Logical_Switch", model: testLogicalSwitch{}},
+		{table: "Logical_Switch_Port", model: testLogicalSwitchPort{}},
+		{table: "Logical_Switch_Port", model: struct {
+			Tag *int64 `ovsdb:"tag"`
+			Up  bool   `ovsdb:"up"`
+		}{}},
+		{table: "Logical_Switch", model: struct {
+			Ports OvsSet `ovsdb:"ports"`
+			IDs   OvsMap `ovsdb:"external_ids"`
+		}{}},
+		{table: "Logical_Switch_Port", model: struct {
+			Name []string `ovsdb:"name"`
+		}{}, shouldFail: true},
+		{table: "Logical_Switch", model: struct {
+			Ports string `ovsdb:"ports"`
+		}{}, shouldFail: true},
+		{table: "Logical_Switch", model: struct {
+			IDs []string `ovsdb:"external_ids"`
+		}{}, shouldFail: true},
+		{table: "Logical_Switch_Port", model: struct {
+			Name *string `ovsdb:"name"`
+		}{}, shouldFail: true},
+		{table: "Logical_Switch_Port", model: struct {
+			Missing string `ovsdb:"missing"`
+		}{}, shouldFail: true},
+		{table: "Logical_Switch_Port", model: struct {
+			UUID int `ovsdb:"_uuid"`
+		}{}, shouldFail: true},
+	} {
+		typ := reflect.TypeOf(test.model)
+		fields, err := modelFields(typ)
+		if err == nil {
+			err = validateModel(schema, test.table, typ, fields)
+		}
+		if err != nil && !test.shouldFail {
+			t.Fatalf("FAIL: Test %d: expected to pass, but failed with: %v", i, err)
+		}
+		if err == nil && test.shouldFail {
+			t.Fatalf("FAIL: Test %d: expected to fail, but passed", i)
+		}
+		t.Logf("PASS: Test %d: %v", i, err)
+	}
+}
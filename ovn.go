@@ -31,7 +31,10 @@ type OvnClient struct {
 		Vswitchd OvsDaemon
 	}
 	Timeout int
-	System  struct {
+	// TLS configures the connections to the databases with "ssl:"
+	// remotes.
+	TLS    *TLSOptions
+	System struct {
 		ID       string
 		RunDir   string
 		Hostname string
@@ -113,26 +116,23 @@ func NewOvnClient() *OvnClient {
 func (cli *OvnClient) Connect() error {
 	errMsgs := []string{}
 	if cli.Database.Vswitch.Client == nil {
-		ovs, err := NewClient(cli.Database.Vswitch.Socket.Remote, cli.Timeout)
-		cli.Database.Vswitch.Client = &ovs
+		ovs, err := NewClient(cli.Database.Vswitch.Socket.Remote, withTimeoutSeconds(cli.Timeout), WithTLS(cli.TLS))
+		cli.Database.Vswitch.Client = ovs
 		if err != nil {
-			cli.Database.Vswitch.Client.closed = true
 			errMsgs = append(errMsgs, fmt.Sprintf("failed connecting to %s via %s: %s", cli.Database.Vswitch.Name, cli.Database.Vswitch.Socket.Remote, err))
 		}
 	}
 	if cli.Database.Northbound.Client == nil {
-		nb, err := NewClient(cli.Database.Northbound.Socket.Remote, cli.Timeout)
-		cli.Database.Northbound.Client = &nb
+		nb, err := NewClient(cli.Database.Northbound.Socket.Remote, withTimeoutSeconds(cli.Timeout), WithTLS(cli.TLS))
+		cli.Database.Northbound.Client = nb
 		if err != nil {
-			cli.Database.Northbound.Client.closed = true
 			errMsgs = append(errMsgs, fmt.Sprintf("failed connecting to %s via %s: %s", cli.Database.Northbound.Name, cli.Database.Northbound.Socket.Remote, err))
 		}
 	}
 	if cli.Database.Southbound.Client == nil {
-		sb, err := NewClient(cli.Database.Southbound.Socket.Remote, cli.Timeout)
-		cli.Database.Southbound.Client = &sb
+		sb, err := NewClient(cli.Database.Southbound.Socket.Remote, withTimeoutSeconds(cli.Timeout), WithTLS(cli.TLS))
+		cli.Database.Southbound.Client = sb
 		if err != nil {
-			cli.Database.Southbound.Client.closed = true
 			errMsgs = append(errMsgs, fmt.Sprintf("failed connecting to %s via %s: %s", cli.Database.Southbound.Name, cli.Database.Southbound.Socket.Remote, err))
 		}
 	}
@@ -155,6 +155,28 @@ func (cli *OvnClient) Close() {
 	}
 }
 
+// EnableCache caches the tables read by the accessors, e.g. GetChassis,
+// and keeps the tables in sync with the databases via monitors. Once
+// enabled, the accessors read the tables locally.
+func (cli *OvnClient) EnableCache() error {
+	for _, db := range []struct {
+		database *OvsDatabase
+		tables   []string
+	}{
+		{database: &cli.Database.Vswitch, tables: []string{"Open_vSwitch"}},
+		{database: &cli.Database.Northbound, tables: []string{"ACL", "Logical_Switch", "Logical_Switch_Port"}},
+		{database: &cli.Database.Southbound, tables: []string{"Chassis", "Datapath_Binding", "Encap", "Port_Binding"}},
+	} {
+		if db.database.Client == nil {
+			return fmt.Errorf("failed enabling cache for %s: not connected", db.database.Name)
+		}
+		if _, err := db.database.Client.CacheTables(db.database.Name, db.tables...); err != nil {
+			return fmt.Errorf("failed enabling cache for %s: %s", db.database.Name, err)
+		}
+	}
+	return nil
+}
+
 func (cli *OvnClient) updateRefs() {
 	cli.Database.Vswitch.Socket.Control = fmt.Sprintf("unix:%s/ovsdb-server.%d.ctl", cli.System.RunDir, cli.Database.Vswitch.Process.ID)
 	cli.Service.Vswitchd.Socket.Control = fmt.Sprintf("unix:%s/ovs-vswitchd.%d.ctl", cli.System.RunDir, cli.Service.Vswitchd.Process.ID)
@@ -29,7 +29,10 @@ type OvsClient struct {
 		Vswitchd      OvsDaemon
 	}
 	Timeout int
-	System  struct {
+	// TLS configures the connections to the databases with "ssl:"
+	// remotes.
+	TLS    *TLSOptions
+	System struct {
 		ID       string
 		RunDir   string
 		Hostname string
@@ -84,10 +87,9 @@ func NewOvsClient() *OvsClient {
 // Connect initiates connections to OVS database.
 func (cli *OvsClient) Connect() error {
 	if cli.Database.Vswitch.Client == nil {
-		ovs, err := NewClient(cli.Database.Vswitch.Socket.Remote, cli.Timeout)
-		cli.Database.Vswitch.Client = &ovs
+		ovs, err := NewClient(cli.Database.Vswitch.Socket.Remote, withTimeoutSeconds(cli.Timeout), WithTLS(cli.TLS))
+		cli.Database.Vswitch.Client = ovs
 		if err != nil {
-			cli.Database.Vswitch.Client.closed = true
 			return fmt.Errorf("failed connecting to %s via %s: %s", cli.Database.Vswitch.Name, cli.Database.Vswitch.Socket.Remote, err)
 		}
 	}
@@ -101,6 +103,19 @@ func (cli *OvsClient) Close() {
 	}
 }
 
+// EnableCache caches the tables read by the accessors, e.g. GetDbInterfaces,
+// and keeps the tables in sync with the database via a monitor. Once
+// enabled, the accessors read the tables locally.
+func (cli *OvsClient) EnableCache() error {
+	if cli.Database.Vswitch.Client == nil {
+		return fmt.Errorf("failed enabling cache for %s: not connected", cli.Database.Vswitch.Name)
+	}
+	if _, err := cli.Database.Vswitch.Client.CacheTables(cli.Database.Vswitch.Name, "Interface", "Open_vSwitch"); err != nil {
+		return fmt.Errorf("failed enabling cache for %s: %s", cli.Database.Vswitch.Name, err)
+	}
+	return nil
+}
+
 func (cli *OvsClient) updateRefs() {
 	cli.Database.Vswitch.Socket.Control = fmt.Sprintf("unix:%s/ovsdb-server.%d.ctl", cli.System.RunDir, cli.Database.Vswitch.Process.ID)
 	cli.Service.Vswitchd.Socket.Control = fmt.Sprintf("unix:%s/ovs-vswitchd.%d.ctl", cli.System.RunDir, cli.Service.Vswitchd.Process.ID)
@@ -88,7 +88,7 @@ func (cli *OvsClient) GetOvsFlows() ([]*OvsFlow, error) {
 	db := "vswitchd-service"
 	cmd := "dpctl/dump-flows"
 	flows := []*OvsFlow{}
-	app, err := NewClient(cli.Service.Vswitchd.Socket.Control, cli.Timeout)
+	app, err := NewClient(cli.Service.Vswitchd.Socket.Control, withTimeoutSeconds(cli.Timeout))
 	if err != nil {
 		app.Close()
 		return flows, fmt.Errorf("failed '%s' from %s: %s", cmd, db, err)
@@ -188,7 +188,7 @@ func (cli *OvsClient) GetTunnels() ([]*OvsTunnel, error) {
 	db := "vswitchd-service"
 	cmd := "ofproto/list-tunnels"
 	tunnels := []*OvsTunnel{}
-	app, err := NewClient(cli.Service.Vswitchd.Socket.Control, cli.Timeout)
+	app, err := NewClient(cli.Service.Vswitchd.Socket.Control, withTimeoutSeconds(cli.Timeout))
 	if err != nil {
 		app.Close()
 		return tunnels, fmt.Errorf("failed '%s' from %s: %s", cmd, db, err)
@@ -0,0 +1,79 @@
+// Copyright 2018 Paul Greenberg (greenpau@outlook.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovsdb
+
+import (
+	"fmt"
+	"time"
+)
+
+// prober detects dead peers, similar to the inactivity probes of
+// ovsdb-server. When nothing is received from the server for the interval,
+// the client sends "echo" request. When nothing is received for another
+// interval, the connection is considered dead.
+type prober struct {
+	interval time.Duration
+	timer    *time.Timer
+	probing  bool
+}
+
+func newProber(interval time.Duration) *prober {
+	p := &prober{interval: interval}
+	if interval > 0 {
+		p.timer = time.NewTimer(interval)
+	}
+	return p
+}
+
+// expired returns the channel receiving the time when the interval
+// expires. The channel is nil when the probes are disabled.
+func (p *prober) expired() <-chan time.Time {
+	if p.timer == nil {
+		return nil
+	}
+	return p.timer.C
+}
+
+// received records the activity of the server.
+func (p *prober) received() {
+	if p.timer == nil {
+		return
+	}
+	p.probing = false
+	if !p.timer.Stop() {
+		select {
+		case <-p.timer.C:
+		default:
+		}
+	}
+	p.timer.Reset(p.interval)
+}
+
+// expire handles the expiration of the interval. It returns true when
+// a probe is to be sent, and an error when the probe went unanswered.
+func (p *prober) expire() (bool, error) {
+	if p.probing {
+		return false, fmt.Errorf("no response to inactivity probe after %s, disconnecting", p.interval)
+	}
+	p.probing = true
+	p.timer.Reset(p.interval)
+	return true, nil
+}
+
+func (p *prober) stop() {
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2018 Paul Greenberg (greenpau@outlook.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovsdb
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestInactivityProbe(t *testing.T) {
+	var silent, probes int32
+	srv := newTestServer(t, func(s *testServer, req testRequest) {
+		if req.Method != "echo" {
+			return
+		}
+		atomic.AddInt32(&probes, 1)
+		if atomic.LoadInt32(&silent) == 0 {
+			s.reply(req, req.Params)
+		}
+	})
+	events := make(chan ConnectionEvent, 10)
+	cli, err := NewClient(srv.Socket,
+		WithInactivityProbe(50*time.Millisecond),
+		WithRetryPolicy(DefaultMaxRetries, ReconnectPolicy{InitialBackoff: 10 * time.Millisecond}),
+		WithConnectionEventHandler(func(event ConnectionEvent) {
+			events <- event
+		}),
+	)
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	defer cli.Close()
+	<-events
+
+	// The server answers the probes, so the connection stays up.
+	time.Sleep(300 * time.Millisecond)
+	select {
+	case event := <-events:
+		t.Fatalf("FAIL: expected the connection to stay up, but got %s state: %v", event.State, event.Err)
+	default:
+	}
+	if atomic.LoadInt32(&probes) == 0 {
+		t.Fatalf("FAIL: expected the client to send inactivity probes")
+	}
+	t.Logf("PASS: answered probes keep the connection up")
+
+	// The server stops answering, e.g. the peer is dead.
+	atomic.StoreInt32(&silent, 1)
+	select {
+	case event := <-events:
+		if event.State != StateDisconnected || event.Err == nil || !strings.Contains(event.Err.Error(), "inactivity probe") {
+			t.Fatalf("FAIL: expected disconnection due to inactivity probe, but got %s state: %v", event.State, event.Err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("FAIL: expected disconnection, but timed out")
+	}
+	for _, expected := range []ConnectionState{StateReconnecting, StateConnected} {
+		select {
+		case event := <-events:
+			if event.State != expected {
+				t.Fatalf("FAIL: expected %s state, but got %s", expected, event.State)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("FAIL: expected %s state, but timed out", expected)
+		}
+	}
+	t.Logf("PASS: reconnected after unanswered probe")
+}
@@ -0,0 +1,1196 @@
+// Copyright 2018 Paul Greenberg (greenpau@outlook.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovsdb
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/scanner"
+	"unicode"
+)
+
+// Query is a query of Transact, see ParseQuery. The server executes the
+// "select" operations of the tables of the query, and the client joins,
+// orders, limits, and projects the selected rows.
+type Query struct {
+	// Operation is the operation of the table of FROM clause, or the
+	// operation of INSERT, UPDATE, DELETE, and MUTATE statements.
+	Operation Operation
+	// Alias is the alias of the table of the operation, if any.
+	Alias string
+	// Projections are the selected columns, or nil for all the columns.
+	Projections []Projection
+	Joins       []Join
+	OrderBy     []Order
+	// Limit is the maximum number of the rows, or -1 for no limit.
+	Limit  int
+	Offset int
+}
+
+// ColumnRef is a column of a query, qualified by the name or the alias of
+// its table.
+type ColumnRef struct {
+	Table  string
+	Column string
+}
+
+// Projection is a selected column of a query. The Alias, if not empty,
+// is the name of the column in the result.
+type Projection struct {
+	ColumnRef
+	Alias string
+}
+
+// Join joins the rows of a table to the rows of the preceding tables of a
+// query, such that the Ref column of one of them refers to the "_uuid"
+// column of the Target one, e.g. Logical_Switch.ports to
+// Logical_Switch_Port.
+type Join struct {
+	// Operation selects the rows of the joined table.
+	Operation Operation
+	Alias     string
+	Ref       ColumnRef
+	Target    string
+}
+
+// Order orders the rows of a query by a column.
+type Order struct {
+	ColumnRef
+	Descending bool
+}
+
+// queryParser parses the queries of Operation.Parse. The parser scans one
+// token ahead: tok, text, and pos describe the token being parsed.
+type queryParser struct {
+	s    scanner.Scanner
+	tok  rune
+	text string
+	pos  scanner.Position
+	err  error
+	// clause is the first clause executed by the client, e.g. "LIMIT",
+	// at clausePos.
+	clause    string
+	clausePos scanner.Position
+}
+
+func newQueryParser(query string) *queryParser {
+	p := &queryParser{}
+	p.s.Init(strings.NewReader(query))
+	// The numbers are scanned as barewords, see bareword, such that e.g.
+	// versions and MAC addresses are not mistaken for malformed numbers.
+	p.s.Mode = scanner.ScanIdents | scanner.ScanStrings | scanner.ScanRawStrings
+	p.s.Error = func(s *scanner.Scanner, msg string) {
+		if p.err == nil {
+			p.err = fmt.Errorf("parser error: %s: %s", position(s.Position), msg)
+		}
+	}
+	p.next()
+	return p
+}
+
+// next scans the next token. The operators ending with "=", e.g. "==" or
+// "+=", are scanned as a single token.
+func (p *queryParser) next() {
+	p.tok = p.s.Scan()
+	p.text = p.s.TokenText()
+	p.pos = p.s.Position
+	if strings.ContainsRune("=!<>+-*/%", p.tok) && p.s.Peek() == '=' {
+		p.s.Next()
+		p.text += "="
+	}
+}
+
+// errorf returns the error at the position of the current token.
+func (p *queryParser) errorf(format string, args ...interface{}) error {
+	return errorAt(p.pos, format, args...)
+}
+
+// errorAt returns the error at the position.
+func errorAt(pos scanner.Position, format string, args ...interface{}) error {
+	return fmt.Errorf("parser error: %s: %s", position(pos), fmt.Sprintf(format, args...))
+}
+
+// position returns the line and the column of the position, e.g. "1:10".
+func position(pos scanner.Position) string {
+	return fmt.Sprintf("%d:%d", pos.Line, pos.Column)
+}
+
+// unexpected returns the error reporting the current token in place of the
+// expected one.
+func (p *queryParser) unexpected(expected string) error {
+	if p.tok == scanner.EOF {
+		return p.errorf("expected %s, found end of query", expected)
+	}
+	return p.errorf("expected %s, found %q", expected, p.text)
+}
+
+// isKeyword returns true when the current token is the keyword. The
+// keywords are case-insensitive.
+func (p *queryParser) isKeyword(keyword string) bool {
+	return p.tok == scanner.Ident && strings.EqualFold(p.text, keyword)
+}
+
+func (p *queryParser) expectKeyword(keyword string) error {
+	if !p.isKeyword(keyword) {
+		return p.unexpected(keyword)
+	}
+	p.next()
+	return nil
+}
+
+func (p *queryParser) expect(token string) error {
+	if p.text != token || p.tok == scanner.String || p.tok == scanner.RawString {
+		return p.unexpected(strconv.Quote(token))
+	}
+	p.next()
+	return nil
+}
+
+// parse parses the query.
+func (p *queryParser) parse(q *Query) error {
+	err := p.parseStatement(q)
+	if err == nil && p.tok != scanner.EOF {
+		err = p.errorf("unexpected %q after %s statement", p.text, strings.ToUpper(q.Operation.Name))
+	}
+	if p.err != nil {
+		// The scanner errors, e.g. unterminated strings, cause the
+		// parser errors.
+		return p.err
+	}
+	return err
+}
+
+func (p *queryParser) parseStatement(q *Query) error {
+	switch {
+	case p.isKeyword("SELECT"):
+		return p.parseSelect(q)
+	case p.isKeyword("INSERT"):
+		return p.parseInsert(&q.Operation)
+	case p.isKeyword("UPDATE"):
+		return p.parseUpdate(&q.Operation)
+	case p.isKeyword("DELETE"):
+		return p.parseDelete(&q.Operation)
+	case p.isKeyword("MUTATE"):
+		return p.parseMutate(&q.Operation)
+	}
+	return p.unexpected("SELECT, INSERT, UPDATE, DELETE, or MUTATE")
+}
+
+// clientClause records the first clause executed by the client.
+func (p *queryParser) clientClause(clause string) {
+	if p.clause == "" {
+		p.clause = clause
+		p.clausePos = p.pos
+	}
+}
+
+// parseSelect parses "SELECT projections FROM table [alias] [JOIN ...]
+// [WHERE conditions] [ORDER BY ...] [LIMIT count] [OFFSET count]".
+func (p *queryParser) parseSelect(q *Query) error {
+	q.Operation.Name = "select"
+	p.next()
+	var positions []scanner.Position
+	if p.tok == '*' {
+		p.next()
+	} else {
+		for {
+			positions = append(positions, p.pos)
+			ref, err := p.parseColumnRef()
+			if err != nil {
+				return err
+			}
+			projection := Projection{ColumnRef: ref}
+			if p.isKeyword("AS") {
+				p.clientClause("AS")
+				p.next()
+				if projection.Alias, err = p.parseIdent("alias"); err != nil {
+					return err
+				}
+			}
+			q.Projections = append(q.Projections, projection)
+			if p.tok != ',' {
+				break
+			}
+			p.next()
+		}
+	}
+	if err := p.expectKeyword("FROM"); err != nil {
+		return err
+	}
+	table, alias, err := p.parseTable()
+	if err != nil {
+		return err
+	}
+	q.Operation.Table, q.Alias = table, alias
+	for p.isKeyword("JOIN") || p.isKeyword("INNER") {
+		if err := p.parseJoin(q); err != nil {
+			return err
+		}
+	}
+	for i, projection := range q.Projections {
+		ref, err := q.qualify(projection.ColumnRef, positions[i])
+		if err != nil {
+			return err
+		}
+		q.Projections[i].ColumnRef = ref
+	}
+	err = p.parseWhere(func(ref ColumnRef, pos scanner.Position) (*Operation, string, error) {
+		ref, err := q.qualify(ref, pos)
+		if err != nil {
+			return nil, "", err
+		}
+		if i := q.tableIndex(ref.Table); i > 0 {
+			return &q.Joins[i-1].Operation, ref.Column, nil
+		}
+		return &q.Operation, ref.Column, nil
+	})
+	if err != nil {
+		return err
+	}
+	if p.isKeyword("ORDER") {
+		if err := p.parseOrderBy(q); err != nil {
+			return err
+		}
+	}
+	if p.isKeyword("LIMIT") {
+		p.clientClause("LIMIT")
+		p.next()
+		if q.Limit, err = p.parseCount(); err != nil {
+			return err
+		}
+	}
+	if p.isKeyword("OFFSET") {
+		p.clientClause("OFFSET")
+		p.next()
+		if q.Offset, err = p.parseCount(); err != nil {
+			return err
+		}
+	}
+	q.selectColumns()
+	return nil
+}
+
+// parseTable parses "table [[AS] alias]".
+func (p *queryParser) parseTable() (string, string, error) {
+	table, err := p.parseIdent("table")
+	if err != nil {
+		return "", "", err
+	}
+	if p.isKeyword("AS") {
+		p.next()
+		alias, err := p.parseIdent("alias")
+		return table, alias, err
+	}
+	if p.tok == scanner.RawString || p.tok == scanner.Ident && !queryKeywords[strings.ToUpper(p.text)] {
+		alias, err := p.parseIdent("alias")
+		return table, alias, err
+	}
+	return table, "", nil
+}
+
+// queryKeywords are the keywords following the tables of SELECT statements.
+var queryKeywords = map[string]bool{
+	"AS":     true,
+	"INNER":  true,
+	"JOIN":   true,
+	"ON":     true,
+	"WHERE":  true,
+	"ORDER":  true,
+	"LIMIT":  true,
+	"OFFSET": true,
+}
+
+// parseJoin parses "[INNER] JOIN table [alias] ON column = column", where
+// one of the columns is "_uuid" column.
+func (p *queryParser) parseJoin(q *Query) error {
+	p.clientClause("JOIN")
+	if p.isKeyword("INNER") {
+		p.next()
+	}
+	if err := p.expectKeyword("JOIN"); err != nil {
+		return err
+	}
+	pos := p.pos
+	table, alias, err := p.parseTable()
+	if err != nil {
+		return err
+	}
+	j := Join{Operation: Operation{Name: "select", Table: table}, Alias: alias}
+	name := j.name()
+	for _, existing := range q.tables() {
+		if existing == name {
+			return errorAt(pos, "duplicate table %s, expected an alias", name)
+		}
+	}
+	q.Joins = append(q.Joins, j)
+	if err := p.expectKeyword("ON"); err != nil {
+		return err
+	}
+	pos = p.pos
+	left, err := p.parseColumnRef()
+	if err != nil {
+		return err
+	}
+	if p.text != "=" && p.text != "==" || p.tok == scanner.String {
+		return p.unexpected(`"="`)
+	}
+	p.next()
+	right, err := p.parseColumnRef()
+	if err != nil {
+		return err
+	}
+	if left, err = q.qualify(left, pos); err != nil {
+		return err
+	}
+	if right, err = q.qualify(right, pos); err != nil {
+		return err
+	}
+	if left.Column == "_uuid" {
+		left, right = right, left
+	}
+	if right.Column != "_uuid" || left.Column == "_uuid" {
+		return errorAt(pos, "expected a reference column and _uuid column")
+	}
+	if left.Table != name && right.Table != name || left.Table == right.Table {
+		return errorAt(pos, "expected the columns of %s and of a preceding table", name)
+	}
+	q.Joins[len(q.Joins)-1].Ref = left
+	q.Joins[len(q.Joins)-1].Target = right.Table
+	return nil
+}
+
+// parseOrderBy parses "ORDER BY column [ASC | DESC], ...". The columns are
+// either the columns of the tables or the aliases of the projections.
+func (p *queryParser) parseOrderBy(q *Query) error {
+	p.clientClause("ORDER BY")
+	p.next()
+	if err := p.expectKeyword("BY"); err != nil {
+		return err
+	}
+	for {
+		pos := p.pos
+		ref, err := p.parseColumnRef()
+		if err != nil {
+			return err
+		}
+		order := Order{}
+		for _, projection := range q.Projections {
+			if ref.Table == "" && projection.Alias != "" && projection.Alias == ref.Column {
+				order.ColumnRef = projection.ColumnRef
+			}
+		}
+		if order.Column == "" {
+			if order.ColumnRef, err = q.qualify(ref, pos); err != nil {
+				return err
+			}
+		}
+		switch {
+		case p.isKeyword("ASC"):
+			p.next()
+		case p.isKeyword("DESC"):
+			order.Descending = true
+			p.next()
+		}
+		q.OrderBy = append(q.OrderBy, order)
+		if p.tok != ',' {
+			return nil
+		}
+		p.next()
+	}
+}
+
+// parseCount parses the non-negative integer of LIMIT and OFFSET clauses.
+func (p *queryParser) parseCount() (int, error) {
+	pos := p.pos
+	if p.tok < 0 || !unicode.IsDigit(p.tok) {
+		return 0, p.unexpected("non-negative integer")
+	}
+	text := p.bareword()
+	n, err := strconv.Atoi(text)
+	if err != nil {
+		return 0, errorAt(pos, "expected non-negative integer, found %q", text)
+	}
+	return n, nil
+}
+
+// parseColumnRef parses "[table.]column".
+func (p *queryParser) parseColumnRef() (ColumnRef, error) {
+	column, err := p.parseIdent("column")
+	if err != nil {
+		return ColumnRef{}, err
+	}
+	if p.tok != '.' {
+		return ColumnRef{Column: column}, nil
+	}
+	p.next()
+	ref := ColumnRef{Table: column}
+	if ref.Column, err = p.parseIdent("column"); err != nil {
+		return ColumnRef{}, err
+	}
+	return ref, nil
+}
+
+// parseInsert parses "INSERT INTO table (columns) VALUES (values)".
+func (p *queryParser) parseInsert(t *Operation) error {
+	t.Name = "insert"
+	p.next()
+	if err := p.expectKeyword("INTO"); err != nil {
+		return err
+	}
+	table, err := p.parseIdent("table")
+	if err != nil {
+		return err
+	}
+	t.Table = table
+	if err := p.expect("("); err != nil {
+		return err
+	}
+	var columns []string
+	for {
+		column, err := p.parseIdent("column")
+		if err != nil {
+			return err
+		}
+		columns = append(columns, column)
+		if p.tok != ',' {
+			break
+		}
+		p.next()
+	}
+	if err := p.expect(")"); err != nil {
+		return err
+	}
+	if err := p.expectKeyword("VALUES"); err != nil {
+		return err
+	}
+	if err := p.expect("("); err != nil {
+		return err
+	}
+	t.Row = Row{}
+	for i, column := range columns {
+		if i > 0 {
+			if err := p.expect(","); err != nil {
+				return err
+			}
+		}
+		value, _, err := p.parseValue()
+		if err != nil {
+			return err
+		}
+		t.Row[column] = value
+	}
+	if p.tok == ',' {
+		return p.errorf("more values than %d columns", len(columns))
+	}
+	return p.expect(")")
+}
+
+// parseUpdate parses "UPDATE table SET column=value, ... [WHERE conditions]".
+func (p *queryParser) parseUpdate(t *Operation) error {
+	t.Name = "update"
+	p.next()
+	table, err := p.parseIdent("table")
+	if err != nil {
+		return err
+	}
+	t.Table = table
+	if err := p.expectKeyword("SET"); err != nil {
+		return err
+	}
+	t.Row = Row{}
+	for {
+		column, err := p.parseIdent("column")
+		if err != nil {
+			return err
+		}
+		if err := p.expect("="); err != nil {
+			return err
+		}
+		value, _, err := p.parseValue()
+		if err != nil {
+			return err
+		}
+		t.Row[column] = value
+		if p.tok != ',' {
+			break
+		}
+		p.next()
+	}
+	return p.parseWhere(whereOperation(t))
+}
+
+// parseDelete parses "DELETE FROM table [WHERE conditions]".
+func (p *queryParser) parseDelete(t *Operation) error {
+	t.Name = "delete"
+	p.next()
+	if err := p.expectKeyword("FROM"); err != nil {
+		return err
+	}
+	table, err := p.parseIdent("table")
+	if err != nil {
+		return err
+	}
+	t.Table = table
+	return p.parseWhere(whereOperation(t))
+}
+
+// parseMutate parses "MUTATE table SET column mutator value, ...
+// [WHERE conditions]".
+func (p *queryParser) parseMutate(t *Operation) error {
+	t.Name = "mutate"
+	p.next()
+	table, err := p.parseIdent("table")
+	if err != nil {
+		return err
+	}
+	t.Table = table
+	if err := p.expectKeyword("SET"); err != nil {
+		return err
+	}
+	for {
+		column, err := p.parseIdent("column")
+		if err != nil {
+			return err
+		}
+		mutator := p.text
+		if p.tok == scanner.Ident {
+			mutator = strings.ToLower(mutator)
+		}
+		if _, exists := mutators[mutator]; !exists || p.tok == scanner.String || p.tok == scanner.RawString {
+			return p.unexpected("mutator")
+		}
+		p.next()
+		value, _, err := p.parseValue()
+		if err != nil {
+			return err
+		}
+		t.Mutations = append(t.Mutations, Mutation{Column: column, Mutator: mutator, Value: value})
+		if p.tok != ',' {
+			break
+		}
+		p.next()
+	}
+	return p.parseWhere(whereOperation(t))
+}
+
+// parseWhere parses the optional "WHERE condition, ..." clause. The
+// conditions are separated by commas or AND keywords. The operation
+// function returns the operation of the table of the column of a condition,
+// and the name of the column.
+func (p *queryParser) parseWhere(operation func(ref ColumnRef, pos scanner.Position) (*Operation, string, error)) error {
+	if !p.isKeyword("WHERE") {
+		return nil
+	}
+	p.next()
+	for {
+		pos := p.pos
+		ref, c, err := p.parseCondition()
+		if err != nil {
+			return err
+		}
+		t, column, err := operation(ref, pos)
+		if err != nil {
+			return err
+		}
+		c.Column = column
+		t.Conditions = append(t.Conditions, c)
+		if p.tok != ',' && !p.isKeyword("AND") {
+			return nil
+		}
+		p.next()
+	}
+}
+
+// whereOperation returns the operation function of parseWhere for the
+// statements of a single table.
+func whereOperation(t *Operation) func(ColumnRef, scanner.Position) (*Operation, string, error) {
+	return func(ref ColumnRef, pos scanner.Position) (*Operation, string, error) {
+		if ref.Table != "" && ref.Table != t.Table {
+			return nil, "", errorAt(pos, "unknown table %s", ref.Table)
+		}
+		return t, ref.Column, nil
+	}
+}
+
+// parseCondition parses "column function value".
+func (p *queryParser) parseCondition() (ColumnRef, Condition, error) {
+	c := Condition{}
+	ref, err := p.parseColumnRef()
+	if err != nil {
+		return ref, c, err
+	}
+	function := p.text
+	if p.tok == scanner.Ident {
+		function = strings.ToLower(function)
+	}
+	if !conditionFunctions[function] || p.tok == scanner.String || p.tok == scanner.RawString {
+		return ref, c, p.unexpected("condition function")
+	}
+	c.Function = function
+	p.next()
+	value, quoted, err := p.parseValue()
+	if err != nil {
+		return ref, c, err
+	}
+	c.Value = value
+	if quoted {
+		c.Type = "string"
+	}
+	return ref, c, nil
+}
+
+// parseIdent parses an identifier, e.g. a table or a column. The
+// identifiers clashing with the keywords are quoted with backquotes.
+func (p *queryParser) parseIdent(what string) (string, error) {
+	switch p.tok {
+	case scanner.Ident:
+		ident := p.text
+		p.next()
+		return ident, nil
+	case scanner.RawString:
+		ident := strings.Trim(p.text, "`")
+		if ident == "" {
+			return "", p.errorf("empty %s name", what)
+		}
+		p.next()
+		return ident, nil
+	}
+	return "", p.unexpected(what)
+}
+
+// parseValue parses a value: an atom, a set, e.g. [a, b], or a map, e.g.
+// {key=value, ...}, like the values of ovs-vsctl(8). The second return
+// value is true for the quoted strings.
+func (p *queryParser) parseValue() (interface{}, bool, error) {
+	switch p.tok {
+	case '[':
+		p.next()
+		set := OvsSet{}
+		for p.tok != ']' {
+			if len(set) > 0 {
+				if err := p.expect(","); err != nil {
+					return nil, false, err
+				}
+			}
+			atom, _, err := p.parseAtom()
+			if err != nil {
+				return nil, false, err
+			}
+			set = append(set, atom)
+		}
+		p.next()
+		return set, false, nil
+	case '{':
+		p.next()
+		m := OvsMap{}
+		for p.tok != '}' {
+			if len(m) > 0 {
+				if err := p.expect(","); err != nil {
+					return nil, false, err
+				}
+			}
+			key, _, err := p.parseAtom()
+			if err != nil {
+				return nil, false, err
+			}
+			if err := p.expect("="); err != nil {
+				return nil, false, err
+			}
+			value, _, err := p.parseAtom()
+			if err != nil {
+				return nil, false, err
+			}
+			m[key] = value
+		}
+		p.next()
+		return m, false, nil
+	}
+	return p.parseAtom()
+}
+
+// parseAtom parses a quoted string, either "..." with the escapes of Go or
+// '...' with doubled single quotes, or a bareword.
+func (p *queryParser) parseAtom() (interface{}, bool, error) {
+	switch {
+	case p.tok == scanner.String:
+		s, err := strconv.Unquote(p.text)
+		if err != nil {
+			return nil, false, p.errorf("invalid string %s", p.text)
+		}
+		p.next()
+		return s, true, nil
+	case p.tok == '\'':
+		s, err := p.singleQuoted()
+		if err != nil {
+			return nil, false, err
+		}
+		return s, true, nil
+	case p.tok == scanner.Ident, p.tok >= 0 && unicode.IsDigit(p.tok), p.tok == '-', p.tok == '+', p.tok == '.', p.tok == ':':
+		return Bareword(p.bareword()), false, nil
+	}
+	return nil, false, p.unexpected("value")
+}
+
+// singleQuoted reads the string quoted with single quotes, the current
+// token being the opening quote.
+func (p *queryParser) singleQuoted() (string, error) {
+	pos := p.pos
+	var b strings.Builder
+	for {
+		ch := p.s.Next()
+		switch ch {
+		case scanner.EOF:
+			return "", errorAt(pos, "literal not terminated")
+		case '\'':
+			if p.s.Peek() != '\'' {
+				p.next()
+				return b.String(), nil
+			}
+			p.s.Next()
+		}
+		b.WriteRune(ch)
+	}
+}
+
+// bareword reads the unquoted value starting at the current token, up to
+// a space or a delimiter, e.g. 7.3.0, br-int, or 00:00:00:00:00:01.
+func (p *queryParser) bareword() string {
+	var b strings.Builder
+	b.WriteString(p.text)
+	for {
+		ch := p.s.Peek()
+		if ch == scanner.EOF || unicode.IsSpace(ch) || strings.ContainsRune(",()[]{}=", ch) {
+			break
+		}
+		b.WriteRune(p.s.Next())
+	}
+	p.next()
+	return b.String()
+}
+
+// barewordValue converts the text of a Bareword into a boolean, an
+// integer, or a real, when possible. The other values are strings.
+func barewordValue(s string) interface{} {
+	switch s {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if s == "" || !strings.ContainsRune("0123456789+-.", rune(s[0])) {
+		return s
+	}
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+	return s
+}
+
+// ParseQuery parses a query, see Operation.Parse. The SELECT statements
+// support the following clauses executed by the client too:
+//
+//	SELECT * | column [AS alias], ... FROM table [alias]
+//	    [[INNER] JOIN table [alias] ON column = column ...]
+//	    [WHERE condition, ...]
+//	    [ORDER BY column [ASC | DESC], ...]
+//	    [LIMIT count] [OFFSET count]
+//
+// The columns are qualified by the names or the aliases of their tables,
+// e.g. ls.name, and default to the table of FROM clause. The JOIN clause
+// joins the rows referring to each other: one of the columns is the
+// reference column of a table, i.e. a set or a map of the UUIDs, and the
+// other one is "_uuid" column of another table, e.g.
+//
+//	SELECT ls.name, lsp.name AS port FROM Logical_Switch ls
+//	    JOIN Logical_Switch_Port lsp ON ls.ports = lsp._uuid
+//	    ORDER BY port LIMIT 10
+func ParseQuery(s string) (Query, error) {
+	q := Query{Operation: Operation{Conditions: []Condition{}}, Limit: -1}
+	if err := newQueryParser(s).parse(&q); err != nil {
+		return q, err
+	}
+	if err := q.Operation.Validate(); err != nil {
+		return q, err
+	}
+	for _, j := range q.Joins {
+		if err := j.Operation.Validate(); err != nil {
+			return q, err
+		}
+	}
+	return q, nil
+}
+
+// isClientSide returns true when the client post-processes the selected
+// rows.
+func (q *Query) isClientSide() bool {
+	if len(q.Joins) > 0 || len(q.OrderBy) > 0 || q.Limit >= 0 || q.Offset > 0 {
+		return true
+	}
+	for _, projection := range q.Projections {
+		if projection.Alias != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// name returns the name of the joined table in the query.
+func (j *Join) name() string {
+	if j.Alias != "" {
+		return j.Alias
+	}
+	return j.Operation.Table
+}
+
+// tables returns the names of the tables of the query, i.e. the aliases
+// or the names of the table of FROM clause and of the joined tables.
+func (q *Query) tables() []string {
+	name := q.Alias
+	if name == "" {
+		name = q.Operation.Table
+	}
+	names := []string{name}
+	for i := range q.Joins {
+		names = append(names, q.Joins[i].name())
+	}
+	return names
+}
+
+// tableIndex returns the index of the table in the tables of the query, or
+// -1 when the query has no such table.
+func (q *Query) tableIndex(name string) int {
+	for i, table := range q.tables() {
+		if table == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// qualify qualifies the column by the table of FROM clause, unless the
+// column is qualified already.
+func (q *Query) qualify(ref ColumnRef, pos scanner.Position) (ColumnRef, error) {
+	if ref.Table == "" {
+		ref.Table = q.tables()[0]
+		return ref, nil
+	}
+	if q.tableIndex(ref.Table) < 0 {
+		return ref, errorAt(pos, "unknown table %s", ref.Table)
+	}
+	return ref, nil
+}
+
+// operation returns the "select" operation of the i-th table of the query.
+func (q *Query) operation(i int) *Operation {
+	if i == 0 {
+		return &q.Operation
+	}
+	return &q.Joins[i-1].Operation
+}
+
+// selectColumns sets the columns of the "select" operations to the columns
+// required by the projections, the orders, and the joins. With no
+// projections, the operations select all the columns.
+func (q *Query) selectColumns() {
+	if q.Projections == nil {
+		return
+	}
+	add := func(ref ColumnRef) {
+		op := q.operation(q.tableIndex(ref.Table))
+		for _, column := range op.Columns {
+			if column == ref.Column {
+				return
+			}
+		}
+		op.Columns = append(op.Columns, ref.Column)
+	}
+	for _, projection := range q.Projections {
+		add(projection.ColumnRef)
+	}
+	for _, order := range q.OrderBy {
+		add(order.ColumnRef)
+	}
+	for _, j := range q.Joins {
+		add(j.Ref)
+		add(ColumnRef{Table: j.Target, Column: "_uuid"})
+	}
+}
+
+// executeQuery selects the rows of the tables of the query, either from
+// the cache or in a single transaction, and joins, orders, limits, and
+// projects them.
+func (c *Client) executeQuery(ctx context.Context, db string, schema Schema, q Query) (Result, error) {
+	ops := []Operation{q.Operation}
+	for _, j := range q.Joins {
+		ops = append(ops, j.Operation)
+	}
+	ops = schema.resolveOperations(ops)
+	rows := make([][]Row, len(ops))
+	var pending []Operation
+	var pendingIndexes []int
+	tc := c.Cache(db)
+	for i, op := range ops {
+		if tc != nil {
+			if r, ok := tc.Select(op.Table, op.Columns, op.Conditions); ok {
+				rows[i] = r
+				continue
+			}
+		}
+		pending = append(pending, op)
+		pendingIndexes = append(pendingIndexes, i)
+	}
+	if len(pending) > 0 {
+		results, err := c.transact(ctx, db, pending)
+		if err != nil {
+			return Result{}, err
+		}
+		for k, i := range pendingIndexes {
+			rows[i] = results[k].Rows
+		}
+	}
+	columns, err := q.columnTypes(schema)
+	if err != nil {
+		return Result{}, err
+	}
+	tuples := q.join(rows)
+	q.order(tuples)
+	if q.Offset >= len(tuples) {
+		tuples = nil
+	} else {
+		tuples = tuples[q.Offset:]
+	}
+	if q.Limit >= 0 && q.Limit < len(tuples) {
+		tuples = tuples[:q.Limit]
+	}
+	return Result{Rows: q.project(tuples), Database: db, Table: q.Operation.Table, Columns: columns}, nil
+}
+
+// join returns the tuples of the joined rows, i.e. the rows of the tables
+// of the query in the order of the tables.
+func (q *Query) join(rows [][]Row) [][]Row {
+	tuples := [][]Row{}
+	for _, row := range rows[0] {
+		tuples = append(tuples, []Row{row})
+	}
+	for i, j := range q.Joins {
+		joined := [][]Row{}
+		ref, target := q.tableIndex(j.Ref.Table), q.tableIndex(j.Target)
+		if ref == i+1 {
+			// The joined rows refer to the rows of a preceding table.
+			referring := make(map[string][]Row)
+			for _, row := range rows[i+1] {
+				for _, id := range datumUUIDs(row[j.Ref.Column], nil, "") {
+					referring[id] = append(referring[id], row)
+				}
+			}
+			for _, tuple := range tuples {
+				for _, id := range datumUUIDs(tuple[target]["_uuid"], nil, "") {
+					for _, row := range referring[id] {
+						joined = append(joined, append(append([]Row{}, tuple...), row))
+					}
+				}
+			}
+		} else {
+			// The rows of a preceding table refer to the joined rows.
+			referred := make(map[string]Row)
+			for _, row := range rows[i+1] {
+				for _, id := range datumUUIDs(row["_uuid"], nil, "") {
+					referred[id] = row
+				}
+			}
+			for _, tuple := range tuples {
+				for _, id := range datumUUIDs(tuple[ref][j.Ref.Column], nil, "") {
+					if row, exists := referred[id]; exists {
+						joined = append(joined, append(append([]Row{}, tuple...), row))
+					}
+				}
+			}
+		}
+		tuples = joined
+	}
+	return tuples
+}
+
+// order sorts the tuples by the columns of ORDER BY clause.
+func (q *Query) order(tuples [][]Row) {
+	if len(q.OrderBy) == 0 {
+		return
+	}
+	indexes := make([]int, len(q.OrderBy))
+	for i, order := range q.OrderBy {
+		indexes[i] = q.tableIndex(order.Table)
+	}
+	sort.SliceStable(tuples, func(a, b int) bool {
+		for i, order := range q.OrderBy {
+			c := compareDatum(tuples[a][indexes[i]][order.Column], tuples[b][indexes[i]][order.Column])
+			if c == 0 {
+				continue
+			}
+			if order.Descending {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+}
+
+// project returns the rows of the result. The columns of the joined
+// queries are qualified by the names of their tables, e.g. "lsp.name",
+// unless the projections have aliases.
+func (q *Query) project(tuples [][]Row) []Row {
+	tables := q.tables()
+	rows := []Row{}
+	for _, tuple := range tuples {
+		if q.Projections == nil && len(q.Joins) == 0 {
+			rows = append(rows, tuple[0])
+			continue
+		}
+		row := make(Row)
+		if q.Projections == nil {
+			for i, r := range tuple {
+				for column, value := range r {
+					row[tables[i]+"."+column] = value
+				}
+			}
+			rows = append(rows, row)
+			continue
+		}
+		for _, projection := range q.Projections {
+			if value, exists := tuple[q.tableIndex(projection.Table)][projection.Column]; exists {
+				row[q.resultColumn(projection)] = value
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// resultColumn returns the name of the projected column in the result.
+func (q *Query) resultColumn(projection Projection) string {
+	switch {
+	case projection.Alias != "":
+		return projection.Alias
+	case len(q.Joins) > 0:
+		return projection.Table + "." + projection.Column
+	}
+	return projection.Column
+}
+
+// columnTypes returns the types of the columns of the result, see
+// Schema.GetColumnType.
+func (q *Query) columnTypes(schema Schema) (map[string]string, error) {
+	tables := q.tables()
+	if q.Projections == nil {
+		if len(q.Joins) == 0 {
+			return schema.GetColumnsTypes(q.Operation.Table)
+		}
+		columns := make(map[string]string)
+		for i, name := range tables {
+			types, err := schema.GetColumnsTypes(q.operation(i).Table)
+			if err != nil {
+				return nil, err
+			}
+			for column, columnType := range types {
+				columns[name+"."+column] = columnType
+			}
+		}
+		return columns, nil
+	}
+	columns := make(map[string]string)
+	for _, projection := range q.Projections {
+		columnType, err := schema.GetColumnType(q.operation(q.tableIndex(projection.Table)).Table, projection.Column)
+		if err != nil {
+			return nil, err
+		}
+		columns[q.resultColumn(projection)] = columnType
+	}
+	return columns, nil
+}
+
+// compareDatum compares the values of a column. The missing values and
+// the empty sets precede the booleans, followed by the integers and the
+// reals, the strings and the UUIDs, and the other sets and the maps.
+func compareDatum(a, b interface{}) int {
+	ra, va := datumRank(a)
+	rb, vb := datumRank(b)
+	if ra != rb {
+		return ra - rb
+	}
+	switch ra {
+	case 1:
+		x, y := va.(bool), vb.(bool)
+		switch {
+		case x == y:
+			return 0
+		case !x:
+			return -1
+		}
+		return 1
+	case 2:
+		x, y := va.(float64), vb.(float64)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	case 3:
+		return strings.Compare(va.(string), vb.(string))
+	case 4:
+		return strings.Compare(fmt.Sprint(va), fmt.Sprint(vb))
+	}
+	return 0
+}
+
+// datumRank returns the rank of the value in the order of compareDatum
+// and the value to compare within the rank.
+func datumRank(value interface{}) (int, interface{}) {
+	if value == nil {
+		return 0, nil
+	}
+	datum, err := DecodeDatum(value)
+	if err != nil {
+		return 4, value
+	}
+	if s, ok := datum.(OvsSet); ok {
+		switch len(s) {
+		case 0:
+			return 0, nil
+		case 1:
+			datum = s[0]
+		}
+	}
+	switch x := datum.(type) {
+	case bool:
+		return 1, x
+	case int64:
+		return 2, float64(x)
+	case float64:
+		return 2, x
+	case string:
+		return 3, x
+	case UUID:
+		return 3, string(x)
+	}
+	return 4, datum
+}
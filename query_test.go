@@ -0,0 +1,362 @@
+// Copyright 2018 Paul Greenberg (greenpau@outlook.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovsdb
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	for i, test := range []struct {
+		query    string
+		expected string
+		err      string
+	}{
+		{
+			query:    "SELECT * FROM Open_vSwitch",
+			expected: `{"op":"select","table":"Open_vSwitch","where":[]}`,
+		},
+		{
+			query:    "select db_version, ovs_version from Open_vSwitch where db_version==7.3.0",
+			expected: `{"op":"select","table":"Open_vSwitch","where":[["db_version","==","7.3.0"]],"columns":["db_version","ovs_version"]}`,
+		},
+		{
+			query:    `SELECT _uuid FROM Logical_Switch_Port WHERE name=='lsp''1' AND tag>=100, up!=true, addresses includes [router, "00:00:00:00:00:01 10.0.0.1"]`,
+			expected: `{"op":"select","table":"Logical_Switch_Port","where":[["name","==","lsp'1"],["tag",">=",100],["up","!=",true],["addresses","includes",["set",["router","00:00:00:00:00:01 10.0.0.1"]]]],"columns":["_uuid"]}`,
+		},
+		{
+			query:    "SELECT `from`, name FROM `Logical_Switch` WHERE external_ids excludes {owner=neutron}",
+			expected: `{"op":"select","table":"Logical_Switch","where":[["external_ids","excludes",["map",[["owner","neutron"]]]]],"columns":["from","name"]}`,
+		},
+		{
+			query:    `INSERT INTO Logical_Switch (name, external_ids, ports, other_config) VALUES ("ls1", {owner=ovsdb, "env"="test"}, [], {})`,
+			expected: `{"op":"insert","table":"Logical_Switch","row":{"external_ids":["map",[["env","test"],["owner","ovsdb"]]],"name":"ls1","other_config":["map",[]],"ports":["set",[]]}}`,
+		},
+		{
+			query:    "UPDATE Logical_Switch_Port SET tag=-1, enabled=false, up=[] WHERE name==lsp1",
+			expected: `{"op":"update","table":"Logical_Switch_Port","where":[["name","==","lsp1"]],"row":{"enabled":false,"tag":-1,"up":["set",[]]}}`,
+		},
+		{
+			query:    "DELETE FROM Logical_Switch WHERE name==ls1",
+			expected: `{"op":"delete","table":"Logical_Switch","where":[["name","==","ls1"]]}`,
+		},
+		{
+			query:    "MUTATE Open_vSwitch SET next_cfg += 1, external_ids insert {a=b}, other_config DELETE [stats-update-interval]",
+			expected: `{"op":"mutate","table":"Open_vSwitch","where":[],"mutations":[["next_cfg","+=",1],["external_ids","insert",["map",[["a","b"]]]],["other_config","delete",["set",["stats-update-interval"]]]]}`,
+		},
+		{
+			query: "DOSELECT * FROM Open_vSwitch",
+			err:   `1:1: expected SELECT, INSERT, UPDATE, DELETE, or MUTATE, found "DOSELECT"`,
+		},
+		{
+			query: "SELECT * TO Open_vSwitch",
+			err:   `1:10: expected FROM, found "TO"`,
+		},
+		{
+			query: "SELECT * FROM Open_vSwitch LIMIT 1",
+			err:   `1:28: LIMIT clause is executed by the client, see ParseQuery`,
+		},
+		{
+			query: "SELECT * FROM Open_vSwitch WHERE db_version=~7.3.0",
+			err:   `1:44: expected condition function, found "="`,
+		},
+		{
+			query: "SELECT * FROM Open_vSwitch\nWHERE db_version==",
+			err:   `2:19: expected value, found end of query`,
+		},
+		{
+			query: `SELECT * FROM Open_vSwitch WHERE db_version=="7.3.0`,
+			err:   `1:46: literal not terminated`,
+		},
+		{
+			query: `INSERT INTO Logical_Switch (name) VALUES ('ls1`,
+			err:   `1:43: literal not terminated`,
+		},
+		{
+			query: `INSERT INTO Logical_Switch (name) VALUES (ls1, ls2)`,
+			err:   `1:46: more values than 1 columns`,
+		},
+		{
+			query: `UPDATE Logical_Switch SET name==ls1`,
+			err:   `1:31: expected "=", found "=="`,
+		},
+		{
+			query: `MUTATE Open_vSwitch SET next_cfg = 1`,
+			err:   `1:34: expected mutator, found "="`,
+		},
+		{
+			query: `SELECT * FROM Logical_Switch WHERE ports includes [a, b`,
+			err:   `1:56: expected ",", found end of query`,
+		},
+	} {
+		op, err := NewOperation(test.query)
+		if test.err != "" {
+			if err == nil {
+				t.Fatalf("FAIL: Test %d: query '%s', expected to fail, but passed: %v", i, test.query, op)
+			}
+			if !strings.HasSuffix(err.Error(), "parser error: "+test.err) {
+				t.Fatalf("FAIL: Test %d: query '%s', expected to fail with %q, but failed with: %v", i, test.query, test.err, err)
+			}
+			t.Logf("PASS: Test %d: query '%s', expected to fail, failed with: %v", i, test.query, err)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("FAIL: Test %d: query '%s', expected to pass, but failed with: %v", i, test.query, err)
+		}
+		b, err := marshalUnescaped(op)
+		if err != nil {
+			t.Fatalf("FAIL: Test %d: query '%s', expected to marshal, but failed: %v", i, test.query, err)
+		}
+		if expected, _ := marshalUnescaped(json.RawMessage(test.expected)); b != expected {
+			t.Fatalf("FAIL: Test %d: query '%s', expected %s, but got %s", i, test.query, test.expected, b)
+		}
+		t.Logf("PASS: Test %d: query '%s', expected to pass, passed", i, test.query)
+	}
+}
+
+func TestTransactQuery(t *testing.T) {
+	requests := make(chan []json.RawMessage, 10)
+	srv := newTestServer(t, func(s *testServer, req testRequest) {
+		switch req.Method {
+		case "get_schema":
+			s.reply(req, json.RawMessage(testNorthboundSchema))
+		case "transact":
+			requests <- req.Params
+			s.reply(req, []interface{}{map[string]interface{}{"count": 1}})
+		}
+	})
+	cli, err := NewClient(srv.Socket)
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	defer cli.Close()
+
+	query := `UPDATE Logical_Switch SET ports=[36bd4ba1-0d47-4d6a-b8b4-6ad5e7a1b3e1] WHERE _uuid=="0c3e1b2a-7d6f-4e5a-8b9c-1d2e3f4a5b6c"`
+	if _, err := cli.Transact("OVN_Northbound", query); err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	params, err := marshalUnescaped(<-requests)
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	expected, _ := marshalUnescaped(json.RawMessage(`["OVN_Northbound",{"op":"update","table":"Logical_Switch","where":[["_uuid","==",["uuid","0c3e1b2a-7d6f-4e5a-8b9c-1d2e3f4a5b6c"]]],"row":{"ports":["set",[["uuid","36bd4ba1-0d47-4d6a-b8b4-6ad5e7a1b3e1"]]]}}]`))
+	if params != expected {
+		t.Fatalf("FAIL: expected %s, but got %s", expected, params)
+	}
+	t.Logf("PASS: converted the values of the query into the types of the columns")
+
+	for i, test := range []struct {
+		query    string
+		expected string
+	}{
+		{
+			query:    "UPDATE Logical_Switch_Port SET name=007, tag=007, external_ids={vlan=100} WHERE name==123",
+			expected: `["OVN_Northbound",{"op":"update","table":"Logical_Switch_Port","where":[["name","==","123"]],"row":{"external_ids":["map",[["vlan","100"]]],"name":"007","tag":7}}]`,
+		},
+		{
+			query:    "INSERT INTO Logical_Switch (name, external_ids) VALUES (007, {vlan=100, up=true})",
+			expected: `["OVN_Northbound",{"op":"insert","table":"Logical_Switch","row":{"external_ids":["map",[["up","true"],["vlan","100"]]],"name":"007"}}]`,
+		},
+	} {
+		if _, err := cli.Transact("OVN_Northbound", test.query); err != nil {
+			t.Fatalf("FAIL: Test %d: %v", i, err)
+		}
+		params, err := marshalUnescaped(<-requests)
+		if err != nil {
+			t.Fatalf("FAIL: Test %d: %v", i, err)
+		}
+		expected, _ := marshalUnescaped(json.RawMessage(test.expected))
+		if params != expected {
+			t.Fatalf("FAIL: Test %d: expected %s, but got %s", i, expected, params)
+		}
+		t.Logf("PASS: Test %d: kept the text of the unquoted values of '%s'", i, test.query)
+	}
+
+	if _, err := cli.Transact("OVN_Northbound", "UPDATE Logical_Switch_Port SET tag=5000"); err == nil || !strings.Contains(err.Error(), "5000 is greater than maxInteger 4095") {
+		t.Fatalf("FAIL: expected the query to be rejected by the client, but got: %v", err)
+	}
+	t.Logf("PASS: rejected the invalid query")
+}
+
+func TestParseClientQuery(t *testing.T) {
+	for i, test := range []struct {
+		query    string
+		expected []string
+		err      string
+	}{
+		{
+			query: "SELECT ls.name, lsp.name AS port FROM Logical_Switch ls JOIN Logical_Switch_Port lsp ON ls.ports = lsp._uuid WHERE lsp.up==true, ls.name==ls1 ORDER BY port DESC, ls.name LIMIT 10 OFFSET 5",
+			expected: []string{
+				`{"op":"select","table":"Logical_Switch","where":[["name","==","ls1"]],"columns":["name","ports"]}`,
+				`{"op":"select","table":"Logical_Switch_Port","where":[["up","==",true]],"columns":["name","_uuid"]}`,
+			},
+		},
+		{
+			query: "SELECT * FROM Port_Binding AS pb INNER JOIN Datapath_Binding dp ON pb.datapath == dp._uuid",
+			expected: []string{
+				`{"op":"select","table":"Port_Binding","where":[]}`,
+				`{"op":"select","table":"Datapath_Binding","where":[]}`,
+			},
+		},
+		{
+			query: "SELECT name FROM Logical_Switch ORDER BY name OFFSET 1",
+			expected: []string{
+				`{"op":"select","table":"Logical_Switch","where":[],"columns":["name"]}`,
+			},
+		},
+		{
+			query: "SELECT name FROM Logical_Switch ls JOIN Logical_Switch_Port lsp ON lsp.name = ls.name",
+			err:   "1:68: expected a reference column and _uuid column",
+		},
+		{
+			query: "SELECT name FROM Logical_Switch ls JOIN Logical_Switch_Port ON ls.ports = ls._uuid",
+			err:   "1:64: expected the columns of Logical_Switch_Port and of a preceding table",
+		},
+		{
+			query: "SELECT name FROM Logical_Switch JOIN Logical_Switch ON ports = _uuid",
+			err:   "1:38: duplicate table Logical_Switch, expected an alias",
+		},
+		{
+			query: "SELECT lsp.name FROM Logical_Switch",
+			err:   "1:8: unknown table lsp",
+		},
+		{
+			query: "SELECT name FROM Logical_Switch ORDER BY name LIMIT -1",
+			err:   `1:53: expected non-negative integer, found "-"`,
+		},
+	} {
+		q, err := ParseQuery(test.query)
+		if test.err != "" {
+			if err == nil || !strings.HasSuffix(err.Error(), "parser error: "+test.err) {
+				t.Fatalf("FAIL: Test %d: query '%s', expected to fail with %q, but got: %v", i, test.query, test.err, err)
+			}
+			t.Logf("PASS: Test %d: query '%s', expected to fail, failed with: %v", i, test.query, err)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("FAIL: Test %d: query '%s', expected to pass, but failed with: %v", i, test.query, err)
+		}
+		ops := []Operation{q.Operation}
+		for _, j := range q.Joins {
+			ops = append(ops, j.Operation)
+		}
+		if len(ops) != len(test.expected) {
+			t.Fatalf("FAIL: Test %d: query '%s', expected %d operations, but got %d", i, test.query, len(test.expected), len(ops))
+		}
+		for k, op := range ops {
+			b, err := marshalUnescaped(op)
+			if err != nil {
+				t.Fatalf("FAIL: Test %d: %v", i, err)
+			}
+			if expected, _ := marshalUnescaped(json.RawMessage(test.expected[k])); b != expected {
+				t.Fatalf("FAIL: Test %d: query '%s', expected %s, but got %s", i, test.query, expected, b)
+			}
+		}
+		t.Logf("PASS: Test %d: query '%s', expected to pass, passed", i, test.query)
+	}
+}
+
+func TestTransactJoin(t *testing.T) {
+	uuid := func(id string) []interface{} { return []interface{}{"uuid", id} }
+	switches := []interface{}{
+		map[string]interface{}{"_uuid": uuid("ls-1"), "name": "ls1", "ports": []interface{}{"set", []interface{}{uuid("lsp-1"), uuid("lsp-2")}}},
+		map[string]interface{}{"_uuid": uuid("ls-2"), "name": "ls2", "ports": uuid("lsp-3")},
+		map[string]interface{}{"_uuid": uuid("ls-3"), "name": "ls3", "ports": []interface{}{"set", []interface{}{}}},
+	}
+	ports := []interface{}{
+		map[string]interface{}{"_uuid": uuid("lsp-1"), "name": "lsp1", "tag": 10},
+		map[string]interface{}{"_uuid": uuid("lsp-2"), "name": "lsp2", "tag": []interface{}{"set", []interface{}{}}},
+		map[string]interface{}{"_uuid": uuid("lsp-3"), "name": "lsp3", "tag": 5},
+	}
+	transactions := make(chan []json.RawMessage, 10)
+	srv := newTestServer(t, func(s *testServer, req testRequest) {
+		switch req.Method {
+		case "get_schema":
+			s.reply(req, json.RawMessage(testNorthboundSchema))
+		case "transact":
+			transactions <- req.Params
+			results := []interface{}{}
+			for _, param := range req.Params[1:] {
+				var op struct {
+					Table string `json:"table"`
+				}
+				json.Unmarshal(param, &op)
+				switch op.Table {
+				case "Logical_Switch":
+					results = append(results, map[string]interface{}{"rows": switches})
+				case "Logical_Switch_Port":
+					results = append(results, map[string]interface{}{"rows": ports})
+				}
+			}
+			s.reply(req, results)
+		}
+	})
+	cli, err := NewClient(srv.Socket)
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	defer cli.Close()
+
+	for i, test := range []struct {
+		query    string
+		column   string
+		expected []string
+	}{
+		{
+			query:    "SELECT ls.name, lsp.name AS port FROM Logical_Switch ls JOIN Logical_Switch_Port lsp ON ls.ports = lsp._uuid ORDER BY port DESC",
+			column:   "port",
+			expected: []string{"lsp3", "lsp2", "lsp1"},
+		},
+		{
+			query:    "SELECT ls.name, lsp.name FROM Logical_Switch_Port lsp JOIN Logical_Switch ls ON ls.ports = lsp._uuid ORDER BY lsp.tag, lsp.name LIMIT 2",
+			column:   "ls.name",
+			expected: []string{"ls1", "ls2"},
+		},
+		{
+			query:    "SELECT name FROM Logical_Switch ORDER BY name DESC LIMIT 1 OFFSET 1",
+			column:   "name",
+			expected: []string{"ls2"},
+		},
+		{
+			query:    "SELECT name FROM Logical_Switch ORDER BY name OFFSET 5",
+			column:   "name",
+			expected: []string{},
+		},
+	} {
+		result, err := cli.Transact("OVN_Northbound", test.query)
+		if err != nil {
+			t.Fatalf("FAIL: Test %d: query '%s': %v", i, test.query, err)
+		}
+		params := <-transactions
+		if len(params) != 1+len(strings.Split(test.query, "JOIN")) {
+			t.Fatalf("FAIL: Test %d: query '%s', expected the tables selected in a single transaction, but got: %s", i, test.query, params)
+		}
+		if len(result.Rows) != len(test.expected) {
+			t.Fatalf("FAIL: Test %d: query '%s', expected %d rows, but got: %v", i, test.query, len(test.expected), result.Rows)
+		}
+		for k, row := range result.Rows {
+			value, _, err := row.GetColumnValue(test.column, result.Columns)
+			if err != nil || value != test.expected[k] {
+				t.Fatalf("FAIL: Test %d: query '%s', expected %s of row %d to be %s, but got: %v", i, test.query, test.column, k, test.expected[k], result.Rows)
+			}
+		}
+		if _, exists := result.Columns[test.column]; !exists {
+			t.Fatalf("FAIL: Test %d: query '%s', expected the type of %s column, but got: %v", i, test.query, test.column, result.Columns)
+		}
+		t.Logf("PASS: Test %d: query '%s', returned %v", i, test.query, result.Rows)
+	}
+}
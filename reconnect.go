@@ -0,0 +1,282 @@
+// Copyright 2018 Paul Greenberg (greenpau@outlook.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovsdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// ConnectionState is the state of the connection of a client.
+type ConnectionState int
+
+const (
+	// StateDisconnected means the connection was lost or closed.
+	StateDisconnected ConnectionState = iota
+	// StateConnected means the client connected to a remote.
+	StateConnected
+	// StateReconnecting means the client is about to reconnect.
+	StateReconnecting
+)
+
+func (s ConnectionState) String() string {
+	switch s {
+	case StateDisconnected:
+		return "disconnected"
+	case StateConnected:
+		return "connected"
+	case StateReconnecting:
+		return "reconnecting"
+	}
+	return fmt.Sprintf("unknown (%d)", int(s))
+}
+
+// ConnectionEvent reports a change in the state of the connection of
+// a client. The Err holds the reason of the disconnection, if any.
+type ConnectionEvent struct {
+	State  ConnectionState
+	Remote string
+	Err    error
+}
+
+// ReconnectPolicy configures the delays between the attempts to
+// reconnect. The delays grow exponentially, from InitialBackoff up to
+// MaxBackoff, and are randomized by Jitter, e.g. 0.2 for +/- 20%.
+type ReconnectPolicy struct {
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	Jitter         float64
+}
+
+// DefaultReconnectPolicy is used when the policy of a client is not set.
+var DefaultReconnectPolicy = ReconnectPolicy{
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     10 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+}
+
+// Backoff returns the delay before an attempt to reconnect. The attempts
+// are numbered from zero.
+func (p ReconnectPolicy) Backoff(attempt int) time.Duration {
+	if p.InitialBackoff <= 0 {
+		p = DefaultReconnectPolicy
+	}
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	d := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt))
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(d)
+}
+
+// emit reports a connection event to the connection event handler.
+func (cli *Client) emit(event ConnectionEvent) {
+	if event.Err != nil {
+		cli.logf("ovsdb: %s %s: %v", event.State, event.Remote, event.Err)
+	} else {
+		cli.logf("ovsdb: %s %s", event.State, event.Remote)
+	}
+	if cli.onConnectionEvent != nil {
+		cli.onConnectionEvent(event)
+	}
+}
+
+// redial replaces the current connection, if any, with a new one. Over
+// the new connection, the client re-establishes the monitors and requests
+// the locks it had before. It must be called with sem held.
+func (cli *Client) redial() (*connection, error) {
+	old := cli.state.conn
+	if old != nil {
+		if !old.reported {
+			old.reported = true
+			// The server releases the locks of a disconnected client.
+			cli.notifier.dropLocks()
+			cli.emit(ConnectionEvent{State: StateDisconnected, Remote: old.remote, Err: old.err})
+		}
+		cli.emit(ConnectionEvent{State: StateReconnecting})
+	}
+	conn, err := cli.dial()
+	if err != nil {
+		cli.state.closed = true
+		return nil, err
+	}
+	if old != nil {
+		if err := cli.replay(conn); err != nil {
+			conn.close(err)
+			cli.state.closed = true
+			return nil, err
+		}
+	}
+	cli.state.conn = conn
+	cli.state.closed = false
+	cli.emit(ConnectionEvent{State: StateConnected, Remote: conn.remote})
+	go cli.supervise(conn)
+	return conn, nil
+}
+
+// supervise reconnects when a connection fails, until a new connection is
+// established, either by the supervisor or by a request, or the client
+// closes.
+func (cli *Client) supervise(conn *connection) {
+	<-conn.done
+	for attempt := 0; ; attempt++ {
+		cli.sem <- struct{}{}
+		if cli.state.conn != conn || cli.state.shutdown {
+			<-cli.sem
+			return
+		}
+		_, err := cli.redial()
+		<-cli.sem
+		if err == nil {
+			return
+		}
+		backoff := cli.reconnect.Backoff(attempt)
+		cli.logf("ovsdb: reconnect to %s failed, retrying in %s: %v", cli.Endpoint, backoff, err)
+		time.Sleep(backoff)
+	}
+}
+
+// replay re-establishes the monitors and requests the locks of the client
+// over a new connection.
+func (cli *Client) replay(conn *connection) error {
+	for _, m := range cli.notifier.replayableMonitors() {
+		ctx, cancel := context.WithTimeout(context.Background(), cli.timeout())
+		err := cli.replayMonitor(ctx, conn, m)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("'%s' method failed for '%s' monitor: %w", m.Method, m.ID, err)
+		}
+	}
+	for _, id := range cli.notifier.lockIDs() {
+		ctx, cancel := context.WithTimeout(context.Background(), cli.timeout())
+		response, err := cli.roundTrip(ctx, conn, "lock", []interface{}{id})
+		cancel()
+		if err != nil {
+			return fmt.Errorf("'%s' method failed for '%s' lock: %w", "lock", id, err)
+		}
+		var result struct {
+			Locked bool `json:"locked"`
+		}
+		if err := json.Unmarshal(response.Result, &result); err != nil {
+			return fmt.Errorf("'%s' method failed for '%s' lock: %w", "lock", id, err)
+		}
+		if result.Locked {
+			cli.notifier.handleLock("locked", id)
+		}
+	}
+	return nil
+}
+
+// replayMonitor re-establishes a monitor with the same id. The monitor
+// delivers the contents of the monitored tables received from the server,
+// along with the deletions of the rows removed while the client was
+// disconnected. The monitors created with "monitor_cond_since" method
+// receive only the changes, when the server still has the last
+// transaction seen by the monitor. The notifications received before the
+// reply is processed are delivered after the contents.
+func (cli *Client) replayMonitor(ctx context.Context, conn *connection, m *Monitor) error {
+	var snapshot *monitorUpdate
+	m.beginReplay()
+	defer func() {
+		m.endReplay(snapshot)
+	}()
+	var sentTxnID string
+	m.mux.Lock()
+	params := []interface{}{m.Database, m.ID}
+	switch m.Method {
+	case "monitor":
+		params = append(params, m.Requests)
+	case "monitor_cond":
+		params = append(params, copyCondRequests(m.CondRequests))
+	case "monitor_cond_since":
+		sentTxnID = m.lastTxnID
+		lastTxnID := sentTxnID
+		if lastTxnID == "" {
+			lastTxnID = "00000000-0000-0000-0000-000000000000"
+		}
+		params = append(params, copyCondRequests(m.CondRequests), lastTxnID)
+	}
+	m.mux.Unlock()
+	response, err := cli.roundTrip(ctx, conn, m.Method, params)
+	if err != nil {
+		return err
+	}
+	var u monitorUpdate
+	switch m.Method {
+	case "monitor":
+		if err := json.Unmarshal(response.Result, &u.updates); err != nil {
+			return err
+		}
+	case "monitor_cond":
+		if err := json.Unmarshal(response.Result, &u.updates2); err != nil {
+			return err
+		}
+	case "monitor_cond_since":
+		var result []json.RawMessage
+		if err := json.Unmarshal(response.Result, &result); err != nil {
+			return err
+		}
+		if len(result) != 3 {
+			return fmt.Errorf("unexpected response: %s", response.Result)
+		}
+		var found bool
+		var txnID string
+		if err := json.Unmarshal(result[0], &found); err != nil {
+			return err
+		}
+		if err := json.Unmarshal(result[1], &txnID); err != nil {
+			return err
+		}
+		if err := json.Unmarshal(result[2], &u.updates2); err != nil {
+			return err
+		}
+		// The "update3" notifications received since the request was sent
+		// carry later transactions.
+		m.mux.Lock()
+		if m.lastTxnID == sentTxnID {
+			m.lastTxnID = txnID
+		}
+		m.mux.Unlock()
+		if found {
+			if u.updates2 != nil {
+				snapshot = &u
+			}
+			return nil
+		}
+	}
+	u = m.resync(u)
+	snapshot = &u
+	return nil
+}
+
+func copyCondRequests(requests map[string]MonitorCondRequest) map[string]MonitorCondRequest {
+	c := make(map[string]MonitorCondRequest)
+	for table, req := range requests {
+		c[table] = req
+	}
+	return c
+}
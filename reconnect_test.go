@@ -0,0 +1,378 @@
+// Copyright 2018 Paul Greenberg (greenpau@outlook.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovsdb
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReconnectPolicy(t *testing.T) {
+	policy := ReconnectPolicy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Multiplier:     2,
+	}
+	for i, expected := range []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	} {
+		if d := policy.Backoff(i); d != expected {
+			t.Fatalf("FAIL: Attempt %d: expected backoff %s, but got %s", i, expected, d)
+		}
+	}
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if d := policy.Backoff(1); d < 100*time.Millisecond || d > 300*time.Millisecond {
+			t.Fatalf("FAIL: expected jittered backoff within 100ms and 300ms, but got %s", d)
+		}
+	}
+	t.Logf("PASS: backoff grows exponentially up to the maximum")
+}
+
+func TestReconnect(t *testing.T) {
+	var mux sync.Mutex
+	rows := map[string]interface{}{
+		"uuid-1": map[string]interface{}{"new": map[string]interface{}{"name": "ls1"}},
+		"uuid-2": map[string]interface{}{"new": map[string]interface{}{"name": "ls2"}},
+	}
+	monitorIDs := []string{}
+	srv := newTestServer(t, func(s *testServer, req testRequest) {
+		switch req.Method {
+		case "monitor":
+			var id string
+			json.Unmarshal(req.Params[1], &id)
+			mux.Lock()
+			monitorIDs = append(monitorIDs, id)
+			result := map[string]interface{}{"Logical_Switch": rows}
+			mux.Unlock()
+			s.reply(req, result)
+		case "lock":
+			s.reply(req, map[string]interface{}{"locked": true})
+		case "echo":
+			s.reply(req, req.Params)
+		}
+	})
+	events := make(chan ConnectionEvent, 10)
+	cli, err := NewClient(srv.Socket,
+		WithRetryPolicy(DefaultMaxRetries, ReconnectPolicy{InitialBackoff: 10 * time.Millisecond}),
+		WithConnectionEventHandler(func(event ConnectionEvent) {
+			events <- event
+		}),
+	)
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	defer cli.Close()
+	lockEvents := cli.LockEvents()
+	m, err := cli.Monitor("OVN_Northbound", map[string]MonitorRequest{"Logical_Switch": {}})
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	if locked, err := cli.Lock("ovn_northd"); err != nil || !locked {
+		t.Fatalf("FAIL: expected to acquire the lock: %v", err)
+	}
+
+	// While the client is disconnected, a row is deleted and another
+	// is inserted.
+	mux.Lock()
+	rows = map[string]interface{}{
+		"uuid-2": map[string]interface{}{"new": map[string]interface{}{"name": "ls2"}},
+		"uuid-3": map[string]interface{}{"new": map[string]interface{}{"name": "ls3"}},
+	}
+	mux.Unlock()
+	srv.disconnect()
+
+	for i, expected := range []ConnectionState{
+		StateConnected,
+		StateDisconnected,
+		StateReconnecting,
+		StateConnected,
+	} {
+		select {
+		case event := <-events:
+			if event.State != expected {
+				t.Fatalf("FAIL: Event %d: expected %s state, but got %s", i, expected, event.State)
+			}
+			if event.State == StateConnected && event.Remote != srv.Socket {
+				t.Fatalf("FAIL: Event %d: unexpected remote: %s", i, event.Remote)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("FAIL: Event %d: expected %s state, but timed out", i, expected)
+		}
+	}
+	t.Logf("PASS: reported the connection state changes")
+
+	select {
+	case updates := <-m.Updates:
+		update := updates["Logical_Switch"]
+		if len(update) != 3 || update["uuid-1"].New != nil || update["uuid-2"].New == nil || update["uuid-3"].New == nil {
+			t.Fatalf("FAIL: unexpected updates after reconnect: %v", updates)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("FAIL: expected updates after reconnect, but timed out")
+	}
+	mux.Lock()
+	if len(monitorIDs) != 2 || monitorIDs[1] != m.ID {
+		t.Fatalf("FAIL: expected the monitor %s to be re-established, but got %v", m.ID, monitorIDs)
+	}
+	mux.Unlock()
+	t.Logf("PASS: re-established the monitor")
+
+	for i, expected := range []LockEvent{
+		{ID: "ovn_northd", Locked: false},
+		{ID: "ovn_northd", Locked: true},
+	} {
+		select {
+		case event := <-lockEvents:
+			if event != expected {
+				t.Fatalf("FAIL: Event %d: unexpected lock event: %v", i, event)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("FAIL: Event %d: expected to receive a lock event, but timed out", i)
+		}
+	}
+	if !cli.HasLock("ovn_northd") {
+		t.Fatalf("FAIL: expected to own the lock after reconnect")
+	}
+	t.Logf("PASS: re-acquired the lock")
+
+	if err := cli.Echo("test message"); err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	cli.Close()
+	select {
+	case event := <-events:
+		if event.State != StateDisconnected || event.Err != nil {
+			t.Fatalf("FAIL: expected disconnected state without error, but got %v", event)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("FAIL: expected disconnected state, but timed out")
+	}
+	t.Logf("PASS: reported the closure")
+}
+
+func TestReplayMonitorOrder(t *testing.T) {
+	var mux sync.Mutex
+	replies := 0
+	srv := newTestServer(t, func(s *testServer, req testRequest) {
+		if req.Method != "monitor_cond" {
+			return
+		}
+		var id string
+		json.Unmarshal(req.Params[1], &id)
+		mux.Lock()
+		replies++
+		replayed := replies > 1
+		mux.Unlock()
+		if !replayed {
+			s.reply(req, map[string]interface{}{
+				"Logical_Switch": map[string]interface{}{
+					"uuid-1": map[string]interface{}{"initial": map[string]interface{}{"name": "ls1"}},
+					"uuid-2": map[string]interface{}{"initial": map[string]interface{}{"name": "ls2"}},
+				},
+			})
+			return
+		}
+		// The row uuid-1 was deleted while the client was disconnected.
+		// Right after the reply, the row uuid-2 is deleted and the row
+		// uuid-3 is inserted.
+		s.reply(req, map[string]interface{}{
+			"Logical_Switch": map[string]interface{}{
+				"uuid-2": map[string]interface{}{"initial": map[string]interface{}{"name": "ls2"}},
+			},
+		})
+		s.notify("update2", id, map[string]interface{}{
+			"Logical_Switch": map[string]interface{}{
+				"uuid-2": map[string]interface{}{"delete": nil},
+				"uuid-3": map[string]interface{}{"insert": map[string]interface{}{"name": "ls3"}},
+			},
+		})
+	})
+	cli, err := NewClient(srv.Socket,
+		WithRetryPolicy(DefaultMaxRetries, ReconnectPolicy{InitialBackoff: 10 * time.Millisecond}),
+	)
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	defer cli.Close()
+	m, err := cli.MonitorCond("OVN_Northbound", map[string]MonitorCondRequest{"Logical_Switch": {}})
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	srv.disconnect()
+
+	for i, expected := range []map[string]string{
+		{"uuid-1": "delete", "uuid-2": "initial"},
+		{"uuid-2": "delete", "uuid-3": "insert"},
+	} {
+		select {
+		case updates := <-m.Updates2:
+			update := updates["Logical_Switch"]
+			if len(updates) != 1 || len(update) != len(expected) {
+				t.Fatalf("FAIL: Update %d: unexpected updates: %v", i, updates)
+			}
+			for uuid, kind := range expected {
+				row, exists := update[uuid]
+				if !exists {
+					t.Fatalf("FAIL: Update %d: expected %s of %s, but got %v", i, kind, uuid, updates)
+				}
+				if (kind == "delete") != row.Delete || (kind == "initial") != (row.Initial != nil) || (kind == "insert") != (row.Insert != nil) {
+					t.Fatalf("FAIL: Update %d: expected %s of %s, but got %v", i, kind, uuid, updates)
+				}
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("FAIL: Update %d: expected updates after reconnect, but timed out", i)
+		}
+	}
+	t.Logf("PASS: delivered the notification after the contents of the replayed monitor")
+}
+
+func TestReplayMonitorTransactionID(t *testing.T) {
+	var mux sync.Mutex
+	replies := 0
+	srv := newTestServer(t, func(s *testServer, req testRequest) {
+		if req.Method != "monitor_cond_since" {
+			return
+		}
+		var id string
+		json.Unmarshal(req.Params[1], &id)
+		mux.Lock()
+		replies++
+		replayed := replies > 1
+		mux.Unlock()
+		if !replayed {
+			s.reply(req, []interface{}{false, "txn-1", map[string]interface{}{}})
+			return
+		}
+		s.reply(req, []interface{}{true, "txn-2", map[string]interface{}{}})
+		s.notify("update3", id, "txn-3", map[string]interface{}{
+			"Logical_Switch": map[string]interface{}{
+				"uuid-1": map[string]interface{}{"insert": map[string]interface{}{"name": "ls1"}},
+			},
+		})
+	})
+	cli, err := NewClient(srv.Socket,
+		WithRetryPolicy(DefaultMaxRetries, ReconnectPolicy{InitialBackoff: 10 * time.Millisecond}),
+	)
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	defer cli.Close()
+	m, _, err := cli.MonitorCondSince("OVN_Northbound", map[string]MonitorCondRequest{"Logical_Switch": {}}, "")
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	srv.disconnect()
+
+	for inserted := false; !inserted; {
+		select {
+		case updates := <-m.Updates2:
+			_, inserted = updates["Logical_Switch"]["uuid-1"]
+		case <-time.After(2 * time.Second):
+			t.Fatalf("FAIL: expected updates after reconnect, but timed out")
+		}
+	}
+	if id := m.LastTransactionID(); id != "txn-3" {
+		t.Fatalf("FAIL: expected the last transaction id txn-3, but got %s", id)
+	}
+	t.Logf("PASS: kept the transaction id of the notification received after the reply")
+}
+
+func TestNoReconnectAfterClose(t *testing.T) {
+	srv := newTestServer(t, func(s *testServer, req testRequest) {
+		s.reply(req, req.Params)
+	})
+	var mux sync.Mutex
+	connected := 0
+	cli, err := NewClient(srv.Socket, WithConnectionEventHandler(func(event ConnectionEvent) {
+		mux.Lock()
+		defer mux.Unlock()
+		if event.State == StateConnected {
+			connected++
+		}
+	}))
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	if err := cli.Close(); err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	err = cli.Echo("ping")
+	if !errors.Is(err, ErrClientClosed) || !errors.Is(err, ErrConnectionLost) {
+		t.Fatalf("FAIL: expected closed client error, but got: %v", err)
+	}
+	mux.Lock()
+	defer mux.Unlock()
+	if connected != 1 {
+		t.Fatalf("FAIL: expected a single connection, but connected %d times", connected)
+	}
+	t.Logf("PASS: expected to fail, failed with: %v", err)
+}
+
+func TestNoResendAfterConnectionLoss(t *testing.T) {
+	var srv *testServer
+	var mux sync.Mutex
+	counts := map[string]int{}
+	srv = newTestServer(t, func(s *testServer, req testRequest) {
+		mux.Lock()
+		counts[req.Method]++
+		count := counts[req.Method]
+		mux.Unlock()
+		if count == 1 {
+			// The server drops the connection after receiving the
+			// first request of each method.
+			srv.disconnect()
+			return
+		}
+		switch req.Method {
+		case "list_dbs":
+			s.reply(req, []string{"OVN_Northbound"})
+		case "transact":
+			s.reply(req, []interface{}{map[string]interface{}{"uuid": []string{"uuid", "5b3a7c1e-2d4f-4a6b-8c9d-0e1f2a3b4c5d"}}})
+		}
+	})
+	cli, err := NewClient(srv.Socket, WithRetryPolicy(2, ReconnectPolicy{InitialBackoff: time.Millisecond}))
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	defer cli.Close()
+
+	if _, err := cli.Databases(); err != nil {
+		t.Fatalf("FAIL: expected 'list_dbs' request to be resent, but failed: %v", err)
+	}
+	insert, err := NewInsertOperation("Logical_Switch", Row{"name": "ls1"}, "")
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	_, err = cli.Execute(NewTransaction("OVN_Northbound").Add(insert))
+	var connErr *ConnectionError
+	if !errors.Is(err, ErrConnectionLost) || !errors.As(err, &connErr) {
+		t.Fatalf("FAIL: expected connection loss, but got: %v", err)
+	}
+	mux.Lock()
+	defer mux.Unlock()
+	if counts["list_dbs"] != 2 || counts["transact"] != 1 {
+		t.Fatalf("FAIL: expected 'list_dbs' to be sent twice and 'transact' once, but got: %v", counts)
+	}
+	t.Logf("PASS: expected to fail, failed with: %v", err)
+}
@@ -0,0 +1,302 @@
+// Copyright 2018 Paul Greenberg (greenpau@outlook.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovsdb
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// Reference is a column of a table referring to the rows of another table,
+// as described by "refTable" and "refType" of the <base-type> of the
+// column in https://tools.ietf.org/html/rfc7047#section-3.2.
+type Reference struct {
+	Table    string
+	Column   string
+	RefTable string
+	// RefType is "strong" or "weak". The server deletes the weak
+	// references to the deleted rows, and refuses to delete the rows
+	// having strong references.
+	RefType string
+}
+
+// Referrer is a row referring to another row through a reference column.
+type Referrer struct {
+	Reference
+	Row Row
+}
+
+// DanglingReference is a reference to a row missing from its table.
+type DanglingReference struct {
+	Reference
+	// UUID is the UUID of the referring row, and RefUUID is the UUID of
+	// the missing row.
+	UUID    string
+	RefUUID string
+}
+
+// References returns the reference columns of a table sorted by column.
+// The map columns with the keys and the values referring to the rows of
+// different tables have two references. When both refer to the same table,
+// the reference is strong, if either of them is.
+func (sc *Schema) References(table string) []Reference {
+	refs := []Reference{}
+	t, exists := sc.Tables[table]
+	if !exists {
+		return refs
+	}
+	for _, column := range sc.GetColumns(table) {
+		ct := t.Columns[column].Type
+		if ct.Key.RefTable != "" {
+			refs = append(refs, Reference{Table: table, Column: column, RefTable: ct.Key.RefTable, RefType: ct.Key.RefType})
+		}
+		if ct.Value == nil || ct.Value.RefTable == "" {
+			continue
+		}
+		if ct.Key.RefTable == ct.Value.RefTable {
+			if ct.Value.RefType == "strong" {
+				refs[len(refs)-1].RefType = "strong"
+			}
+			continue
+		}
+		refs = append(refs, Reference{Table: table, Column: column, RefTable: ct.Value.RefTable, RefType: ct.Value.RefType})
+	}
+	return refs
+}
+
+// reference returns the reference of a column.
+func (sc *Schema) reference(table, column string) (Reference, error) {
+	if _, exists := sc.Tables[table]; !exists {
+		return Reference{}, fmt.Errorf("table %s not found in %s schema", table, sc.Name)
+	}
+	if _, exists := sc.Tables[table].Columns[column]; !exists {
+		return Reference{}, fmt.Errorf("column %s not found in table %s", column, table)
+	}
+	for _, ref := range sc.References(table) {
+		if ref.Column == column {
+			return ref, nil
+		}
+	}
+	return Reference{}, fmt.Errorf("column %s of table %s is not a reference", column, table)
+}
+
+// referencedUUIDs returns the sorted UUIDs of the rows the value of a
+// reference column refers to, see datumUUIDs.
+func (sc *Schema) referencedUUIDs(ref Reference, value interface{}) []string {
+	ct := sc.Tables[ref.Table].Columns[ref.Column].Type
+	return datumUUIDs(value, &ct, ref.RefTable)
+}
+
+// Follow returns the copies of the rows referred to by a reference column
+// of a row, sorted by UUID, e.g. the ports of a bridge. The references to
+// the rows missing from the cache are skipped, see DanglingReferences.
+func (tc *TableCache) Follow(table, uuid, column string) ([]Row, error) {
+	return tc.Walk(table, uuid, column)
+}
+
+// Walk follows the reference columns of the path from a row and returns
+// the copies of the rows at the end of the path, sorted by UUID, e.g. the
+// interfaces of the ports of a bridge for "ports" and "interfaces" path.
+// The tables along the path must be cached.
+func (tc *TableCache) Walk(table, uuid string, path ...string) ([]Row, error) {
+	tc.mux.RLock()
+	defer tc.mux.RUnlock()
+	if _, exists := tc.tables[table][uuid]; !exists {
+		return nil, fmt.Errorf("row %s not found in %s table", uuid, table)
+	}
+	uuids := []string{uuid}
+	for _, column := range path {
+		ref, err := tc.Schema.reference(table, column)
+		if err != nil {
+			return nil, err
+		}
+		if _, cached := tc.tables[ref.RefTable]; !cached {
+			return nil, fmt.Errorf("table %s referred to by column %s of table %s is not cached", ref.RefTable, column, table)
+		}
+		next := make(map[string]bool)
+		for _, id := range uuids {
+			for _, refID := range tc.Schema.referencedUUIDs(ref, tc.tables[table][id][column]) {
+				if _, exists := tc.tables[ref.RefTable][refID]; exists {
+					next[refID] = true
+				}
+			}
+		}
+		uuids = uuids[:0]
+		for id := range next {
+			uuids = append(uuids, id)
+		}
+		sort.Strings(uuids)
+		table = ref.RefTable
+	}
+	rows := []Row{}
+	for _, id := range uuids {
+		rows = append(rows, copyRow(tc.tables[table][id]))
+	}
+	return rows, nil
+}
+
+// Referrers returns the cached rows referring to a row, e.g. the logical
+// switch of a logical switch port, sorted by table, UUID, and column.
+func (tc *TableCache) Referrers(table, uuid string) []Referrer {
+	tc.mux.RLock()
+	defer tc.mux.RUnlock()
+	referrers := []Referrer{}
+	for _, t := range tc.cachedTables() {
+		refs := []Reference{}
+		for _, ref := range tc.Schema.References(t) {
+			if ref.RefTable == table {
+				refs = append(refs, ref)
+			}
+		}
+		if len(refs) == 0 {
+			continue
+		}
+		for _, id := range tc.uuids(t) {
+			row := tc.tables[t][id]
+			for _, ref := range refs {
+				for _, refID := range tc.Schema.referencedUUIDs(ref, row[ref.Column]) {
+					if refID == uuid {
+						referrers = append(referrers, Referrer{Reference: ref, Row: copyRow(row)})
+						break
+					}
+				}
+			}
+		}
+	}
+	return referrers
+}
+
+// DanglingReferences returns the references of the cached rows to the rows
+// missing from the cached tables, sorted by table, UUID, column, and the
+// UUID of the missing row. The references to the tables not cached are
+// not checked. The strong references dangle when the cache is not
+// consistent, e.g. the tables are monitored separately, while the weak
+// references dangle until the server removes them.
+func (tc *TableCache) DanglingReferences() []DanglingReference {
+	tc.mux.RLock()
+	defer tc.mux.RUnlock()
+	dangling := []DanglingReference{}
+	for _, table := range tc.cachedTables() {
+		refs := []Reference{}
+		for _, ref := range tc.Schema.References(table) {
+			if _, cached := tc.tables[ref.RefTable]; cached {
+				refs = append(refs, ref)
+			}
+		}
+		if len(refs) == 0 {
+			continue
+		}
+		for _, id := range tc.uuids(table) {
+			row := tc.tables[table][id]
+			for _, ref := range refs {
+				for _, refID := range tc.Schema.referencedUUIDs(ref, row[ref.Column]) {
+					if _, exists := tc.tables[ref.RefTable][refID]; !exists {
+						dangling = append(dangling, DanglingReference{Reference: ref, UUID: id, RefUUID: refID})
+					}
+				}
+			}
+		}
+	}
+	return dangling
+}
+
+// cachedTables returns the sorted names of the cached tables. The caller
+// holds the lock.
+func (tc *TableCache) cachedTables() []string {
+	tables := []string{}
+	for table := range tc.tables {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	return tables
+}
+
+// FollowReferences follows the reference columns of the path from the rows
+// of a table, e.g. the rows returned by Transact, and returns the rows at
+// the end of the path, sorted by UUID. The rows of the cached tables are
+// read from the cache, and the other rows are selected from the server,
+// one transaction per column of the path. The rows missing from the
+// tables are skipped.
+func (c *Client) FollowReferences(db, table string, rows []Row, path ...string) ([]Row, error) {
+	return c.FollowReferencesContext(context.Background(), db, table, rows, path...)
+}
+
+// FollowReferencesContext is like FollowReferences, but honors the
+// cancellation and the deadline of the context.
+func (c *Client) FollowReferencesContext(ctx context.Context, db, table string, rows []Row, path ...string) ([]Row, error) {
+	if c == nil {
+		return nil, fmt.Errorf("interface is unavailable")
+	}
+	schema, err := c.GetSchemaContext(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	for _, column := range path {
+		ref, err := schema.reference(table, column)
+		if err != nil {
+			return nil, err
+		}
+		seen := make(map[string]bool)
+		uuids := []string{}
+		for _, row := range rows {
+			if _, exists := row[column]; !exists {
+				return nil, fmt.Errorf("following '%s' column of '%s' table failed: column not selected", column, table)
+			}
+			for _, id := range schema.referencedUUIDs(ref, row[column]) {
+				if !seen[id] {
+					seen[id] = true
+					uuids = append(uuids, id)
+				}
+			}
+		}
+		sort.Strings(uuids)
+		rows, err = c.selectRowsByUUID(ctx, db, ref.RefTable, uuids)
+		if err != nil {
+			return nil, fmt.Errorf("following '%s' column of '%s' table failed: %w", column, table, err)
+		}
+		table = ref.RefTable
+	}
+	return rows, nil
+}
+
+// selectRowsByUUID returns the rows with the UUIDs, either from the cache
+// or from the server in a single transaction.
+func (c *Client) selectRowsByUUID(ctx context.Context, db, table string, uuids []string) ([]Row, error) {
+	rows := []Row{}
+	if len(uuids) == 0 {
+		return rows, nil
+	}
+	if tc := c.Cache(db); tc != nil && tc.HasTable(table) {
+		for _, id := range uuids {
+			if row, exists := tc.Row(table, id); exists {
+				rows = append(rows, row)
+			}
+		}
+		return rows, nil
+	}
+	ops := []Operation{}
+	for _, id := range uuids {
+		ops = append(ops, Operation{Name: "select", Table: table, Conditions: []Condition{NewUUIDCondition(id)}})
+	}
+	results, err := c.transact(ctx, db, ops)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range results {
+		rows = append(rows, r.Rows...)
+	}
+	return rows, nil
+}
@@ -0,0 +1,269 @@
+// Copyright 2018 Paul Greenberg (greenpau@outlook.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovsdb
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+var testBridgeSchema = []byte(`{
+  "name": "Open_vSwitch",
+  "version": "8.3.0",
+  "tables": {
+    "Bridge": {
+      "columns": {
+        "name": {"type": "string"},
+        "ports": {"type": {"key": {"type": "uuid", "refTable": "Port"}, "min": 0, "max": "unlimited"}},
+        "mirrors": {"type": {"key": {"type": "uuid", "refTable": "Mirror"}, "min": 0, "max": "unlimited"}}
+      },
+      "isRoot": true
+    },
+    "Port": {
+      "columns": {
+        "name": {"type": "string"},
+        "interfaces": {"type": {"key": {"type": "uuid", "refTable": "Interface"}, "min": 1, "max": "unlimited"}}
+      }
+    },
+    "Interface": {
+      "columns": {
+        "name": {"type": "string"}
+      }
+    },
+    "Mirror": {
+      "columns": {
+        "name": {"type": "string"},
+        "select_src_port": {"type": {"key": {"type": "uuid", "refTable": "Port", "refType": "weak"}, "min": 0, "max": "unlimited"}}
+      }
+    }
+  }
+}`)
+
+// testBridgeRows are the rows of two bridges. The port p3 of br-ex
+// refers to the missing interface i4.
+var testBridgeRows = TableUpdates{
+	"Bridge": {
+		"b1": {New: Row{"name": "br-int", "ports": []interface{}{"set", []interface{}{[]interface{}{"uuid", "p1"}, []interface{}{"uuid", "p2"}}}, "mirrors": []interface{}{"uuid", "m1"}}},
+		"b2": {New: Row{"name": "br-ex", "ports": []interface{}{"uuid", "p3"}, "mirrors": []interface{}{"set", []interface{}{}}}},
+	},
+	"Port": {
+		"p1": {New: Row{"name": "p1", "interfaces": []interface{}{"uuid", "i1"}}},
+		"p2": {New: Row{"name": "p2", "interfaces": []interface{}{"set", []interface{}{[]interface{}{"uuid", "i2"}, []interface{}{"uuid", "i3"}}}}},
+		"p3": {New: Row{"name": "p3", "interfaces": []interface{}{"uuid", "i4"}}},
+	},
+	"Interface": {
+		"i1": {New: Row{"name": "i1"}},
+		"i2": {New: Row{"name": "i2"}},
+		"i3": {New: Row{"name": "i3"}},
+	},
+	"Mirror": {
+		"m1": {New: Row{"name": "m1", "select_src_port": []interface{}{"set", []interface{}{[]interface{}{"uuid", "p1"}, []interface{}{"uuid", "p9"}}}}},
+	},
+}
+
+// rowNames returns the names of the rows.
+func rowNames(rows []Row) []string {
+	names := []string{}
+	for _, row := range rows {
+		names = append(names, row["name"].(string))
+	}
+	return names
+}
+
+func TestSchemaReferences(t *testing.T) {
+	schema := newTestSchema(t, testBridgeSchema)
+	expected := []Reference{
+		{Table: "Bridge", Column: "mirrors", RefTable: "Mirror", RefType: "strong"},
+		{Table: "Bridge", Column: "ports", RefTable: "Port", RefType: "strong"},
+	}
+	if refs := schema.References("Bridge"); !reflect.DeepEqual(refs, expected) {
+		t.Fatalf("FAIL: expected %v, but got %v", expected, refs)
+	}
+	expected = []Reference{{Table: "Mirror", Column: "select_src_port", RefTable: "Port", RefType: "weak"}}
+	if refs := schema.References("Mirror"); !reflect.DeepEqual(refs, expected) {
+		t.Fatalf("FAIL: expected %v, but got %v", expected, refs)
+	}
+	if refs := schema.References("Interface"); len(refs) != 0 {
+		t.Fatalf("FAIL: expected no references, but got %v", refs)
+	}
+	t.Logf("PASS: found the references of the tables")
+}
+
+func TestTableCacheReferences(t *testing.T) {
+	schema := newTestSchema(t, testBridgeSchema)
+	tc, err := NewTableCache(schema, "Bridge", "Port", "Interface", "Mirror")
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	tc.Populate(testBridgeRows)
+
+	for i, test := range []struct {
+		table    string
+		uuid     string
+		path     []string
+		expected []string
+		err      bool
+	}{
+		{table: "Bridge", uuid: "b1", path: []string{"ports"}, expected: []string{"p1", "p2"}},
+		{table: "Bridge", uuid: "b1", path: []string{"ports", "interfaces"}, expected: []string{"i1", "i2", "i3"}},
+		{table: "Bridge", uuid: "b2", path: []string{"ports", "interfaces"}, expected: []string{}},
+		{table: "Bridge", uuid: "b1", path: []string{"mirrors", "select_src_port", "interfaces"}, expected: []string{"i1"}},
+		{table: "Bridge", uuid: "b1", expected: []string{"br-int"}},
+		{table: "Bridge", uuid: "b1", path: []string{"name"}, err: true},
+		{table: "Bridge", uuid: "b1", path: []string{"controller"}, err: true},
+		{table: "Bridge", uuid: "b9", path: []string{"ports"}, err: true},
+	} {
+		rows, err := tc.Walk(test.table, test.uuid, test.path...)
+		if test.err {
+			if err == nil {
+				t.Fatalf("FAIL: Test %d: expected %v path to fail, but got %v", i, test.path, rows)
+			}
+			t.Logf("PASS: Test %d: expected to fail, failed with: %v", i, err)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("FAIL: Test %d: %v", i, err)
+		}
+		if names := rowNames(rows); !reflect.DeepEqual(names, test.expected) {
+			t.Fatalf("FAIL: Test %d: expected %v, but got %v", i, test.expected, names)
+		}
+		t.Logf("PASS: Test %d: walked %v path to %v", i, test.path, test.expected)
+	}
+
+	rows, err := tc.Follow("Port", "p2", "interfaces")
+	if err != nil || !reflect.DeepEqual(rowNames(rows), []string{"i2", "i3"}) {
+		t.Fatalf("FAIL: expected the interfaces of p2, but got %v: %v", rows, err)
+	}
+	t.Logf("PASS: followed the interfaces of p2")
+
+	referrers := tc.Referrers("Port", "p1")
+	if len(referrers) != 2 || referrers[0].Table != "Bridge" || referrers[0].Row["name"] != "br-int" ||
+		referrers[1].Table != "Mirror" || referrers[1].RefType != "weak" {
+		t.Fatalf("FAIL: expected br-int and m1 to refer to p1, but got %v", referrers)
+	}
+	t.Logf("PASS: found the rows referring to p1")
+
+	expected := []DanglingReference{
+		{Reference: Reference{Table: "Mirror", Column: "select_src_port", RefTable: "Port", RefType: "weak"}, UUID: "m1", RefUUID: "p9"},
+		{Reference: Reference{Table: "Port", Column: "interfaces", RefTable: "Interface", RefType: "strong"}, UUID: "p3", RefUUID: "i4"},
+	}
+	if dangling := tc.DanglingReferences(); !reflect.DeepEqual(dangling, expected) {
+		t.Fatalf("FAIL: expected %v, but got %v", expected, dangling)
+	}
+	t.Logf("PASS: found the dangling references")
+}
+
+func TestMapReferences(t *testing.T) {
+	schema := newTestSchema(t, []byte(`{
+  "name": "Test",
+  "version": "1.0.0",
+  "tables": {
+    "Node": {
+      "columns": {
+        "name": {"type": "string"},
+        "links": {"type": {"key": {"type": "uuid", "refTable": "Node", "refType": "weak"}, "value": {"type": "uuid", "refTable": "Node"}, "min": 0, "max": "unlimited"}}
+      },
+      "isRoot": true
+    }
+  }
+}`))
+	ref := Reference{Table: "Node", Column: "links", RefTable: "Node", RefType: "strong"}
+	if refs := schema.References("Node"); !reflect.DeepEqual(refs, []Reference{ref}) {
+		t.Fatalf("FAIL: expected %v, but got %v", []Reference{ref}, refs)
+	}
+	t.Logf("PASS: found a single reference of the map column")
+
+	link := func(k, v string) []interface{} {
+		return []interface{}{[]interface{}{"uuid", k}, []interface{}{"uuid", v}}
+	}
+	tc, err := NewTableCache(schema, "Node")
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	tc.Populate(TableUpdates{
+		"Node": {
+			"n1": {New: Row{"name": "n1", "links": []interface{}{"map", []interface{}{link("n2", "n9")}}}},
+			"n2": {New: Row{"name": "n2", "links": []interface{}{"map", []interface{}{}}}},
+			"n3": {New: Row{"name": "n3", "links": []interface{}{"map", []interface{}{link("n2", "n2")}}}},
+		},
+	})
+	referrers := tc.Referrers("Node", "n2")
+	if len(referrers) != 2 || referrers[0].Row["name"] != "n1" || referrers[1].Row["name"] != "n3" {
+		t.Fatalf("FAIL: expected n1 and n3 to refer to n2, but got %v", referrers)
+	}
+	t.Logf("PASS: found the rows referring to n2")
+
+	expected := []DanglingReference{{Reference: ref, UUID: "n1", RefUUID: "n9"}}
+	if dangling := tc.DanglingReferences(); !reflect.DeepEqual(dangling, expected) {
+		t.Fatalf("FAIL: expected %v, but got %v", expected, dangling)
+	}
+	t.Logf("PASS: found the dangling reference")
+}
+
+func TestFollowReferences(t *testing.T) {
+	uuid := func(id string) []interface{} { return []interface{}{"uuid", id} }
+	rows := map[string]map[string]interface{}{
+		"p1": {"_uuid": uuid("p1"), "name": "p1", "interfaces": uuid("i1")},
+		"p2": {"_uuid": uuid("p2"), "name": "p2", "interfaces": []interface{}{"set", []interface{}{uuid("i2"), uuid("i3")}}},
+		"i1": {"_uuid": uuid("i1"), "name": "i1"},
+		"i2": {"_uuid": uuid("i2"), "name": "i2"},
+	}
+	srv := newTestServer(t, func(s *testServer, req testRequest) {
+		switch req.Method {
+		case "get_schema":
+			s.reply(req, json.RawMessage(testBridgeSchema))
+		case "transact":
+			results := []interface{}{}
+			for _, param := range req.Params[1:] {
+				var op struct {
+					Where [][]interface{} `json:"where"`
+				}
+				json.Unmarshal(param, &op)
+				id := op.Where[0][2].([]interface{})[1].(string)
+				selected := []interface{}{}
+				if row, exists := rows[id]; exists {
+					selected = append(selected, row)
+				}
+				results = append(results, map[string]interface{}{"rows": selected})
+			}
+			s.reply(req, results)
+		}
+	})
+	cli, err := NewClient(srv.Socket)
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	defer cli.Close()
+
+	bridges := []Row{{"name": "br-int", "ports": []interface{}{"set", []interface{}{uuid("p1"), uuid("p2")}}}}
+	ports, err := cli.FollowReferences("Open_vSwitch", "Bridge", bridges, "ports")
+	if err != nil || !reflect.DeepEqual(rowNames(ports), []string{"p1", "p2"}) {
+		t.Fatalf("FAIL: expected the ports of br-int, but got %v: %v", ports, err)
+	}
+	interfaces, err := cli.FollowReferences("Open_vSwitch", "Bridge", bridges, "ports", "interfaces")
+	if err != nil || !reflect.DeepEqual(rowNames(interfaces), []string{"i1", "i2"}) {
+		t.Fatalf("FAIL: expected the interfaces of br-int without the missing i3, but got %v: %v", interfaces, err)
+	}
+	t.Logf("PASS: followed the references from the server")
+
+	if _, err := cli.FollowReferences("Open_vSwitch", "Bridge", []Row{{"name": "br-int"}}, "ports"); err == nil {
+		t.Fatalf("FAIL: expected the rows without the reference column to fail")
+	}
+	if _, err := cli.FollowReferences("Open_vSwitch", "Bridge", bridges, "name"); err == nil {
+		t.Fatalf("FAIL: expected the column not being a reference to fail")
+	}
+	t.Logf("PASS: rejected the invalid paths")
+}
@@ -0,0 +1,173 @@
+// Copyright 2018 Paul Greenberg (greenpau@outlook.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovsdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// parseRemotes splits a comma-separated list of remotes.
+func parseRemotes(s string) []string {
+	remotes := []string{}
+	for _, remote := range strings.Split(s, ",") {
+		remote = strings.TrimSpace(remote)
+		if remote == "" {
+			continue
+		}
+		remotes = append(remotes, remote)
+	}
+	if len(remotes) == 0 {
+		// The empty remote fails in parseSocket.
+		remotes = append(remotes, s)
+	}
+	return remotes
+}
+
+// Remote returns the remote of the current connection.
+func (cli *Client) Remote() string {
+	if cli == nil || cli.sem == nil {
+		return ""
+	}
+	cli.sem <- struct{}{}
+	defer func() { <-cli.sem }()
+	if cli.state.conn == nil || cli.state.closed {
+		return ""
+	}
+	select {
+	case <-cli.state.conn.done:
+		return ""
+	default:
+	}
+	return cli.state.conn.remote
+}
+
+// dial connects to the first available remote of the Endpoint. With
+// WithLeaderOnly option, the remotes not being the cluster leader of the
+// databases are skipped.
+func (cli *Client) dial() (*connection, error) {
+	remotes := parseRemotes(cli.Endpoint)
+	if cli.randomRemotes {
+		rand.Shuffle(len(remotes), func(i, j int) {
+			remotes[i], remotes[j] = remotes[j], remotes[i]
+		})
+	}
+	errMsgs := []string{}
+	for _, remote := range remotes {
+		conn, err := cli.dialRemote(remote)
+		if err == nil && cli.leaderOnly {
+			if err = cli.watchLeader(conn); err != nil {
+				conn.close(err)
+			}
+		}
+		if err != nil {
+			if len(remotes) == 1 {
+				return nil, err
+			}
+			errMsgs = append(errMsgs, fmt.Sprintf("%s: %v", remote, err))
+			continue
+		}
+		return conn, nil
+	}
+	return nil, fmt.Errorf("%s", errMsgs)
+}
+
+// close shuts down a connection for a reason.
+func (conn *connection) close(reason error) {
+	select {
+	case conn.txQueue <- Request{Method: "shutdown", Params: reason}:
+	case <-conn.done:
+	}
+	<-conn.done
+}
+
+// serverDatabaseColumns are the columns of "Database" table of "_Server"
+// database, as described in ovsdb-server(5), determining the leadership.
+var serverDatabaseColumns = []string{"name", "model", "connected", "leader"}
+
+// isLeader returns false when a row of "Database" table of "_Server"
+// database describes a clustered database, which is either disconnected
+// from the cluster, or not the leader of the cluster.
+func isLeader(row Row) bool {
+	if row["model"] != "clustered" {
+		return true
+	}
+	return row["connected"] == true && row["leader"] == true
+}
+
+// requiresLeader reports whether a row of "Database" table of "_Server"
+// database describes a database whose leadership is required with
+// WithLeaderOnly option.
+func (cli *Client) requiresLeader(row Row) bool {
+	if len(cli.leaderDatabases) == 0 {
+		return true
+	}
+	name, _ := row["name"].(string)
+	return cli.leaderDatabases[name]
+}
+
+// watchLeader verifies that the server is the leader of the clustered
+// databases used by the client, and closes the connection when the leadership moves. After
+// the reconnect, the interrupted "echo", "list_dbs", and "get_schema"
+// requests are sent to the new leader, while the others, e.g. "transact",
+// fail with ConnectionError.
+func (cli *Client) watchLeader(conn *connection) error {
+	ctx, cancel := context.WithTimeout(context.Background(), cli.timeout())
+	defer cancel()
+	method := "monitor"
+	m := newMonitor(cli.notifier.nextID(), "_Server", method)
+	m.internal = true
+	m.Requests = map[string]MonitorRequest{
+		"Database": {Columns: serverDatabaseColumns},
+	}
+	cli.notifier.addMonitor(m)
+	response, err := cli.roundTrip(ctx, conn, method, []interface{}{m.Database, m.ID, m.Requests})
+	if err == nil {
+		err = json.Unmarshal(response.Result, &m.Initial)
+	}
+	if err != nil {
+		cli.notifier.removeMonitor(m.ID)
+		return fmt.Errorf("leader check failed: %w", err)
+	}
+	for _, rowUpdate := range m.Initial["Database"] {
+		if cli.requiresLeader(rowUpdate.New) && !isLeader(rowUpdate.New) {
+			cli.notifier.removeMonitor(m.ID)
+			return fmt.Errorf("leader check failed: %s database is not the cluster leader", rowUpdate.New["name"])
+		}
+	}
+	go func() {
+		defer cli.notifier.removeMonitor(m.ID)
+		for {
+			select {
+			case updates, ok := <-m.Updates:
+				if !ok {
+					return
+				}
+				for _, rowUpdate := range updates["Database"] {
+					if rowUpdate.New != nil && cli.requiresLeader(rowUpdate.New) && !isLeader(rowUpdate.New) {
+						conn.close(fmt.Errorf("%s database is no longer the cluster leader at %s", rowUpdate.New["name"], conn.remote))
+						return
+					}
+				}
+			case <-conn.done:
+				return
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,191 @@
+// Copyright 2018 Paul Greenberg (greenpau@outlook.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovsdb
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseRemotes(t *testing.T) {
+	for i, test := range []struct {
+		input    string
+		expected []string
+	}{
+		{input: "unix:/var/run/openvswitch/db.sock", expected: []string{"unix:/var/run/openvswitch/db.sock"}},
+		{input: "tcp:a:6641,tcp:b:6641, tcp:c:6641", expected: []string{"tcp:a:6641", "tcp:b:6641", "tcp:c:6641"}},
+		{input: "", expected: []string{""}},
+	} {
+		remotes := parseRemotes(test.input)
+		if len(remotes) != len(test.expected) {
+			t.Fatalf("FAIL: Test %d: %v (expected) vs. %v (actual)", i, test.expected, remotes)
+		}
+		for j := range remotes {
+			if remotes[j] != test.expected[j] {
+				t.Fatalf("FAIL: Test %d: %v (expected) vs. %v (actual)", i, test.expected, remotes)
+			}
+		}
+		t.Logf("PASS: Test %d: %s", i, test.input)
+	}
+}
+
+func TestClusterClient(t *testing.T) {
+	var mux sync.Mutex
+	leader := "a"
+	monitors := make(map[string]string)
+	newMember := func(name string) *testServer {
+		return newTestServer(t, func(s *testServer, req testRequest) {
+			switch req.Method {
+			case "monitor":
+				var id string
+				json.Unmarshal(req.Params[1], &id)
+				mux.Lock()
+				monitors[name] = id
+				isLeader := leader == name
+				mux.Unlock()
+				s.reply(req, map[string]interface{}{
+					"Database": map[string]interface{}{
+						"uuid-1": map[string]interface{}{
+							"new": map[string]interface{}{"name": "OVN_Northbound", "model": "clustered", "connected": true, "leader": isLeader},
+						},
+					},
+				})
+			case "echo":
+				s.reply(req, req.Params)
+			}
+		})
+	}
+	srvA := newMember("a")
+	srvB := newMember("b")
+	missing := "unix:" + filepath.Join(t.TempDir(), "missing.sock")
+
+	cli, err := NewClient(missing+","+srvB.Socket+","+srvA.Socket, WithLeaderOnly())
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	defer cli.Close()
+	if cli.Remote() != srvA.Socket {
+		t.Fatalf("FAIL: expected to connect to the leader %s, but connected to %s", srvA.Socket, cli.Remote())
+	}
+	t.Logf("PASS: connected to the leader")
+
+	// The leadership moves to the other member.
+	mux.Lock()
+	leader = "b"
+	id := monitors["a"]
+	mux.Unlock()
+	srvA.notify("update", id, map[string]interface{}{
+		"Database": map[string]interface{}{
+			"uuid-1": map[string]interface{}{
+				"old": map[string]interface{}{"leader": true},
+				"new": map[string]interface{}{"name": "OVN_Northbound", "model": "clustered", "connected": true, "leader": false},
+			},
+		},
+	})
+	// The client reconnects in background.
+	deadline := time.Now().Add(2 * time.Second)
+	for cli.Remote() != srvB.Socket {
+		if time.Now().After(deadline) {
+			t.Fatalf("FAIL: expected to reconnect to the leader %s, but connected to %s", srvB.Socket, cli.Remote())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := cli.Echo("test message"); err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	t.Logf("PASS: reconnected to the new leader")
+
+	cli2, err := NewClient(missing + "," + srvB.Socket)
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	defer cli2.Close()
+	if cli2.Remote() != srvB.Socket {
+		t.Fatalf("FAIL: expected to connect to %s, but connected to %s", srvB.Socket, cli2.Remote())
+	}
+	t.Logf("PASS: connected to the first available remote")
+}
+
+func TestLeaderOnlyDatabases(t *testing.T) {
+	var mux sync.Mutex
+	var monitorID string
+	srv := newTestServer(t, func(s *testServer, req testRequest) {
+		switch req.Method {
+		case "monitor":
+			mux.Lock()
+			json.Unmarshal(req.Params[1], &monitorID)
+			mux.Unlock()
+			s.reply(req, map[string]interface{}{
+				"Database": map[string]interface{}{
+					"uuid-1": map[string]interface{}{
+						"new": map[string]interface{}{"name": "OVN_Northbound", "model": "clustered", "connected": true, "leader": true},
+					},
+					"uuid-2": map[string]interface{}{
+						"new": map[string]interface{}{"name": "OVN_Southbound", "model": "clustered", "connected": true, "leader": false},
+					},
+				},
+			})
+		case "echo":
+			s.reply(req, req.Params)
+		}
+	})
+
+	if _, err := NewClient(srv.Socket, WithLeaderOnly("OVN_Southbound")); err == nil {
+		t.Fatalf("FAIL: expected to skip the follower of OVN_Southbound database")
+	}
+	t.Logf("PASS: skipped the follower")
+
+	events := make(chan ConnectionEvent, 10)
+	cli, err := NewClient(srv.Socket,
+		WithLeaderOnly("OVN_Northbound"),
+		WithConnectionEventHandler(func(event ConnectionEvent) {
+			events <- event
+		}),
+	)
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	defer cli.Close()
+	if cli.Remote() != srv.Socket {
+		t.Fatalf("FAIL: expected to connect to the leader of OVN_Northbound database %s, but connected to %s", srv.Socket, cli.Remote())
+	}
+	<-events
+	t.Logf("PASS: connected to the leader of OVN_Northbound database")
+
+	// The other database loses the connection to its cluster.
+	mux.Lock()
+	id := monitorID
+	mux.Unlock()
+	srv.notify("update", id, map[string]interface{}{
+		"Database": map[string]interface{}{
+			"uuid-2": map[string]interface{}{
+				"old": map[string]interface{}{"connected": true},
+				"new": map[string]interface{}{"name": "OVN_Southbound", "model": "clustered", "connected": false, "leader": false},
+			},
+		},
+	})
+	if err := cli.Echo("test message"); err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	select {
+	case event := <-events:
+		t.Fatalf("FAIL: expected to stay connected, but got %v", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+	t.Logf("PASS: ignored the leadership of OVN_Southbound database")
+}
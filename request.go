@@ -18,4 +18,8 @@ package ovsdb
 type Request struct {
 	Method string
 	Params interface{}
+	// id identifies the request on a connection. The response to
+	// the request is delivered via reply.
+	id    uint64
+	reply chan Response
 }
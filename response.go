@@ -28,14 +28,13 @@ type Response struct {
 	Result json.RawMessage `json:"result"`
 	Error
 	Seq uint64 `json:"id"`
+	// err is the failure of the request not sent to the server, e.g.
+	// because it could not be encoded.
+	err error
 }
 
 // UnmarshalJSON - TODO
 func (r *Response) UnmarshalJSON(b []byte) error {
-	if bytes.HasPrefix(b, []byte(`[{"`)) {
-		b = bytes.TrimLeft(b, "[")
-		b = bytes.TrimRight(b, "]")
-	}
 	if err := json.Unmarshal(b, &r.Result); err != nil {
 		return err
 	}
@@ -43,6 +42,26 @@ func (r *Response) UnmarshalJSON(b []byte) error {
 		if err := json.Unmarshal(b, &r.Error); err != nil {
 			return err
 		}
+		return nil
+	}
+	// The result of "transact" method is an array of the results of the
+	// operations. Any of them may be an error, e.g. following the empty
+	// results of "comment" or "commit" operations. The first error is
+	// reported in the response.
+	var results []json.RawMessage
+	if err := json.Unmarshal(b, &results); err != nil {
+		return nil
+	}
+	for _, result := range results {
+		var e Error
+		if err := json.Unmarshal(result, &e); err != nil {
+			// The element is not an object, e.g. a database name.
+			continue
+		}
+		if e.Message != "" {
+			r.Error = e
+			break
+		}
 	}
 	return nil
 }
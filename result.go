@@ -15,6 +15,7 @@
 package ovsdb
 
 import (
+	"encoding/json"
 	"fmt"
 	//"github.com/davecgh/go-spew/spew"
 	"reflect"
@@ -22,12 +23,40 @@ import (
 
 // Result - TODO
 type Result struct {
-	Rows     []Row `json:"rows"`
+	Rows []Row `json:"rows"`
+	// Count is the number of rows matched by "update", "mutate" and
+	// "delete" operations.
+	Count int `json:"count"`
+	// UUID is the UUID of the row created by "insert" operation.
+	UUID     string `json:"-"`
+	Error    Error  `json:"-"`
 	Database string
 	Table    string
 	Columns  map[string]string
 }
 
+// UnmarshalJSON decodes the result of an operation, as described in
+// https://tools.ietf.org/html/rfc7047#section-5.2
+func (r *Result) UnmarshalJSON(b []byte) error {
+	type result Result
+	v := struct {
+		*result
+		UUID []string `json:"uuid"`
+	}{
+		result: (*result)(r),
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if len(v.UUID) == 2 {
+		r.UUID = v.UUID[1]
+	}
+	if err := json.Unmarshal(b, &r.Error); err != nil {
+		return err
+	}
+	return nil
+}
+
 // Row - TODO
 type Row map[string]interface{}
 
@@ -52,6 +81,7 @@ func (r *Row) GetColumnValue(column string, columns map[string]string) (interfac
 		case "uuid":
 			return sliceDataValue.(string), "string", nil
 		case "set":
+			intData := []int{}
 			for _, x := range sliceDataValue.([]interface{}) {
 				switch reflect.TypeOf(x).Kind().String() {
 				case "slice":
@@ -64,8 +94,13 @@ func (r *Row) GetColumnValue(column string, columns map[string]string) (interfac
 					}
 				case "string":
 					sliceData = append(sliceData, reflect.ValueOf(x).Interface().(string))
+				case "float64":
+					intData = append(intData, int(x.(float64)))
 				}
 			}
+			if len(intData) > 0 {
+				return intData, "[]integer", nil
+			}
 			if len(sliceData) > 0 {
 				return sliceData, "[]string", nil
 			}
@@ -83,6 +118,13 @@ func (r *Row) GetColumnValue(column string, columns map[string]string) (interfac
 					}
 					xDataKey := xData.Index(0).Interface()
 					xDataValue := xData.Index(1).Interface()
+					if ref, err := decodeAtom(xDataValue); err == nil {
+						if id, ok := ref.(UUID); ok {
+							// The values referring to rows are
+							// reported as strings.
+							xDataValue = string(id)
+						}
+					}
 					xDataKeyType := reflect.ValueOf(xDataKey).Kind()
 					xDataValueType := reflect.ValueOf(xDataValue).Kind()
 					if mapType == "" || mapType == "map[]" {
@@ -95,7 +137,7 @@ func (r *Row) GetColumnValue(column string, columns map[string]string) (interfac
 					if xDataKeyType != reflect.String {
 						return nil, "", fmt.Errorf("Column %s does not contain map with string keys: %v", column, data)
 					}
-					kv[xData.Index(0).Interface().(string)] = xData.Index(1).Interface()
+					kv[xDataKey.(string)] = xDataValue
 				}
 			}
 			switch mapType {
@@ -15,10 +15,9 @@
 package ovsdb
 
 import (
-	//"encoding/json"
+	"context"
+	"encoding/json"
 	"fmt"
-	//"github.com/davecgh/go-spew/spew"
-	"reflect"
 	"sort"
 )
 
@@ -40,31 +39,223 @@ type Table struct {
 
 // Column - TODO
 type Column struct {
-	Type      interface{} `json:"type"`
-	Ephemeral bool        `json:"ephemeral"`
-	Mutable   bool        `json:"mutable"`
+	Type      ColumnType `json:"type"`
+	Ephemeral bool       `json:"ephemeral"`
+	Mutable   bool       `json:"mutable"`
+}
+
+// UnmarshalJSON decodes <column-schema>, as described in
+// https://tools.ietf.org/html/rfc7047#section-3.2. The columns are
+// mutable unless "mutable" member is false.
+func (c *Column) UnmarshalJSON(b []byte) error {
+	type column Column
+	v := column{Mutable: true}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*c = Column(v)
+	return nil
+}
+
+// Unlimited is the maximum number of the elements of the sets and the maps
+// with "unlimited" max.
+const Unlimited = -1
+
+// ColumnType is the <type> of a column, as described in RFC 7047 section
+// 3.2. A column holds between Min and Max elements of Key type or, when
+// Value is set, Key-Value pairs.
+type ColumnType struct {
+	Key   BaseType
+	Value *BaseType
+	Min   int
+	// Max is either a positive integer or Unlimited.
+	Max int
+}
+
+// BaseType is the <base-type> of the keys and the values of a column, as
+// described in RFC 7047 section 3.2. The constraints apply to the values of
+// the matching atomic type only. The nil pointers stand for the absence of
+// the constraints.
+type BaseType struct {
+	// Type is "integer", "real", "boolean", "string", or "uuid".
+	Type       string
+	Enum       OvsSet
+	MinInteger *int64
+	MaxInteger *int64
+	MinReal    *float64
+	MaxReal    *float64
+	MinLength  *int
+	MaxLength  *int
+	// RefTable is the table referred to by the values of "uuid" type.
+	RefTable string
+	// RefType is "strong" or "weak" for the references, see RefTable.
+	RefType string
+}
+
+// IsMap returns true when the column holds key-value pairs.
+func (t ColumnType) IsMap() bool {
+	return t.Value != nil
+}
+
+// IsSet returns true when the column holds a set, i.e. neither a map nor
+// exactly one atom. The optional columns, i.e. with min 0 and max 1, hold
+// sets.
+func (t ColumnType) IsSet() bool {
+	return t.Value == nil && (t.Min != 1 || t.Max != 1)
+}
+
+// IsOptional returns true when the column holds at most one atom.
+func (t ColumnType) IsOptional() bool {
+	return t.Value == nil && t.Min == 0 && t.Max == 1
+}
+
+// UnmarshalJSON decodes either an <atomic-type> or a JSON object with
+// "key", "value", "min", and "max" members.
+func (t *ColumnType) UnmarshalJSON(b []byte) error {
+	*t = ColumnType{Min: 1, Max: 1}
+	var atomic string
+	if err := json.Unmarshal(b, &atomic); err == nil {
+		t.Key = BaseType{Type: atomic}
+		return nil
+	}
+	var v struct {
+		Key   *BaseType       `json:"key"`
+		Value *BaseType       `json:"value"`
+		Min   *int            `json:"min"`
+		Max   json.RawMessage `json:"max"`
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return fmt.Errorf("invalid column type %s: %v", b, err)
+	}
+	if v.Key == nil {
+		return fmt.Errorf("invalid column type %s: no key", b)
+	}
+	t.Key = *v.Key
+	t.Value = v.Value
+	if v.Min != nil {
+		t.Min = *v.Min
+	}
+	if len(v.Max) > 0 {
+		var unlimited string
+		if err := json.Unmarshal(v.Max, &unlimited); err == nil {
+			if unlimited != "unlimited" {
+				return fmt.Errorf("invalid column type %s: invalid max", b)
+			}
+			t.Max = Unlimited
+		} else if err := json.Unmarshal(v.Max, &t.Max); err != nil {
+			return fmt.Errorf("invalid column type %s: invalid max", b)
+		}
+	}
+	return nil
+}
+
+// MarshalJSON encodes the type in the shortest form.
+func (t ColumnType) MarshalJSON() ([]byte, error) {
+	if t.Value == nil && t.Min == 1 && t.Max == 1 && t.Key.isAtomic() {
+		return json.Marshal(t.Key.Type)
+	}
+	v := map[string]interface{}{"key": t.Key}
+	if t.Value != nil {
+		v["value"] = t.Value
+	}
+	if t.Min != 1 {
+		v["min"] = t.Min
+	}
+	switch t.Max {
+	case 1:
+	case Unlimited:
+		v["max"] = "unlimited"
+	default:
+		v["max"] = t.Max
+	}
+	return json.Marshal(v)
+}
+
+// isAtomic returns true when the base type has no constraints, i.e. it is
+// an <atomic-type>.
+func (b BaseType) isAtomic() bool {
+	return b.Enum == nil && b.MinInteger == nil && b.MaxInteger == nil &&
+		b.MinReal == nil && b.MaxReal == nil && b.MinLength == nil &&
+		b.MaxLength == nil && b.RefTable == ""
+}
+
+type baseType struct {
+	Type       string   `json:"type"`
+	Enum       OvsSet   `json:"enum,omitempty"`
+	MinInteger *int64   `json:"minInteger,omitempty"`
+	MaxInteger *int64   `json:"maxInteger,omitempty"`
+	MinReal    *float64 `json:"minReal,omitempty"`
+	MaxReal    *float64 `json:"maxReal,omitempty"`
+	MinLength  *int     `json:"minLength,omitempty"`
+	MaxLength  *int     `json:"maxLength,omitempty"`
+	RefTable   string   `json:"refTable,omitempty"`
+	RefType    string   `json:"refType,omitempty"`
+}
+
+// UnmarshalJSON decodes either an <atomic-type> or a JSON object with
+// "type" member and the constraints.
+func (b *BaseType) UnmarshalJSON(data []byte) error {
+	var atomic string
+	if err := json.Unmarshal(data, &atomic); err == nil {
+		*b = BaseType{Type: atomic}
+		return nil
+	}
+	var v baseType
+	if err := json.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("invalid base type %s: %v", data, err)
+	}
+	if v.Type == "" {
+		return fmt.Errorf("invalid base type %s: no type", data)
+	}
+	if v.RefTable != "" && v.RefType == "" {
+		v.RefType = "strong"
+	}
+	*b = BaseType(v)
+	return nil
+}
+
+// MarshalJSON encodes the type in the shortest form.
+func (b BaseType) MarshalJSON() ([]byte, error) {
+	if b.isAtomic() {
+		return json.Marshal(b.Type)
+	}
+	return json.Marshal(baseType(b))
 }
 
 // GetSchema - TODO
 func (c *Client) GetSchema(s string) (Schema, error) {
-	if _, exists := c.Schemas[s]; exists {
-		return c.Schemas[s], nil
+	return c.GetSchemaContext(context.Background(), s)
+}
+
+// GetSchemaContext is like GetSchema, but honors the cancellation and the
+// deadline of the context.
+func (c *Client) GetSchemaContext(ctx context.Context, s string) (Schema, error) {
+	if c == nil || c.schemaMux == nil {
+		return Schema{}, fmt.Errorf("client was not initialized")
+	}
+	c.schemaMux.RLock()
+	schema, exists := c.Schemas[s]
+	c.schemaMux.RUnlock()
+	if exists {
+		return schema, nil
 	}
 	method := "get_schema"
 	js, err := encodeString(s)
 	if err != nil {
 		fmt.Errorf("'%s' method failed: %v", method, err)
 	}
-	response, err := c.query(method, js)
+	response, err := c.queryContext(ctx, method, js)
 	if err != nil {
-		return Schema{}, fmt.Errorf("'%s' method failed for '%s' database: %v", method, s, err)
+		return Schema{}, fmt.Errorf("'%s' method failed for '%s' database: %w", method, s, err)
 	}
-	schema, err := response.GetSchema()
+	schema, err = response.GetSchema()
 	if err != nil {
-		return Schema{}, fmt.Errorf("'%s' method failed for '%s' database: %v", method, s, err)
+		return Schema{}, fmt.Errorf("'%s' method failed for '%s' database: %w", method, s, err)
 	}
+	c.schemaMux.Lock()
+	defer c.schemaMux.Unlock()
 	c.Schemas[s] = schema
-	return c.Schemas[s], nil
+	return schema, nil
 }
 
 // GetTables - TODO
@@ -111,7 +302,6 @@ func (sc *Schema) GetColumnType(table, column string) (string, error) {
 	if column == "_uuid" || column == "_version" {
 		return "uuid", nil
 	}
-	var columnType string
 	if _, exists := sc.Tables[table]; !exists {
 		return "", fmt.Errorf("Table %s not found", table)
 	}
@@ -119,64 +309,13 @@ func (sc *Schema) GetColumnType(table, column string) (string, error) {
 		return "", fmt.Errorf("Column %s not found in Table %s", column, table)
 	}
 	t := sc.Tables[table].Columns[column].Type
-	k := reflect.ValueOf(t).Kind()
-
-	switch k {
-	case reflect.Map:
-		//if table == "Port_Binding" && column == "chassis" {
-		//	spew.Dump("***** start ******")
-		//	spew.Dump(table)
-		//	spew.Dump(column)
-		//	spew.Dump("      *****")
-		//	spew.Dump(t)
-		//	spew.Dump(k)
-		//	spew.Dump("***** end ******")
-		//}
-		m := t.(map[string]interface{})
-		mapKey, mapKeyExists := m["key"]
-		mapValue, mapValueExists := m["value"]
-		if mapKeyExists && mapValueExists {
-			if reflect.ValueOf(mapKey).Kind() == reflect.String {
-				if reflect.ValueOf(mapValue).Kind() == reflect.String {
-					return fmt.Sprintf("map[%s]%s", mapKey.(string), mapValue.(string)), nil
-				}
-			}
-		}
-		if !mapKeyExists {
-			return "", fmt.Errorf("Table %s Column %s: unsupported map, 'key' not found: %v", table, column, m)
-		}
-		mapKeyType := reflect.ValueOf(mapKey).Kind()
-		switch mapKeyType {
-		case reflect.Map:
-			mtt := mapKey.(map[string]interface{})
-			if _, exists := mtt["type"]; !exists {
-				return "", fmt.Errorf("Table %s Column %s: unsupported column type: %s-%s: %v", table, column, k, mapKeyType, t)
-			}
-			if reflect.ValueOf(mtt["type"]).Kind() != reflect.String {
-				return "", fmt.Errorf("Table %s Column %s: unsupported column type: %s-%s: %v", table, column, k, mapKeyType, t)
-			}
-			_, refTableExists := mtt["refTable"]
-			if refTableExists {
-				// TODO: handle ref table
-				//spew.Dump("***** start ******")
-				//spew.Dump(table)
-				//spew.Dump(column)
-				//spew.Dump(t)
-				//spew.Dump(k)
-				//spew.Dump("***** end ******")
-				columnType = fmt.Sprintf("map[string]%s", mtt["type"].(string))
-			} else {
-				columnType = mtt["type"].(string)
-			}
-		case reflect.String:
-			columnType = mapKey.(string)
-		default:
-			return "", fmt.Errorf("Table %s Column %s: unsupported column type: %s-%s", table, column, k, mapKeyType)
-		}
-	case reflect.String:
-		columnType = t.(string)
-	default:
-		return "", fmt.Errorf("Table %s Column %s: unsupported column type: %s", table, column, k)
+	switch {
+	case t.Value != nil:
+		return fmt.Sprintf("map[%s]%s", t.Key.Type, t.Value.Type), nil
+	case t.Key.RefTable != "":
+		// The references are reported as the maps from the names to
+		// the UUIDs of the rows.
+		return fmt.Sprintf("map[string]%s", t.Key.Type), nil
 	}
-	return columnType, nil
+	return t.Key.Type, nil
 }
@@ -15,7 +15,9 @@
 package ovsdb
 
 import (
+	"encoding/json"
 	//"github.com/davecgh/go-spew/spew"
+	"reflect"
 	"sort"
 	"testing"
 )
@@ -32,7 +34,7 @@ func TestGetSchemaMethod(t *testing.T) {
 	sort.Strings(keys)
 	for dbName := range dbs {
 		dbSock := dbs[dbName]
-		cli, err := NewClient(dbSock, 0)
+		cli, err := NewClient(dbSock)
 
 		if err != nil {
 			t.Fatalf("FAIL: %v", err)
@@ -67,7 +69,7 @@ func TestSchemaGetTables(t *testing.T) {
 	sort.Strings(keys)
 	for dbName := range dbs {
 		dbSock := dbs[dbName]
-		cli, err := NewClient(dbSock, 0)
+		cli, err := NewClient(dbSock)
 		if err != nil {
 			t.Fatalf("FAIL: %v", err)
 		}
@@ -101,7 +103,7 @@ func TestSchemaGetColumns(t *testing.T) {
 	sort.Strings(keys)
 	for dbName := range dbs {
 		dbSock := dbs[dbName]
-		cli, err := NewClient(dbSock, 0)
+		cli, err := NewClient(dbSock)
 		if err != nil {
 			t.Fatalf("FAIL: %v", err)
 		}
@@ -132,7 +134,7 @@ func TestSchemaGetColumns(t *testing.T) {
 
 func TestSchemaGetColumnType(t *testing.T) {
 	dbName := "Open_vSwitch"
-	cli, err := NewClient("unix:/var/run/openvswitch/db.sock", 0)
+	cli, err := NewClient("unix:/var/run/openvswitch/db.sock")
 	if err != nil {
 		t.Fatalf("FAIL: %v", err)
 	}
@@ -199,7 +201,7 @@ func TestSchemaTypesAll(t *testing.T) {
 	sort.Strings(keys)
 	for dbName := range dbs {
 		dbSock := dbs[dbName]
-		cli, err := NewClient(dbSock, 0)
+		cli, err := NewClient(dbSock)
 		if err != nil {
 			t.Fatalf("FAIL: %v", err)
 		}
@@ -240,3 +242,87 @@ func TestSchemaTypesAll(t *testing.T) {
 	}
 	t.Logf("PASS: schema.GetTables")
 }
+
+func TestColumnType(t *testing.T) {
+	schema := newTestSchema(t, testVswitchSchema)
+	minVLAN, maxVLAN := int64(0), int64(4095)
+	for i, test := range []struct {
+		table      string
+		column     string
+		expected   ColumnType
+		columnType string
+	}{
+		{
+			table:      "Bridge",
+			column:     "name",
+			expected:   ColumnType{Key: BaseType{Type: "string"}, Min: 1, Max: 1},
+			columnType: "string",
+		},
+		{
+			table:  "Bridge",
+			column: "ports",
+			expected: ColumnType{
+				Key: BaseType{Type: "uuid", RefTable: "Port", RefType: "strong"},
+				Max: Unlimited,
+			},
+			columnType: "map[string]uuid",
+		},
+		{
+			table:  "Bridge",
+			column: "fail_mode",
+			expected: ColumnType{
+				Key: BaseType{Type: "string", Enum: OvsSet{"standalone", "secure"}},
+				Max: 1,
+			},
+			columnType: "string",
+		},
+		{
+			table:  "Bridge",
+			column: "flood_vlans",
+			expected: ColumnType{
+				Key: BaseType{Type: "integer", MinInteger: &minVLAN, MaxInteger: &maxVLAN},
+				Max: 4096,
+			},
+			columnType: "integer",
+		},
+		{
+			table:  "Interface",
+			column: "statistics",
+			expected: ColumnType{
+				Key:   BaseType{Type: "string"},
+				Value: &BaseType{Type: "integer"},
+				Max:   Unlimited,
+			},
+			columnType: "map[string]integer",
+		},
+	} {
+		column := schema.Tables[test.table].Columns[test.column]
+		if !reflect.DeepEqual(column.Type, test.expected) {
+			t.Fatalf("FAIL: Test %d: %s column of %s table: expected %+v, but got %+v", i, test.column, test.table, test.expected, column.Type)
+		}
+		columnType, err := schema.GetColumnType(test.table, test.column)
+		if err != nil || columnType != test.columnType {
+			t.Fatalf("FAIL: Test %d: expected %s type, but got %s: %v", i, test.columnType, columnType, err)
+		}
+		b, err := json.Marshal(column.Type)
+		if err != nil {
+			t.Fatalf("FAIL: Test %d: %v", i, err)
+		}
+		var decoded ColumnType
+		if err := json.Unmarshal(b, &decoded); err != nil || !reflect.DeepEqual(decoded, column.Type) {
+			t.Fatalf("FAIL: Test %d: expected %s to decode as %+v, but got %+v: %v", i, b, column.Type, decoded, err)
+		}
+		t.Logf("PASS: Test %d: %s column of %s table: %s", i, test.column, test.table, b)
+	}
+
+	ct := schema.Tables["Interface"].Columns["admin_state"].Type
+	if !ct.IsOptional() || !ct.IsSet() || ct.IsMap() {
+		t.Fatalf("FAIL: expected an optional column, but got %+v", ct)
+	}
+	for _, b := range []string{`{"min":0}`, `{"key":"string","max":"many"}`, `{"key":{"enum":"a"}}`, `1`} {
+		if err := json.Unmarshal([]byte(b), &ct); err == nil {
+			t.Fatalf("FAIL: expected %s not to decode as column type", b)
+		}
+	}
+	t.Logf("PASS: rejected invalid column types")
+}
@@ -0,0 +1,118 @@
+// Copyright 2018 Paul Greenberg (greenpau@outlook.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovsdb
+
+import (
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// testRequest is a JSON-RPC request received by testServer.
+type testRequest struct {
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+	ID     interface{}       `json:"id"`
+}
+
+// testServer is a fake OVSDB server listening on a unix socket. The handler
+// receives each request and writes replies and notifications with send.
+type testServer struct {
+	t        *testing.T
+	listener net.Listener
+	Socket   string
+	handler  func(s *testServer, req testRequest)
+	mux      sync.Mutex
+	enc      *json.Encoder
+	conn     net.Conn
+}
+
+func newTestServer(t *testing.T, handler func(s *testServer, req testRequest)) *testServer {
+	path := filepath.Join(t.TempDir(), "db.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("FAIL: failed to start test server: %v", err)
+	}
+	return newTestServerListener(t, l, "unix:"+path, handler)
+}
+
+// newTestServerListener starts a fake OVSDB server accepting connections
+// on a listener, e.g. TLS listener. The socket is the remote of the server.
+func newTestServerListener(t *testing.T, l net.Listener, socket string, handler func(s *testServer, req testRequest)) *testServer {
+	s := &testServer{
+		t:        t,
+		listener: l,
+		Socket:   socket,
+		handler:  handler,
+	}
+	go s.serve()
+	t.Cleanup(func() { l.Close() })
+	return s
+}
+
+func (s *testServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mux.Lock()
+		s.enc = json.NewEncoder(conn)
+		s.conn = conn
+		s.mux.Unlock()
+		go func(conn net.Conn) {
+			defer conn.Close()
+			dec := json.NewDecoder(conn)
+			for {
+				var req testRequest
+				if err := dec.Decode(&req); err != nil {
+					return
+				}
+				s.handler(s, req)
+			}
+		}(conn)
+	}
+}
+
+// send writes a message to the most recent connection.
+func (s *testServer) send(msg interface{}) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.enc == nil {
+		return
+	}
+	s.enc.Encode(msg)
+}
+
+// reply sends a successful response to a request.
+func (s *testServer) reply(req testRequest, result interface{}) {
+	s.send(map[string]interface{}{"id": req.ID, "result": result, "error": nil})
+}
+
+// notify sends a notification.
+func (s *testServer) notify(method string, params ...interface{}) {
+	s.send(map[string]interface{}{"id": nil, "method": method, "params": params})
+}
+
+// disconnect closes the most recent connection.
+func (s *testServer) disconnect() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.conn != nil {
+		s.conn.Close()
+	}
+}
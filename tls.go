@@ -0,0 +1,166 @@
+// Copyright 2018 Paul Greenberg (greenpau@outlook.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovsdb
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"os"
+)
+
+// TLSOptions holds the files used to connect to "ssl:" remotes. They are
+// equivalent to --private-key (-p), --certificate (-c), --ca-cert (-C) and
+// --bootstrap-ca-cert options of ovn-nbctl and ovs-vsctl.
+type TLSOptions struct {
+	// PrivateKey is the path to the PEM-encoded private key of the client.
+	PrivateKey string
+	// Certificate is the path to the PEM-encoded certificate of the client.
+	Certificate string
+	// CACert is the path to the PEM-encoded certificate of the CA
+	// authenticating the server.
+	CACert string
+	// BootstrapCACert enables the bootstrap of the CA certificate. When
+	// CACert does not exist, the client trusts the CA certificate at the
+	// top of the chain presented by the server and saves it to CACert.
+	BootstrapCACert bool
+}
+
+// config returns the TLS configuration of the options. The bootstrap flag
+// is true when the CA certificate is to be obtained from the server.
+func (o *TLSOptions) config() (*tls.Config, bool, error) {
+	if o == nil {
+		return nil, false, fmt.Errorf("tls error: no private key, certificate, and CA certificate")
+	}
+	if o.PrivateKey == "" || o.Certificate == "" || o.CACert == "" {
+		return nil, false, fmt.Errorf("tls error: private key, certificate, and CA certificate are required")
+	}
+	cert, err := tls.LoadX509KeyPair(o.Certificate, o.PrivateKey)
+	if err != nil {
+		return nil, false, fmt.Errorf("tls error: %v", err)
+	}
+	// Similar to Open vSwitch, the client authenticates the server by its
+	// CA, but does not match the name of the server against the name in
+	// the certificate.
+	config := &tls.Config{
+		Certificates:       []tls.Certificate{cert},
+		InsecureSkipVerify: true,
+	}
+	b, err := os.ReadFile(o.CACert)
+	if err != nil {
+		if os.IsNotExist(err) && o.BootstrapCACert {
+			return config, true, nil
+		}
+		return nil, false, fmt.Errorf("tls error: %v", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(b) {
+		return nil, false, fmt.Errorf("tls error: no certificates found in %s", o.CACert)
+	}
+	config.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		return verifyPeerCertificate(rawCerts, roots)
+	}
+	return config, false, nil
+}
+
+// verifyPeerCertificate verifies the certificate chain presented by the
+// server against the trusted CA certificates.
+func verifyPeerCertificate(rawCerts [][]byte, roots *x509.CertPool) error {
+	certs, err := parseCertificates(rawCerts)
+	if err != nil {
+		return err
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err = certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+	})
+	if err != nil {
+		return fmt.Errorf("tls error: %v", err)
+	}
+	return nil
+}
+
+func parseCertificates(rawCerts [][]byte) ([]*x509.Certificate, error) {
+	if len(rawCerts) == 0 {
+		return nil, fmt.Errorf("tls error: server presented no certificates")
+	}
+	certs := []*x509.Certificate{}
+	for _, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return nil, fmt.Errorf("tls error: %v", err)
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
+
+// bootstrapCACert obtains the CA certificate from the chain presented by
+// the server. The certificate at the top of the chain must be a
+// self-signed CA certificate authenticating the chain.
+func (o *TLSOptions) bootstrapCACert(rawCerts [][]byte) error {
+	certs, err := parseCertificates(rawCerts)
+	if err != nil {
+		return err
+	}
+	ca := certs[len(certs)-1]
+	if len(certs) < 2 || !ca.IsCA || ca.CheckSignatureFrom(ca) != nil {
+		return fmt.Errorf("tls error: server did not present a self-signed CA certificate for bootstrap")
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	if err := verifyPeerCertificate(rawCerts, roots); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(o.CACert, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return fmt.Errorf("tls error: failed saving bootstrap CA certificate: %v", err)
+	}
+	defer f.Close()
+	if err := pem.Encode(f, &pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}); err != nil {
+		return fmt.Errorf("tls error: failed saving bootstrap CA certificate: %v", err)
+	}
+	return nil
+}
+
+// dialTLS connects to "ssl:" remote.
+func dialTLS(ctx context.Context, dial DialContextFunc, addr string, opts *TLSOptions) (net.Conn, error) {
+	config, bootstrap, err := opts.config()
+	if err != nil {
+		return nil, err
+	}
+	if bootstrap {
+		config.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			return opts.bootstrapCACert(rawCerts)
+		}
+	}
+	c, err := dial(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	tc := tls.Client(c, config)
+	if err := tc.HandshakeContext(ctx); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return tc, nil
+}
@@ -0,0 +1,180 @@
+// Copyright 2018 Paul Greenberg (greenpau@outlook.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovsdb
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testPKI is a CA issuing the certificates of a test server and a client,
+// similar to the one created by ovs-pki.
+type testPKI struct {
+	dir    string
+	caCert *x509.Certificate
+	caKey  *ecdsa.PrivateKey
+	serial int64
+}
+
+func newTestPKI(t *testing.T, name string) *testPKI {
+	p := &testPKI{dir: t.TempDir()}
+	p.caCert, p.caKey = p.issue(t, name, nil, nil, true)
+	return p
+}
+
+func (p *testPKI) issue(t *testing.T, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, isCA bool) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	p.serial++
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(p.serial),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	b, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	cert, err := x509.ParseCertificate(b)
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	return cert, key
+}
+
+// write saves a certificate and a key issued by the CA to files. It returns
+// the paths to the files.
+func (p *testPKI) write(t *testing.T, name string, cert *x509.Certificate, key *ecdsa.PrivateKey) (string, string) {
+	certPath := filepath.Join(p.dir, name+"-cert.pem")
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), 0644); err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	if key == nil {
+		return certPath, ""
+	}
+	b, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	keyPath := filepath.Join(p.dir, name+"-privkey.pem")
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: b}), 0600); err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	return certPath, keyPath
+}
+
+func TestTLSClient(t *testing.T) {
+	pki := newTestPKI(t, "ovnnb CA")
+	serverCert, serverKey := pki.issue(t, "ovnnb", pki.caCert, pki.caKey, false)
+	clientCert, clientKey := pki.issue(t, "ovn-exporter", pki.caCert, pki.caKey, false)
+	clientCertPath, clientKeyPath := pki.write(t, "client", clientCert, clientKey)
+	caCertPath, _ := pki.write(t, "ca", pki.caCert, nil)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(pki.caCert)
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{
+			// The server presents the CA certificate for bootstrap.
+			Certificate: [][]byte{serverCert.Raw, pki.caCert.Raw},
+			PrivateKey:  serverKey,
+		}},
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+	})
+	if err != nil {
+		t.Fatalf("FAIL: failed to start test server: %v", err)
+	}
+	srv := newTestServerListener(t, l, "ssl:"+l.Addr().String(), func(s *testServer, req testRequest) {
+		if req.Method == "echo" {
+			s.reply(req, req.Params)
+		}
+	})
+
+	otherPKI := newTestPKI(t, "other CA")
+	otherCACertPath, _ := otherPKI.write(t, "ca", otherPKI.caCert, nil)
+	bootstrapCACertPath := filepath.Join(t.TempDir(), "cacert.pem")
+
+	for i, test := range []struct {
+		opts       *TLSOptions
+		shouldFail bool
+	}{
+		{
+			opts: &TLSOptions{PrivateKey: clientKeyPath, Certificate: clientCertPath, CACert: caCertPath},
+		},
+		{
+			opts:       &TLSOptions{PrivateKey: clientKeyPath, Certificate: clientCertPath, CACert: otherCACertPath},
+			shouldFail: true,
+		},
+		{
+			opts:       &TLSOptions{PrivateKey: clientKeyPath, Certificate: clientCertPath, CACert: bootstrapCACertPath},
+			shouldFail: true,
+		},
+		{
+			opts: &TLSOptions{PrivateKey: clientKeyPath, Certificate: clientCertPath, CACert: bootstrapCACertPath, BootstrapCACert: true},
+		},
+		{
+			// The bootstrapped CA certificate is used afterwards.
+			opts: &TLSOptions{PrivateKey: clientKeyPath, Certificate: clientCertPath, CACert: bootstrapCACertPath, BootstrapCACert: true},
+		},
+		{
+			shouldFail: true,
+		},
+	} {
+		cli, err := NewClient(srv.Socket, WithTLS(test.opts))
+		if err == nil {
+			err = cli.Echo("test message")
+			cli.Close()
+		}
+		if err != nil {
+			if !test.shouldFail {
+				t.Fatalf("FAIL: Test %d: expected to pass, but failed with: %v", i, err)
+			}
+			t.Logf("PASS: Test %d: expected to fail: failed with: %v", i, err)
+			continue
+		}
+		if test.shouldFail {
+			t.Fatalf("FAIL: Test %d: expected to fail, but passed", i)
+		}
+		t.Logf("PASS: Test %d: expected to pass: passed", i)
+	}
+	b, err := os.ReadFile(bootstrapCACertPath)
+	if err != nil {
+		t.Fatalf("FAIL: expected the bootstrap CA certificate to be saved: %v", err)
+	}
+	block, _ := pem.Decode(b)
+	if block == nil || string(block.Bytes) != string(pki.caCert.Raw) {
+		t.Fatalf("FAIL: unexpected bootstrap CA certificate: %s", b)
+	}
+}
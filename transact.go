@@ -15,6 +15,7 @@
 package ovsdb
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	//"github.com/davecgh/go-spew/spew"
@@ -26,6 +27,23 @@ type Transaction struct {
 	Operations []Operation
 }
 
+// NewTransaction returns an empty transaction for a database. The
+// operations added to the transaction are committed atomically by
+// Client.Execute.
+func NewTransaction(db string) *Transaction {
+	return &Transaction{
+		Database:   db,
+		Operations: []Operation{},
+	}
+}
+
+// Add appends operations to the transaction. The operations may refer
+// to the rows inserted by the preceding operations via NamedUUID.
+func (t *Transaction) Add(ops ...Operation) *Transaction {
+	t.Operations = append(t.Operations, ops...)
+	return t
+}
+
 // ToBytes - TODO
 func (t *Transaction) ToBytes() ([]byte, int, error) {
 	out := []byte{}
@@ -56,33 +74,178 @@ func (t *Transaction) ToString() (string, error) {
 
 // Transact - TODO
 func (c *Client) Transact(db string, query string) (Result, error) {
+	return c.TransactContext(context.Background(), db, query)
+}
+
+// TransactContext is like Transact, but honors the cancellation and the
+// deadline of the context.
+func (c *Client) TransactContext(ctx context.Context, db string, query string) (Result, error) {
 	if c == nil {
 		return Result{}, fmt.Errorf("interface is unavailable")
 	}
-	op, err := NewOperation(query)
+	q, err := ParseQuery(query)
 	if err != nil {
 		return Result{}, err
 	}
-	params := Transaction{
-		Database:   db,
-		Operations: []Operation{},
+	// The values of the query are converted into the types of the columns,
+	// e.g. the strings into UUIDs, so the schema is required.
+	schema, err := c.GetSchemaContext(ctx, db)
+	if err != nil {
+		return Result{}, fmt.Errorf("'%s' method, query: '%s' failed: %w", "transact", query, err)
+	}
+	if q.isClientSide() {
+		r, err := c.executeQuery(ctx, db, schema, q)
+		if err != nil {
+			return Result{}, fmt.Errorf("'%s' method, query: '%s' failed: %w", "transact", query, err)
+		}
+		return r, nil
+	}
+	op := schema.resolveOperations([]Operation{q.Operation})[0]
+	if op.Name == "select" {
+		if tc := c.Cache(db); tc != nil {
+			if rows, ok := tc.Select(op.Table, op.Columns, op.Conditions); ok {
+				columns, err := c.getColumns(ctx, db, op.Table)
+				if err != nil {
+					return Result{}, fmt.Errorf("'%s' method, query: '%s' failed: %w", "transact", query, err)
+				}
+				return Result{Rows: rows, Database: db, Table: op.Table, Columns: columns}, nil
+			}
+		}
 	}
-	params.Operations = append(params.Operations, op)
 	method := "transact"
-	response, err := c.query(method, params)
+	r, err := c.transactOperation(ctx, db, op)
+	if err != nil {
+		return Result{}, fmt.Errorf("'%s' method, query: '%s' failed: %w", method, query, err)
+	}
+	return r, nil
+}
+
+// TransactOperation executes an operation, e.g. "insert", in a transaction.
+func (c *Client) TransactOperation(db string, op Operation) (Result, error) {
+	return c.TransactOperationContext(context.Background(), db, op)
+}
+
+// TransactOperationContext is like TransactOperation, but honors the
+// cancellation and the deadline of the context.
+func (c *Client) TransactOperationContext(ctx context.Context, db string, op Operation) (Result, error) {
+	if c == nil {
+		return Result{}, fmt.Errorf("interface is unavailable")
+	}
+	r, err := c.transactOperation(ctx, db, op)
 	if err != nil {
-		return Result{}, fmt.Errorf("'%s' method, query: '%s' failed: %v", method, query, err)
+		return Result{}, fmt.Errorf("'%s' method, operation: '%s' failed: %w", "transact", op.Name, err)
 	}
-	var r Result
-	if err := json.Unmarshal(response.Result, &r); err != nil {
-		return Result{}, fmt.Errorf("'%s' method, query: '%s' failed: %v", method, query, err)
+	return r, nil
+}
+
+func (c *Client) transactOperation(ctx context.Context, db string, op Operation) (Result, error) {
+	results, err := c.transact(ctx, db, []Operation{op})
+	if err != nil {
+		return Result{}, err
 	}
+	r := results[0]
 	r.Database = db
 	r.Table = op.Table
-	columns, err := c.getColumns(db, op.Table)
+	if op.Table == "" {
+		return r, nil
+	}
+	columns, err := c.getColumns(ctx, db, op.Table)
 	if err != nil {
-		return Result{}, fmt.Errorf("'%s' method, query: '%s' failed: %v", method, query, err)
+		return Result{}, err
 	}
 	r.Columns = columns
 	return r, nil
 }
+
+// Execute commits the operations of a transaction atomically. It returns
+// the results of the operations in the order of the operations. When an
+// operation fails, the server does not commit any of the operations, and
+// the error identifies the first failed operation.
+func (c *Client) Execute(t *Transaction) ([]Result, error) {
+	return c.ExecuteContext(context.Background(), t)
+}
+
+// ExecuteContext is like Execute, but when the context is done before the
+// transaction completes, e.g. while a "wait" operation blocks, the client
+// asks the server to cancel the transaction and returns the error of the
+// context.
+func (c *Client) ExecuteContext(ctx context.Context, t *Transaction) ([]Result, error) {
+	method := "transact"
+	if c == nil {
+		return nil, fmt.Errorf("interface is unavailable")
+	}
+	if t == nil || len(t.Operations) == 0 {
+		return nil, fmt.Errorf("'%s' method failed: no operations", method)
+	}
+	for i, op := range t.Operations {
+		if err := op.Validate(); err != nil {
+			return nil, fmt.Errorf("'%s' method failed: operation %d (%s): %w", method, i, op.Name, err)
+		}
+	}
+	results, err := c.transact(ctx, t.Database, t.Operations)
+	for i := range results {
+		results[i].Database = t.Database
+		if i < len(t.Operations) {
+			results[i].Table = t.Operations[i].Table
+		}
+	}
+	if err != nil {
+		return results, fmt.Errorf("'%s' method failed: %w", method, err)
+	}
+	return results, nil
+}
+
+// transact sends the operations to the server in a single transaction.
+// When the schema of the database is cached, the client converts the values
+// of the operations into the types of the columns and validates the
+// operations before sending them.
+func (c *Client) transact(ctx context.Context, db string, ops []Operation) ([]Result, error) {
+	c.schemaMux.RLock()
+	schema, cached := c.Schemas[db]
+	c.schemaMux.RUnlock()
+	if cached {
+		ops = schema.resolveOperations(ops)
+		if err := schema.validateTransaction(ops, c.Cache(db)); err != nil {
+			return nil, err
+		}
+	}
+	params := Transaction{
+		Database:   db,
+		Operations: ops,
+	}
+	response, err := c.queryContext(ctx, "transact", params)
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Result) == 0 && response.Error.Message != "" {
+		// The server rejected the request as a whole.
+		respErr := response.Error
+		return nil, &respErr
+	}
+	var results []Result
+	if err := json.Unmarshal(response.Result, &results); err != nil {
+		return nil, err
+	}
+	// Each result is checked, since a failed operation may follow the
+	// operations with empty results, e.g. "comment".
+	for i, r := range results {
+		if r.Error.Message == "" {
+			continue
+		}
+		opErr := r.Error
+		if i < len(ops) {
+			return results, &OperationError{Index: i, Operation: ops[i].Name, Err: &opErr}
+		}
+		// The server appends an error when the transaction as a whole
+		// fails, e.g. due to a constraint violation.
+		return results, fmt.Errorf("commit failed: %w", &opErr)
+	}
+	if response.Error.Message != "" {
+		respErr := response.Error
+		return results, &respErr
+	}
+	if len(results) < len(ops) {
+		return results, fmt.Errorf("expected %d results, but received %d", len(ops), len(results))
+	}
+	return results, nil
+}
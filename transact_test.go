@@ -15,13 +15,17 @@
 package ovsdb
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	//"github.com/davecgh/go-spew/spew"
 	"testing"
+	"time"
 )
 
 func TestTransactMethod(t *testing.T) {
 	sock := "unix:/var/run/openvswitch/db.sock"
-	cli, err := NewClient(sock, 0)
+	cli, err := NewClient(sock)
 	if err != nil {
 		t.Fatalf("FAIL: expected to connect to %s, but failed with: %v", sock, err)
 	}
@@ -97,3 +101,206 @@ func TestTransactMethod(t *testing.T) {
 		t.Fatalf("Failed %d tests", testsFailed)
 	}
 }
+
+func TestTransactWriteOperation(t *testing.T) {
+	srv := newTestServer(t, func(s *testServer, req testRequest) {
+		switch req.Method {
+		case "get_schema":
+			s.reply(req, json.RawMessage(testNorthboundSchema))
+		case "transact":
+			var op map[string]interface{}
+			json.Unmarshal(req.Params[1], &op)
+			switch op["op"] {
+			case "insert":
+				s.reply(req, []interface{}{
+					map[string]interface{}{"uuid": []string{"uuid", "5b3a7c1e-2d4f-4a6b-8c9d-0e1f2a3b4c5d"}},
+				})
+			case "delete":
+				s.reply(req, []interface{}{
+					map[string]interface{}{"count": 1},
+					map[string]interface{}{"error": "referential integrity violation", "details": "cannot delete Logical_Switch_Port row"},
+				})
+			}
+		}
+	})
+	cli, err := NewClient(srv.Socket)
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	defer cli.Close()
+
+	op, err := NewInsertOperation("Logical_Switch", Row{"name": "ls1"}, "")
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	result, err := cli.TransactOperation("OVN_Northbound", op)
+	if err != nil {
+		t.Fatalf("FAIL: expected to insert a row, but failed: %v", err)
+	}
+	if result.UUID != "5b3a7c1e-2d4f-4a6b-8c9d-0e1f2a3b4c5d" {
+		t.Fatalf("FAIL: unexpected inserted row UUID: %s", result.UUID)
+	}
+
+	op, err = NewDeleteOperation("Logical_Switch_Port", NewUUIDCondition("0c3e1b2a-7d6f-4e5a-8b9c-1d2e3f4a5b6c"))
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	if _, err := cli.TransactOperation("OVN_Northbound", op); err == nil {
+		t.Fatalf("FAIL: expected the transaction to fail, but passed")
+	} else if !errors.Is(err, ErrReferentialIntegrity) || errors.Is(err, ErrConstraintViolation) {
+		t.Fatalf("FAIL: expected referential integrity violation, but got: %v", err)
+	} else {
+		t.Logf("PASS: expected to fail, failed with: %v", err)
+	}
+
+	// The connection remains usable after a failed transaction.
+	if _, err := cli.TransactOperation("OVN_Northbound", op); err == nil {
+		t.Fatalf("FAIL: expected the transaction to fail, but passed")
+	}
+	t.Logf("PASS: 'transact' method with write operations completed successfully")
+}
+
+func TestExecuteTransaction(t *testing.T) {
+	requests := make(chan testRequest, 2)
+	srv := newTestServer(t, func(s *testServer, req testRequest) {
+		requests <- req
+		if len(req.Params) == 3 {
+			s.reply(req, []interface{}{
+				map[string]interface{}{"uuid": []string{"uuid", "5b3a7c1e-2d4f-4a6b-8c9d-0e1f2a3b4c5d"}},
+				map[string]interface{}{"count": 1},
+			})
+			return
+		}
+		s.reply(req, []interface{}{
+			map[string]interface{}{"error": "constraint violation", "details": "duplicate name"},
+			nil,
+		})
+	})
+	cli, err := NewClient(srv.Socket)
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	defer cli.Close()
+
+	cond, err := NewCondition([]string{"name==\"ls1\""})
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	insert, err := NewInsertOperation("Logical_Switch_Port", Row{"name": "lsp1"}, "new_lsp")
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	mutation, err := NewMutation("ports", "insert", []NamedUUID{"new_lsp"})
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	mutate, err := NewMutateOperation("Logical_Switch", []Mutation{mutation}, cond)
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	results, err := cli.Execute(NewTransaction("OVN_Northbound").Add(insert, mutate))
+	if err != nil {
+		t.Fatalf("FAIL: expected the transaction to pass, but failed: %v", err)
+	}
+	req := <-requests
+	expected := `["ports","insert",["set",[["named-uuid","new_lsp"]]]]`
+	var op struct {
+		Mutations []json.RawMessage `json:"mutations"`
+	}
+	if err := json.Unmarshal(req.Params[2], &op); err != nil || len(op.Mutations) != 1 || string(op.Mutations[0]) != expected {
+		t.Fatalf("FAIL: unexpected operation: %s", req.Params[2])
+	}
+	if len(results) != 2 || results[0].UUID != "5b3a7c1e-2d4f-4a6b-8c9d-0e1f2a3b4c5d" || results[1].Count != 1 {
+		t.Fatalf("FAIL: unexpected results: %v", results)
+	}
+
+	_, err = cli.Execute(NewTransaction("OVN_Northbound").Add(insert))
+	if err == nil {
+		t.Fatalf("FAIL: expected the transaction to fail, but passed")
+	}
+	expected = "'transact' method failed: operation 0 (insert) failed: constraint violation: duplicate name"
+	if err.Error() != expected {
+		t.Fatalf("FAIL: unexpected error: %v", err)
+	}
+	var opErr *OperationError
+	if !errors.Is(err, ErrConstraintViolation) || !errors.As(err, &opErr) || opErr.Index != 0 || opErr.Err.Details != "duplicate name" {
+		t.Fatalf("FAIL: expected constraint violation of operation 0, but got: %v", err)
+	}
+	t.Logf("PASS: transaction with multiple operations completed successfully")
+}
+
+func TestExecuteErrorAfterEmptyResult(t *testing.T) {
+	srv := newTestServer(t, func(s *testServer, req testRequest) {
+		s.reply(req, []interface{}{
+			map[string]interface{}{},
+			map[string]interface{}{"error": "constraint violation", "details": "dup"},
+		})
+	})
+	cli, err := NewClient(srv.Socket)
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	defer cli.Close()
+	comment, err := NewCommentOperation("add lsp1")
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	insert, err := NewInsertOperation("Logical_Switch_Port", Row{"name": "lsp1"}, "")
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	results, err := cli.Execute(NewTransaction("OVN_Northbound").Add(comment, insert))
+	if err == nil {
+		t.Fatalf("FAIL: expected the transaction to fail, but passed with: %v", results)
+	}
+	var opErr *OperationError
+	if !errors.Is(err, ErrConstraintViolation) || !errors.As(err, &opErr) || opErr.Index != 1 || opErr.Err.Details != "dup" {
+		t.Fatalf("FAIL: expected constraint violation of operation 1, but got: %v", err)
+	}
+	t.Logf("PASS: error following an empty result reported: %v", err)
+}
+
+func TestExecuteContextCancel(t *testing.T) {
+	canceled := make(chan interface{}, 1)
+	srv := newTestServer(t, func(s *testServer, req testRequest) {
+		switch req.Method {
+		case "transact":
+			// The "wait" operation blocks until the client cancels it.
+			canceled <- req.ID
+		case "cancel":
+			id := <-canceled
+			var param float64
+			json.Unmarshal(req.Params[0], &param)
+			if req.ID != nil || param != id.(float64) {
+				t.Errorf("FAIL: unexpected cancel request: %v", req)
+			}
+			s.send(map[string]interface{}{"id": id, "result": nil, "error": "canceled"})
+		case "echo":
+			s.reply(req, req.Params)
+		}
+	})
+	cli, err := NewClient(srv.Socket)
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	defer cli.Close()
+
+	wait, err := NewWaitOperation("Logical_Switch", -1, "==", []string{"name"}, []Row{{"name": "ls1"}})
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = cli.ExecuteContext(ctx, NewTransaction("OVN_Northbound").Add(wait))
+	if err == nil {
+		t.Fatalf("FAIL: expected the transaction to be canceled, but passed")
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("FAIL: unexpected error: %v", err)
+	}
+	// The connection remains usable after the cancellation.
+	if err := cli.Echo("test message"); err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	t.Logf("PASS: transaction canceled successfully")
+}
@@ -28,6 +28,11 @@ func parseSocket(s string) (string, string, error) {
 		arr := strings.Split(s, ":")
 		return arr[0], arr[1], nil
 	}
+	for _, proto := range []string{"tcp", "ssl"} {
+		if strings.HasPrefix(s, proto+":") {
+			return proto, strings.TrimPrefix(s, proto+":"), nil
+		}
+	}
 	return "tcp", s, nil
 }
 
@@ -0,0 +1,492 @@
+// Copyright 2018 Paul Greenberg (greenpau@outlook.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovsdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+)
+
+// uuidColumnType is the type of "_uuid" and "_version" columns, as
+// described in https://tools.ietf.org/html/rfc7047#section-3.2.
+var uuidColumnType = ColumnType{Key: BaseType{Type: "uuid"}, Min: 1, Max: 1}
+
+// columnType returns the type of a column of a table, including "_uuid"
+// and "_version" columns.
+func (sc *Schema) columnType(table, column string) (ColumnType, bool) {
+	if column == "_uuid" || column == "_version" {
+		return uuidColumnType, true
+	}
+	c, exists := sc.Tables[table].Columns[column]
+	return c.Type, exists
+}
+
+// ValidateOperation checks an operation against the schema, as the server
+// would do: the table and the columns exist, the modified columns are
+// mutable, the types of the values match the types of the columns, the
+// values respect the enums and the bounds of their base types, and the
+// sets and maps have the allowed number of elements.
+func (sc *Schema) ValidateOperation(op Operation) error {
+	return sc.ValidateTransaction(op)
+}
+
+// ValidateTransaction is like ValidateOperation, but also checks that the
+// rows inserted by the operations into a table fit its "maxRows".
+func (sc *Schema) ValidateTransaction(ops ...Operation) error {
+	return sc.validateTransaction(ops, nil)
+}
+
+// resolveOperations returns a copy of the operations with the values of
+// the conditions, the rows, and the mutations converted into the types of
+// their columns, see resolveDatum.
+func (sc *Schema) resolveOperations(ops []Operation) []Operation {
+	resolveRow := func(table string, row Row) Row {
+		if row == nil {
+			return nil
+		}
+		resolved := Row{}
+		for column, value := range row {
+			resolved[column] = value
+			ct, exists := sc.columnType(table, column)
+			if !exists {
+				continue
+			}
+			if datum, err := normalizeDatum(value); err == nil {
+				resolved[column] = resolveDatum(ct, datum, false)
+			}
+		}
+		return resolved
+	}
+	resolved := make([]Operation, len(ops))
+	for i, op := range ops {
+		resolved[i] = op
+		if op.Conditions != nil {
+			resolved[i].Conditions = make([]Condition, len(op.Conditions))
+			for j, c := range op.Conditions {
+				resolved[i].Conditions[j] = c
+				if ct, exists := sc.columnType(op.Table, c.Column); exists {
+					resolved[i].Conditions[j] = resolveCondition(ct, c)
+				}
+			}
+		}
+		resolved[i].Row = resolveRow(op.Table, op.Row)
+		if op.Rows != nil {
+			resolved[i].Rows = make([]Row, len(op.Rows))
+			for j, row := range op.Rows {
+				resolved[i].Rows[j] = resolveRow(op.Table, row)
+			}
+		}
+		if op.Mutations != nil {
+			resolved[i].Mutations = make([]Mutation, len(op.Mutations))
+			for j, m := range op.Mutations {
+				resolved[i].Mutations[j] = m
+				ct, exists := sc.columnType(op.Table, m.Column)
+				if !exists {
+					continue
+				}
+				if datum, err := normalizeDatum(m.Value); err == nil {
+					resolved[i].Mutations[j].Value = resolveDatum(ct, datum, false)
+				}
+			}
+		}
+	}
+	return resolved
+}
+
+// validateTransaction validates the operations. When the rows of a table
+// are cached, the check of "maxRows" accounts for the existing rows.
+func (sc *Schema) validateTransaction(ops []Operation, tc *TableCache) error {
+	inserts := map[string]int{}
+	deletes := map[string]bool{}
+	for i, op := range ops {
+		v := &operationValidator{schema: sc, index: i, op: op}
+		if err := v.validate(); err != nil {
+			return err
+		}
+		switch op.Name {
+		case "insert":
+			inserts[op.Table]++
+		case "delete":
+			deletes[op.Table] = true
+		}
+	}
+	for i, op := range ops {
+		if op.Name != "insert" {
+			continue
+		}
+		maxRows := sc.Tables[op.Table].MaxRows
+		if maxRows <= 0 {
+			continue
+		}
+		rows := inserts[op.Table]
+		if tc != nil && !deletes[op.Table] {
+			if n, cached := tc.count(op.Table); cached {
+				rows += n
+			}
+		}
+		if rows > maxRows {
+			return &ValidationError{
+				Index:     i,
+				Operation: op.Name,
+				Table:     op.Table,
+				Reason:    fmt.Sprintf("%d rows exceed maxRows %d", rows, maxRows),
+			}
+		}
+		// The table is checked once, at its first insert.
+		delete(inserts, op.Table)
+	}
+	return nil
+}
+
+// operationValidator validates an operation of a transaction.
+type operationValidator struct {
+	schema *Schema
+	index  int
+	op     Operation
+}
+
+func (v *operationValidator) errorf(column, format string, args ...interface{}) error {
+	return &ValidationError{
+		Index:     v.index,
+		Operation: v.op.Name,
+		Table:     v.op.Table,
+		Column:    column,
+		Reason:    fmt.Sprintf(format, args...),
+	}
+}
+
+func (v *operationValidator) validate() error {
+	op := v.op
+	switch op.Name {
+	case "commit", "abort", "comment", "assert":
+		return nil
+	}
+	if _, exists := v.schema.Tables[op.Table]; !exists {
+		return v.errorf("", "table not found in %s schema", v.schema.Name)
+	}
+	for _, column := range op.Columns {
+		if _, exists := v.schema.columnType(op.Table, column); !exists {
+			return v.errorf(column, "column not found")
+		}
+	}
+	for _, c := range op.Conditions {
+		if err := v.validateCondition(c); err != nil {
+			return err
+		}
+	}
+	switch op.Name {
+	case "insert", "update":
+		if err := v.validateRow(op.Row, op.Name == "update"); err != nil {
+			return err
+		}
+	case "wait":
+		columns := map[string]bool{}
+		for _, column := range op.Columns {
+			columns[column] = true
+		}
+		for _, row := range op.Rows {
+			for column, value := range row {
+				if !columns[column] {
+					return v.errorf(column, "column not listed in the columns of the operation")
+				}
+				ct, _ := v.schema.columnType(op.Table, column)
+				if err := v.validateValue(column, ct, value); err != nil {
+					return err
+				}
+			}
+		}
+	case "mutate":
+		for _, m := range op.Mutations {
+			if err := v.validateMutation(m); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateRow validates the columns of a row of "insert" or "update"
+// operation. The update of the immutable columns is not allowed.
+func (v *operationValidator) validateRow(row Row, update bool) error {
+	for column, value := range row {
+		if column == "_uuid" || column == "_version" {
+			return v.errorf(column, "column is read-only")
+		}
+		c, exists := v.schema.Tables[v.op.Table].Columns[column]
+		if !exists {
+			return v.errorf(column, "column not found")
+		}
+		if update && !c.Mutable {
+			return v.errorf(column, "column is immutable")
+		}
+		if err := v.validateValue(column, c.Type, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *operationValidator) validateMutation(m Mutation) error {
+	if m.Column == "_uuid" || m.Column == "_version" {
+		return v.errorf(m.Column, "column is read-only")
+	}
+	c, exists := v.schema.Tables[v.op.Table].Columns[m.Column]
+	if !exists {
+		return v.errorf(m.Column, "column not found")
+	}
+	if !c.Mutable {
+		return v.errorf(m.Column, "column is immutable")
+	}
+	ct := c.Type
+	switch m.Mutator {
+	case "+=", "-=", "*=", "/=", "%=":
+		if ct.IsMap() || ct.Key.Type != "integer" && ct.Key.Type != "real" {
+			return v.errorf(m.Column, "%s mutator requires integer or real column", m.Mutator)
+		}
+		if m.Mutator == "%=" && ct.Key.Type != "integer" {
+			return v.errorf(m.Column, "%s mutator requires integer column", m.Mutator)
+		}
+		// The constraints of the column apply to the result only.
+		scalar := ColumnType{Key: BaseType{Type: ct.Key.Type}, Min: 1, Max: 1}
+		return v.validateValue(m.Column, scalar, m.Value)
+	case "insert", "delete":
+		if !ct.IsMap() && !ct.IsSet() {
+			return v.errorf(m.Column, "%s mutator requires set or map column", m.Mutator)
+		}
+		ct.Min = 0
+		if m.Mutator == "delete" && ct.IsMap() {
+			// The keys of the pairs to delete may be given as a set.
+			datum, err := normalizeDatum(m.Value)
+			if err != nil {
+				return v.errorf(m.Column, "%v", err)
+			}
+			if _, ok := datum.(OvsSet); ok {
+				ct = ColumnType{Key: ct.Key, Min: 0, Max: Unlimited}
+			}
+		}
+		return v.validateValue(m.Column, ct, m.Value)
+	}
+	return v.errorf(m.Column, "unsupported mutator: %s", m.Mutator)
+}
+
+func (v *operationValidator) validateCondition(c Condition) error {
+	ct, exists := v.schema.columnType(v.op.Table, c.Column)
+	if !exists {
+		return v.errorf(c.Column, "column not found")
+	}
+	if !conditionFunctions[c.Function] {
+		return v.errorf(c.Column, "unsupported function: %s", c.Function)
+	}
+	switch c.Function {
+	case "<", "<=", ">", ">=":
+		if ct.IsMap() || ct.Max != 1 || ct.Key.Type != "integer" && ct.Key.Type != "real" {
+			return v.errorf(c.Column, "%s function requires integer or real column", c.Function)
+		}
+		ct.Min = 1
+	case "includes", "excludes":
+		ct.Min = 0
+	}
+	datum, err := c.Datum()
+	if err != nil {
+		return v.errorf(c.Column, "%v", err)
+	}
+	return v.validateValue(c.Column, ct, datum)
+}
+
+// normalizeDatum converts a value, as accepted by Row and Mutation, into
+// the typed model, see DecodeDatum. The Barewords are kept, to be converted
+// into the types of their columns, see resolveDatum.
+func normalizeDatum(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case Bareword:
+		return v, nil
+	case OvsSet:
+		s := OvsSet{}
+		for _, e := range v {
+			atom, err := normalizeAtom(e)
+			if err != nil {
+				return nil, err
+			}
+			s = append(s, atom)
+		}
+		return s, nil
+	case OvsMap:
+		m := OvsMap{}
+		for k, e := range v {
+			key, err := normalizeAtom(k)
+			if err != nil {
+				return nil, err
+			}
+			atom, err := normalizeAtom(e)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = atom
+		}
+		return m, nil
+	}
+	encoded, err := encodeDatum(value)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(encoded)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalDatum(b)
+}
+
+// normalizeAtom converts an element of a set or a map into the typed
+// model, see normalizeDatum.
+func normalizeAtom(value interface{}) (interface{}, error) {
+	atom, err := normalizeDatum(value)
+	if err != nil {
+		return nil, err
+	}
+	switch atom.(type) {
+	case OvsSet, OvsMap:
+		return nil, fmt.Errorf("unsupported atom type %T: %v", value, value)
+	}
+	return atom, nil
+}
+
+// validateValue checks the type, the constraints, and the number of the
+// elements of a value of a column.
+func (v *operationValidator) validateValue(column string, ct ColumnType, value interface{}) error {
+	datum, err := normalizeDatum(value)
+	if err != nil {
+		return v.errorf(column, "%v", err)
+	}
+	n := 0
+	if ct.IsMap() {
+		m, ok := datum.(OvsMap)
+		if s, isSet := datum.(OvsSet); isSet && len(s) == 0 {
+			// The empty map may be written as the empty set.
+			m, ok = OvsMap{}, true
+		}
+		if !ok {
+			return v.errorf(column, "expected map, but got %v", value)
+		}
+		for k, e := range m {
+			if err := checkAtom(ct.Key, k); err != nil {
+				return v.errorf(column, "key %v: %v", k, err)
+			}
+			if err := checkAtom(*ct.Value, e); err != nil {
+				return v.errorf(column, "value of key %v: %v", k, err)
+			}
+		}
+		n = len(m)
+	} else {
+		if _, ok := datum.(OvsMap); ok {
+			return v.errorf(column, "expected %s, but got map %v", ct.Key.Type, value)
+		}
+		elements := datumSet(datum)
+		for _, e := range elements {
+			if err := checkAtom(ct.Key, e); err != nil {
+				return v.errorf(column, "%v", err)
+			}
+		}
+		n = len(elements)
+	}
+	if n < ct.Min || ct.Max != Unlimited && n > ct.Max {
+		max := strconv.Itoa(ct.Max)
+		if ct.Max == Unlimited {
+			max = "unlimited"
+		}
+		return v.errorf(column, "%d elements violate min %d and max %s", n, ct.Min, max)
+	}
+	return nil
+}
+
+// checkAtom checks the type and the constraints of an atom, see
+// https://tools.ietf.org/html/rfc7047#section-3.2.
+func checkAtom(b BaseType, atom interface{}) error {
+	if w, ok := atom.(Bareword); ok {
+		atom = resolveAtom(b, w, false)
+	}
+	switch b.Type {
+	case "integer":
+		i, ok := atom.(int64)
+		if !ok {
+			return fmt.Errorf("%v is not integer", atom)
+		}
+		if b.MinInteger != nil && i < *b.MinInteger {
+			return fmt.Errorf("%d is less than minInteger %d", i, *b.MinInteger)
+		}
+		if b.MaxInteger != nil && i > *b.MaxInteger {
+			return fmt.Errorf("%d is greater than maxInteger %d", i, *b.MaxInteger)
+		}
+	case "real":
+		var f float64
+		switch a := atom.(type) {
+		case float64:
+			f = a
+		case int64:
+			f = float64(a)
+		default:
+			return fmt.Errorf("%v is not real", atom)
+		}
+		if b.MinReal != nil && f < *b.MinReal {
+			return fmt.Errorf("%v is less than minReal %v", f, *b.MinReal)
+		}
+		if b.MaxReal != nil && f > *b.MaxReal {
+			return fmt.Errorf("%v is greater than maxReal %v", f, *b.MaxReal)
+		}
+	case "boolean":
+		if _, ok := atom.(bool); !ok {
+			return fmt.Errorf("%v is not boolean", atom)
+		}
+	case "string":
+		s, ok := atom.(string)
+		if !ok {
+			return fmt.Errorf("%v is not string", atom)
+		}
+		n := utf8.RuneCountInString(s)
+		if b.MinLength != nil && n < *b.MinLength {
+			return fmt.Errorf("%q is shorter than minLength %d", s, *b.MinLength)
+		}
+		if b.MaxLength != nil && n > *b.MaxLength {
+			return fmt.Errorf("%q is longer than maxLength %d", s, *b.MaxLength)
+		}
+	case "uuid":
+		switch atom.(type) {
+		case UUID, NamedUUID:
+		default:
+			return fmt.Errorf("%v is not uuid", atom)
+		}
+	default:
+		return fmt.Errorf("unsupported atomic type: %s", b.Type)
+	}
+	if b.Enum != nil && !enumContains(b.Enum, atom) {
+		return fmt.Errorf("%v is not one of %v", atom, b.Enum)
+	}
+	return nil
+}
+
+func enumContains(enum OvsSet, atom interface{}) bool {
+	for _, e := range enum {
+		if e == atom {
+			return true
+		}
+		if i, ok := e.(int64); ok {
+			if f, ok := atom.(float64); ok && float64(i) == f {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,215 @@
+// Copyright 2018 Paul Greenberg (greenpau@outlook.com)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovsdb
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateOperation(t *testing.T) {
+	schema := newTestSchema(t, testVswitchSchema)
+	nameCondition := Condition{Column: "name", Function: "==", Value: "br-int", Type: "string"}
+	for i, test := range []struct {
+		op     Operation
+		reason string
+	}{
+		{op: Operation{Name: "select", Table: "Bridge", Columns: []string{"_uuid", "name"}, Conditions: []Condition{nameCondition}}},
+		{op: Operation{Name: "insert", Table: "Bridge", Row: Row{
+			"name":      "br-int",
+			"fail_mode": "secure",
+			"protocols": []string{"OpenFlow13", "OpenFlow15"},
+			"ports":     OvsSet{NamedUUID("new_port")},
+		}}},
+		{op: Operation{Name: "update", Table: "Bridge", Row: Row{"flood_vlans": []int{1, 2}, "fail_mode": OvsSet{}}, Conditions: []Condition{nameCondition}}},
+		{op: Operation{Name: "mutate", Table: "Open_vSwitch", Mutations: []Mutation{
+			{Column: "next_cfg", Mutator: "+=", Value: 1},
+			{Column: "bridges", Mutator: "insert", Value: OvsSet{UUID("36bd4ba1-0d47-4d6a-b8b4-6ad5e7a1b3e1")}},
+			{Column: "other_config", Mutator: "delete", Value: []string{"stats-update-interval"}},
+		}}},
+		{op: Operation{Name: "delete", Table: "Bridge", Conditions: []Condition{NewUUIDCondition("36bd4ba1-0d47-4d6a-b8b4-6ad5e7a1b3e1")}}},
+		{op: Operation{Name: "wait", Table: "Bridge", Until: "==", Columns: []string{"name"}, Rows: []Row{{"name": "br-int"}}}},
+		{op: Operation{Name: "commit", Durable: true}},
+		{
+			op:     Operation{Name: "select", Table: "Port"},
+			reason: "table Port: table not found in Open_vSwitch schema",
+		},
+		{
+			op:     Operation{Name: "select", Table: "Bridge", Columns: []string{"mtu"}},
+			reason: "column mtu: column not found",
+		},
+		{
+			op:     Operation{Name: "insert", Table: "Bridge", Row: Row{"name": "br-int", "mtu": 1500}},
+			reason: "column mtu: column not found",
+		},
+		{
+			op:     Operation{Name: "insert", Table: "Bridge", Row: Row{"name": 1}},
+			reason: "column name: 1 is not string",
+		},
+		{
+			op:     Operation{Name: "insert", Table: "Bridge", Row: Row{"fail_mode": "open"}},
+			reason: "column fail_mode: open is not one of [standalone secure]",
+		},
+		{
+			op:     Operation{Name: "insert", Table: "Bridge", Row: Row{"fail_mode": []string{"standalone", "secure"}}},
+			reason: "column fail_mode: 2 elements violate min 0 and max 1",
+		},
+		{
+			op:     Operation{Name: "insert", Table: "Bridge", Row: Row{"flood_vlans": []int{4096}}},
+			reason: "column flood_vlans: 4096 is greater than maxInteger 4095",
+		},
+		{
+			op:     Operation{Name: "insert", Table: "Bridge", Row: Row{"ports": "p1"}},
+			reason: "column ports: p1 is not uuid",
+		},
+		{
+			op:     Operation{Name: "insert", Table: "Bridge", Row: Row{"_uuid": UUID("u1")}},
+			reason: "column _uuid: column is read-only",
+		},
+		{
+			op:     Operation{Name: "update", Table: "Bridge", Row: Row{"name": "br-ex"}},
+			reason: "column name: column is immutable",
+		},
+		{
+			op:     Operation{Name: "insert", Table: "Interface", Row: Row{"statistics": OvsMap{"rx_packets": "many"}}},
+			reason: "column statistics: value of key rx_packets: many is not integer",
+		},
+		{
+			op:     Operation{Name: "insert", Table: "Interface", Row: Row{"statistics": []string{"rx_packets"}}},
+			reason: "column statistics: expected map",
+		},
+		{
+			op:     Operation{Name: "mutate", Table: "Bridge", Mutations: []Mutation{{Column: "name", Mutator: "insert", Value: "br-ex"}}},
+			reason: "column name: column is immutable",
+		},
+		{
+			op:     Operation{Name: "mutate", Table: "Open_vSwitch", Mutations: []Mutation{{Column: "next_cfg", Mutator: "insert", Value: 1}}},
+			reason: "column next_cfg: insert mutator requires set or map column",
+		},
+		{
+			op:     Operation{Name: "mutate", Table: "Open_vSwitch", Mutations: []Mutation{{Column: "other_config", Mutator: "+=", Value: 1}}},
+			reason: "column other_config: += mutator requires integer or real column",
+		},
+		{
+			op:     Operation{Name: "select", Table: "Bridge", Conditions: []Condition{{Column: "name", Function: "<", Value: "br", Type: "string"}}},
+			reason: "column name: < function requires integer or real column",
+		},
+		{
+			op:     Operation{Name: "select", Table: "Bridge", Conditions: []Condition{{Column: "_uuid", Function: "==", Value: "u1", Type: "string"}}},
+			reason: "column _uuid: u1 is not uuid",
+		},
+		{
+			op:     Operation{Name: "select", Table: "Bridge", Conditions: []Condition{{Column: "name", Function: "=~", Value: "br", Type: "string"}}},
+			reason: "column name: unsupported function: =~",
+		},
+		{
+			op:     Operation{Name: "wait", Table: "Bridge", Until: "==", Columns: []string{"name"}, Rows: []Row{{"fail_mode": "secure"}}},
+			reason: "column fail_mode: column not listed in the columns of the operation",
+		},
+	} {
+		err := schema.ValidateOperation(test.op)
+		if test.reason == "" {
+			if err != nil {
+				t.Fatalf("FAIL: Test %d: expected to pass, but failed with: %v", i, err)
+			}
+			t.Logf("PASS: Test %d: %s operation is valid", i, test.op.Name)
+			continue
+		}
+		if err == nil {
+			t.Fatalf("FAIL: Test %d: expected to fail with %q, but passed", i, test.reason)
+		}
+		var validationErr *ValidationError
+		if !errors.Is(err, ErrInvalidOperation) || !errors.As(err, &validationErr) || !strings.Contains(err.Error(), test.reason) {
+			t.Fatalf("FAIL: Test %d: expected to fail with %q, but failed with: %v", i, test.reason, err)
+		}
+		t.Logf("PASS: Test %d: expected to fail, failed with: %v", i, err)
+	}
+}
+
+func TestValidateTransactionMaxRows(t *testing.T) {
+	schema := newTestSchema(t, testVswitchSchema)
+	insert := Operation{Name: "insert", Table: "Open_vSwitch", Row: Row{"next_cfg": 0}}
+	if err := schema.ValidateTransaction(insert); err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	err := schema.ValidateTransaction(insert, Operation{Name: "comment", Comment: "test"}, insert)
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Index != 0 || validationErr.Column != "" {
+		t.Fatalf("FAIL: expected maxRows violation, but got: %v", err)
+	}
+	t.Logf("PASS: expected to fail, failed with: %v", err)
+
+	tc, err := NewTableCache(schema, "Open_vSwitch")
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	tc.Populate(TableUpdates{"Open_vSwitch": {"uuid-1": {New: Row{"next_cfg": float64(1)}}}})
+	if err := schema.validateTransaction([]Operation{insert}, tc); err == nil {
+		t.Fatalf("FAIL: expected the cached row to count towards maxRows")
+	}
+	remove := Operation{Name: "delete", Table: "Open_vSwitch"}
+	if err := schema.validateTransaction([]Operation{remove, insert}, tc); err != nil {
+		t.Fatalf("FAIL: expected the deletion to make room for the row: %v", err)
+	}
+	t.Logf("PASS: cached rows count towards maxRows")
+}
+
+func TestTransactValidation(t *testing.T) {
+	requests := make(chan string, 10)
+	srv := newTestServer(t, func(s *testServer, req testRequest) {
+		requests <- req.Method
+		switch req.Method {
+		case "get_schema":
+			s.reply(req, json.RawMessage(testNorthboundSchema))
+		case "transact":
+			s.reply(req, []interface{}{map[string]interface{}{"count": 1}})
+		}
+	})
+	cli, err := NewClient(srv.Socket)
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	defer cli.Close()
+
+	op, err := NewUpdateOperation("Logical_Switch_Port", Row{"tag": 5000}, NewUUIDCondition("0c3e1b2a-7d6f-4e5a-8b9c-1d2e3f4a5b6c"))
+	if err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	// The schema is not cached yet, so the server validates the operation.
+	if _, err := cli.Execute(NewTransaction("OVN_Northbound").Add(op)); err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	if method := <-requests; method != "transact" {
+		t.Fatalf("FAIL: expected transact request, but got %s", method)
+	}
+	if _, err := cli.GetSchema("OVN_Northbound"); err != nil {
+		t.Fatalf("FAIL: %v", err)
+	}
+	<-requests
+
+	_, err = cli.Execute(NewTransaction("OVN_Northbound").Add(op))
+	if !errors.Is(err, ErrInvalidOperation) || !strings.Contains(err.Error(), "5000 is greater than maxInteger 4095") {
+		t.Fatalf("FAIL: expected the operation to be rejected by the client, but got: %v", err)
+	}
+	select {
+	case method := <-requests:
+		t.Fatalf("FAIL: expected the invalid operation not to be sent, but got %s request", method)
+	case <-time.After(50 * time.Millisecond):
+	}
+	t.Logf("PASS: expected to fail, failed with: %v", err)
+}